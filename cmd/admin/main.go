@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/neepooha/sso/internal/config"
+	"github.com/neepooha/sso/internal/services/accounts"
+	"github.com/neepooha/sso/internal/storage"
+	"github.com/neepooha/sso/internal/storage/postgres"
+	"log/slog"
+	"os"
+)
+
+const usage = `usage: admin <command> [flags]
+
+commands:
+  export   export everything stored about a user as JSON
+           -email <email> | -id <user id> [-out <file>]
+  verify-audit
+           check the hash chain of the audit log
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = export(os.Args[2:])
+	case "verify-audit":
+		err = verifyAudit()
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	email := fs.String("email", "", "email of the user")
+	id := fs.Uint64("id", 0, "id of the user")
+	out := fs.String("out", "", "output file, stdout by default")
+	_ = fs.Parse(args)
+	if (*email == "") == (*id == 0) {
+		return fmt.Errorf("exactly one of -email or -id is required")
+	}
+
+	cfg := config.MustLoad()
+	log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	ctx := context.Background()
+
+	storage, err := openPostgres(cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	userID := *id
+	if *email != "" {
+		user, err := storage.GetUser(ctx, *email)
+		if err != nil {
+			return err
+		}
+		userID = user.ID
+	}
+
+	accountsService := accounts.New(log, storage, cfg.Accounts.Retention, cfg.Accounts.PurgeMode, cfg.Accounts.Operators, []byte(cfg.Audit.EmailKey))
+	archive, err := accountsService.Export(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(append(archive, '\n'))
+		return err
+	}
+	return os.WriteFile(*out, archive, 0o600)
+}
+
+func verifyAudit() error {
+	cfg := config.MustLoad()
+
+	storage, err := openPostgres(cfg)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	checked, err := storage.VerifyAudit(context.Background())
+	if err != nil {
+		return fmt.Errorf("%d entries ok, then: %w", checked, err)
+	}
+	fmt.Printf("audit log ok: %d entries\n", checked)
+	return nil
+}
+
+// openPostgres opens the storage, the commands need what only postgres has
+func openPostgres(cfg *config.Config) (*postgres.Storage, error) {
+	if cfg.Storage.Driver != storage.DriverPostgres {
+		return nil, fmt.Errorf("storage driver %q is not supported, admin needs postgres", cfg.Storage.Driver)
+	}
+	return postgres.New(cfg)
+}
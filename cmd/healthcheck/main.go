@@ -0,0 +1,88 @@
+// healthcheck asks the grpc.health.v1 service of a running sso for its status and
+// exits with 1 unless it is SERVING. The image is built from scratch, so the
+// Docker healthcheck can't use grpc_health_probe or a shell
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func main() {
+	var addr, service, caFile, certFile, keyFile string
+	var useTLS bool
+	var timeout time.Duration
+	flag.StringVar(&addr, "addr", "localhost:44044", "address of the gRPC server")
+	flag.StringVar(&service, "service", "", "service to check, empty for the whole server")
+	flag.DurationVar(&timeout, "timeout", 2*time.Second, "timeout of the check")
+	flag.BoolVar(&useTLS, "tls", false, "connect with TLS")
+	flag.StringVar(&caFile, "ca", "", "CA of the server certificate, system roots when empty")
+	flag.StringVar(&certFile, "cert", "", "client certificate when the server requires mTLS")
+	flag.StringVar(&keyFile, "key", "", "key of the client certificate")
+	flag.Parse()
+
+	creds := insecure.NewCredentials()
+	if useTLS {
+		cfg, err := tlsConfig(caFile, certFile, keyFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		creds = credentials.NewTLS(cfg)
+	}
+	if err := check(addr, service, creds, timeout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func tlsConfig(caFile string, certFile string, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", caFile)
+		}
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func check(addr string, service string, creds credentials.TransportCredentials, timeout time.Duration) error {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("status is %s", resp.GetStatus())
+	}
+	return nil
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"github.com/neepooha/sso/internal/app"
 	"github.com/neepooha/sso/internal/config"
 	"github.com/neepooha/sso/internal/lib/logger/handlers/slogpretty"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 const (
@@ -25,13 +28,25 @@ func main() {
 
 	application := app.New(log, cfg)
 	go application.GRPCSrv.MustRun()
+	go application.HTTPSrv.MustRun()
+	go application.Metrics.MustRun()
+	application.Jobs.Run()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGTERM, syscall.SIGINT)
 	call := <-stop
 	log.Info("stopping application", slog.String("signal", call.String()))
+	application.HTTPSrv.Stop()
 	application.GRPCSrv.Stop()
+	application.Jobs.Stop()
+	application.Metrics.Stop()
+	application.Hooks.Close()
 	application.Storage.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := application.StopTracing(ctx); err != nil {
+		log.Error("failed to flush traces", sl.Err(err))
+	}
 	log.Info("application stopped")
 }
 
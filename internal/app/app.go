@@ -1,43 +1,251 @@
 package app
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
 	grpcapp "github.com/neepooha/sso/internal/app/grpc"
+	httpapp "github.com/neepooha/sso/internal/app/http"
+	jobsapp "github.com/neepooha/sso/internal/app/jobs"
 	"github.com/neepooha/sso/internal/config"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/gateway"
+	accountsgrpc "github.com/neepooha/sso/internal/grpc/accounts"
+	"github.com/neepooha/sso/internal/grpc/authz"
+	"github.com/neepooha/sso/internal/grpc/deadline"
+	eventsgrpc "github.com/neepooha/sso/internal/grpc/events"
+	healthgrpc "github.com/neepooha/sso/internal/grpc/health"
+	profilesgrpc "github.com/neepooha/sso/internal/grpc/profiles"
+	serviceaccountsgrpc "github.com/neepooha/sso/internal/grpc/serviceaccounts"
+	webhooksgrpc "github.com/neepooha/sso/internal/grpc/webhooks"
+	"github.com/neepooha/sso/internal/lib/jwt"
+	"github.com/neepooha/sso/internal/lib/metrics"
 	"github.com/neepooha/sso/internal/lib/migrator"
+	"github.com/neepooha/sso/internal/lib/netguard"
+	"github.com/neepooha/sso/internal/lib/tlsconfig"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/services/accounts"
 	"github.com/neepooha/sso/internal/services/apps"
+	"github.com/neepooha/sso/internal/services/audit"
 	"github.com/neepooha/sso/internal/services/auth"
+	"github.com/neepooha/sso/internal/services/events"
+	"github.com/neepooha/sso/internal/services/hooks"
 	perm "github.com/neepooha/sso/internal/services/permissions"
+	"github.com/neepooha/sso/internal/services/profiles"
+	"github.com/neepooha/sso/internal/services/serviceaccounts"
+	"github.com/neepooha/sso/internal/services/webhooks"
+	"github.com/neepooha/sso/internal/storage"
+	"github.com/neepooha/sso/internal/storage/memory"
 	"github.com/neepooha/sso/internal/storage/postgres"
+	"github.com/neepooha/sso/internal/storage/sqlite"
 	"log/slog"
+	"net/http"
 
 	"github.com/golang-migrate/migrate/v4"
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type App struct {
-	GRPCSrv *grpcapp.App
-	Storage *postgres.Storage
+	// StopTracing flushes spans that weren't exported yet
+	StopTracing func(ctx context.Context) error
+	GRPCSrv     *grpcapp.App
+	HTTPSrv     *httpapp.App
+	Metrics     *httpapp.App
+	Jobs        *jobsapp.App
+	Hooks       *hooks.Hooks
+	Storage     Storage
+}
+
+// Storage is what auth, permissions, apps and audit need, every driver of storage.driver has it
+type Storage interface {
+	storage.Storage
+	auth.SessionStorage
+	auth.ClaimSource
+	apps.AppsSetterDeleter
+	apps.HookStorage
+	hooks.HookProvider
+	audit.AuditStorage
+	healthgrpc.Pinger
+	healthgrpc.MigrationProvider
+	Close()
 }
 
 func New(log *slog.Logger, cfg *config.Config) *App {
-	storage, err := postgres.New(cfg)
+	stopTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	storage, pg, err := newStorage(cfg)
+	if err != nil {
+		panic(err)
+	}
+	version, err := migrateStorage(log, cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	jwt.Configure(jwt.Options{Issuer: cfg.JWT.Issuer, ClockSkew: cfg.JWT.ClockSkew})
+
+	guard, err := netguard.New(cfg.Egress.AllowedNets)
+	if err != nil {
+		panic(err)
+	}
+	auditServer := audit.New(log, storage, []byte(cfg.Audit.EmailKey))
+	hooksServer := hooks.New(log, storage, cfg.Hooks.DefaultTimeout, guard, cfg.Hooks.GRPCInsecure, cfg.Hooks.ConnIdleTimeout)
+	defaults := models.AppSettings{
+		AccessTTL:        cfg.TokenTTL,
+		RefreshTTL:       cfg.Sessions.RefreshTTL,
+		IdleTimeout:      cfg.Sessions.IdleTimeout,
+		AbsoluteLifetime: cfg.Sessions.AbsoluteLifetime,
+		MaxSessions:      cfg.Sessions.MaxSessions,
+	}
+	authServer := auth.New(log, storage, storage, storage, storage, storage, storage, storage, auditServer, hooksServer, defaults, cfg.Impersonation.TTL)
+	matrix := perm.Matrix(cfg.Permissions.Matrix)
+	if err := matrix.Validate(); err != nil {
+		panic(err)
+	}
+	permServer := perm.New(log, storage, storage, storage, auditServer, matrix)
+	appsServer := apps.New(log, storage, storage, storage, storage, storage, guard, auditServer, cfg.Accounts.Operators)
+
+	// the other services are postgres only. On other drivers they stay nil, the gRPC
+	// server answers their calls with Unimplemented and API keys are rejected
+	var (
+		profilesService        profilesgrpc.Profiles
+		accountsService        accountsgrpc.Accounts
+		webhooksService        webhooksgrpc.Webhooks
+		eventsService          eventsgrpc.Events
+		serviceAccountsService serviceaccountsgrpc.ServiceAccounts
+		keys                   authz.KeyAuthenticator = noKeys{}
+		jobs                   []jobsapp.Job
+	)
+	if pg != nil {
+		accountsServer := accounts.New(log, pg, cfg.Accounts.Retention, cfg.Accounts.PurgeMode, cfg.Accounts.Operators, []byte(cfg.Audit.EmailKey))
+		webhooksServer := webhooks.New(log, pg, guard, cfg.Webhooks.Timeout, cfg.Webhooks.MaxAttempts, cfg.Webhooks.BatchSize)
+		eventsServer := events.New(log, pg, cfg.Events.PollInterval)
+		serviceAccountsServer := serviceaccounts.New(log, pg, pg, auditServer, defaults)
+		profilesService = profiles.New(log, pg, pg, pg)
+		accountsService = accountsServer
+		webhooksService = webhooksServer
+		eventsService = eventsServer
+		serviceAccountsService = serviceAccountsServer
+		keys = serviceAccountsServer
+		jobs = append(jobs,
+			jobsapp.Job{Name: "purge_users", Interval: cfg.Accounts.PurgeInterval, Run: accountsServer.Purge},
+			jobsapp.Job{Name: "dispatch_webhooks", Interval: cfg.Webhooks.DispatchInterval, Run: webhooksServer.Dispatch},
+			jobsapp.Job{Name: "listen_events", Interval: cfg.Events.PollInterval, Run: eventsServer.Listen},
+		)
+		metrics.Registry.MustRegister(metrics.NewPoolCollector(pg))
+	}
+
+	clientCerts, err := authz.ParseClientCertPolicy(cfg.GRPC.TLS.RequireClientCert)
+	if err != nil {
+		panic(err)
+	}
+	var tlsConfig *tls.Config
+	if cfg.GRPC.TLS.CertFile != "" {
+		reloader, err := tlsconfig.New(log, cfg.GRPC.TLS.CertFile, cfg.GRPC.TLS.KeyFile, cfg.GRPC.TLS.ClientCAFile, cfg.GRPC.TLS.ClientAuth)
+		if err != nil {
+			panic(err)
+		}
+		tlsConfig = reloader.Config()
+		jobs = append(jobs, jobsapp.Job{Name: "reload_tls", Interval: cfg.GRPC.TLS.ReloadInterval, Run: reloader.Reload})
+	}
+	if !clientCerts.Empty() && (tlsConfig == nil || cfg.GRPC.TLS.ClientAuth == tlsconfig.ClientAuthNone) {
+		panic("grpc.tls.require_client_cert needs grpc.tls with client_auth optional or require")
+	}
+	authorizer := authz.New(log, storage, storage, storage, keys, authz.Rules, clientCerts)
+	deadlines := deadline.New(cfg.GRPC.Timeout, cfg.GRPC.MethodTimeouts)
+
+	checker := healthgrpc.New(log, storage, storage, version, cfg.Health.Timeout)
+
+	grpcApp := grpcapp.New(log, authServer, permServer, appsServer, profilesService, accountsService, auditServer, webhooksService, eventsService, serviceAccountsService, checker, authorizer, deadlines, cfg.GRPC.Reflection, tlsConfig, cfg.GRPC.Host, cfg.GRPC.Port)
+	conn, err := grpcApp.Dial()
 	if err != nil {
 		panic(err)
 	}
-	err = migrator.Migrate(cfg)
+	gw, err := gateway.New(log, conn,
+		gateway.Service{Prefix: "auth", Descriptor: ssov2.File_sso_auth_proto.Services().ByName("Auth")},
+		gateway.Service{Prefix: "permissions", Descriptor: ssov2.File_sso_permissions_proto.Services().ByName("Permissions")},
+		gateway.Service{Prefix: "apps", Descriptor: ssov2.File_sso_apps_proto.Services().ByName("Apps")},
+	)
 	if err != nil {
-		if errors.Is(err, migrate.ErrNoChange) {
-			log.Debug("no migrations to apply")
-		} else {
+		panic(err)
+	}
+	var httpTLS *tls.Config
+	if cfg.HTTP.TLS.CertFile != "" {
+		reloader, err := tlsconfig.New(log, cfg.HTTP.TLS.CertFile, cfg.HTTP.TLS.KeyFile, "", tlsconfig.ClientAuthNone)
+		if err != nil {
 			panic(err)
 		}
+		httpTLS = reloader.HTTPConfig()
+		jobs = append(jobs, jobsapp.Job{Name: "reload_http_tls", Interval: cfg.HTTP.TLS.ReloadInterval, Run: reloader.Reload})
+	}
+	httpApp := httpapp.New(log, "gateway", gw, cfg.HTTP.Host, cfg.HTTP.Port, cfg.HTTP.Timeout, httpTLS)
+
+	metrics.SetMaxApps(cfg.Metrics.MaxApps)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{Registry: metrics.Registry}))
+	metricsApp := httpapp.New(log, "metrics", mux, cfg.Metrics.Host, cfg.Metrics.Port, cfg.HTTP.Timeout, nil)
+
+	jobsApp := jobsapp.New(log, append(jobs,
+		jobsapp.Job{Name: "purge_sessions", Interval: cfg.Sessions.PurgeInterval, Run: authServer.PurgeSessions},
+		jobsapp.Job{Name: "close_idle_hook_conns", Interval: cfg.Hooks.ConnIdleTimeout, Run: hooksServer.CloseIdle},
+		jobsapp.Job{Name: "health_check", Interval: cfg.Health.Interval, Run: checker.Check},
+	)...)
+	return &App{StopTracing: stopTracing, GRPCSrv: grpcApp, HTTPSrv: httpApp, Metrics: metricsApp, Jobs: jobsApp, Hooks: hooksServer, Storage: storage}
+}
+
+// migrateStorage applies the migrations of storage.driver and returns the latest
+// version. The memory storage has no schema, its version is 0
+func migrateStorage(log *slog.Logger, cfg *config.Config) (uint, error) {
+	if cfg.Storage.Driver == storage.DriverMemory {
+		return 0, nil
+	}
+	err := migrator.Migrate(cfg)
+	if err != nil {
+		if !errors.Is(err, migrate.ErrNoChange) {
+			return 0, err
+		}
+		log.Debug("no migrations to apply")
 	}
 	log.Debug("migrations applied successfully")
+	return migrator.LatestVersion(cfg.Storage.Migrations_path)
+}
+
+// newStorage opens the storage of storage.driver. The postgres storage is returned
+// a second time for the services only it has, it is nil for other drivers
+func newStorage(cfg *config.Config) (Storage, *postgres.Storage, error) {
+	switch cfg.Storage.Driver {
+	case storage.DriverPostgres:
+		pg, err := postgres.New(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pg, pg, nil
+	case storage.DriverSQLite:
+		s, err := sqlite.New(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, nil, nil
+	case storage.DriverMemory:
+		return memory.New(), nil, nil
+	}
+	return nil, nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+}
 
-	authServer := auth.New(log, storage, storage, storage, cfg.TokenTTL)
-	permServer := perm.New(log, storage, storage)
-	appsServer := apps.New(log, storage, storage, storage, storage)
+// noKeys rejects every API key when service accounts aren't served
+type noKeys struct{}
 
-	grpcApp := grpcapp.New(log, authServer, permServer, appsServer, cfg.GRPC.Host, cfg.GRPC.Port)
-	return &App{GRPCSrv: grpcApp, Storage: storage}
+func (noKeys) Authenticate(ctx context.Context, appName string, apiKey string) (models.ServiceAccount, error) {
+	return models.ServiceAccount{}, serviceaccounts.ErrInvalidKey
 }
@@ -1,32 +1,152 @@
 package grpcapp
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	accountsgrpc "github.com/neepooha/sso/internal/grpc/accounts"
 	appsgrpc "github.com/neepooha/sso/internal/grpc/apps"
+	auditgrpc "github.com/neepooha/sso/internal/grpc/audit"
 	authgrpc "github.com/neepooha/sso/internal/grpc/auth"
+	"github.com/neepooha/sso/internal/grpc/authz"
+	"github.com/neepooha/sso/internal/grpc/deadline"
+	eventsgrpc "github.com/neepooha/sso/internal/grpc/events"
+	healthgrpc "github.com/neepooha/sso/internal/grpc/health"
 	permgrpc "github.com/neepooha/sso/internal/grpc/permissions"
+	profilesgrpc "github.com/neepooha/sso/internal/grpc/profiles"
+	"github.com/neepooha/sso/internal/grpc/recovery"
+	"github.com/neepooha/sso/internal/grpc/requestlog"
+	serviceaccountsgrpc "github.com/neepooha/sso/internal/grpc/serviceaccounts"
+	"github.com/neepooha/sso/internal/grpc/unsupported"
+	webhooksgrpc "github.com/neepooha/sso/internal/grpc/webhooks"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/metrics"
 	"log/slog"
 	"net"
+	"time"
 
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/test/bufconn"
 )
 
+// shutdownTimeout bounds GracefulStop, which otherwise waits for open
+// WatchEvents streams forever
+const shutdownTimeout = 10 * time.Second
+
+// inprocBufferSize is the buffer of the in-process listener used by the HTTP gateway
+const inprocBufferSize = 1 << 20
+
+// inprocNetwork is the network of connections of the in-process listener
+const inprocNetwork = "bufconn"
+
 type App struct {
 	log        *slog.Logger
 	gRPCServer *grpc.Server
+	checker    *healthgrpc.Checker
+	inproc     *bufconn.Listener
 	host       string
 	port       string
 }
 
-func New(log *slog.Logger, authService authgrpc.Auth, permService permgrpc.Perm, appsService appsgrpc.Apps, host string, port string) *App {
-	gRPCServer := grpc.NewServer()
+func New(log *slog.Logger, authService authgrpc.Auth, permService permgrpc.Perm, appsService appsgrpc.Apps, profilesService profilesgrpc.Profiles, accountsService accountsgrpc.Accounts, auditService auditgrpc.Audit, webhooksService webhooksgrpc.Webhooks, eventsService eventsgrpc.Events, serviceAccountsService serviceaccountsgrpc.ServiceAccounts, checker *healthgrpc.Checker, authorizer *authz.Authorizer, deadlines *deadline.Deadlines, withReflection bool, tlsConfig *tls.Config, host string, port string) *App {
+	// services the storage driver doesn't have are nil, they are registered
+	// unimplemented and their calls fail with ReasonNotSupported
+	var unsupportedServices []string
+	if profilesService == nil {
+		unsupportedServices = append(unsupportedServices, ssov2.Profiles_ServiceDesc.ServiceName)
+	}
+	if accountsService == nil {
+		unsupportedServices = append(unsupportedServices, ssov2.Accounts_ServiceDesc.ServiceName)
+	}
+	if webhooksService == nil {
+		unsupportedServices = append(unsupportedServices, ssov2.Webhooks_ServiceDesc.ServiceName)
+	}
+	if eventsService == nil {
+		unsupportedServices = append(unsupportedServices, ssov2.Events_ServiceDesc.ServiceName)
+	}
+	if serviceAccountsService == nil {
+		unsupportedServices = append(unsupportedServices, ssov2.ServiceAccounts_ServiceDesc.ServiceName)
+	}
+	unsupportedCalls := unsupported.New(unsupportedServices...)
+
+	opts := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			requestlog.UnaryServerInterceptor(log),
+			metrics.UnaryServerInterceptor(),
+			deadlines.Unary(),
+			recovery.UnaryServerInterceptor(log),
+			unsupportedCalls.Unary(),
+			authorizer.Unary(),
+		),
+		grpc.ChainStreamInterceptor(
+			requestlog.StreamServerInterceptor(log),
+			metrics.StreamServerInterceptor(),
+			deadlines.Stream(),
+			recovery.StreamServerInterceptor(log),
+			unsupportedCalls.Stream(),
+			authorizer.Stream(),
+		),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(newServerCreds(tlsConfig)))
+	} else {
+		log.Warn("gRPC server is running without TLS")
+	}
+	gRPCServer := grpc.NewServer(opts...)
 	authgrpc.Register(gRPCServer, authService)
 	permgrpc.Register(gRPCServer, permService)
 	appsgrpc.Register(gRPCServer, appsService)
+	auditgrpc.Register(gRPCServer, auditService)
+	if profilesService != nil {
+		profilesgrpc.Register(gRPCServer, profilesService)
+	} else {
+		ssov2.RegisterProfilesServer(gRPCServer, ssov2.UnimplementedProfilesServer{})
+	}
+	if accountsService != nil {
+		accountsgrpc.Register(gRPCServer, accountsService)
+	} else {
+		ssov2.RegisterAccountsServer(gRPCServer, ssov2.UnimplementedAccountsServer{})
+	}
+	if webhooksService != nil {
+		webhooksgrpc.Register(gRPCServer, webhooksService)
+	} else {
+		ssov2.RegisterWebhooksServer(gRPCServer, ssov2.UnimplementedWebhooksServer{})
+	}
+	if eventsService != nil {
+		eventsgrpc.Register(gRPCServer, eventsService)
+	} else {
+		ssov2.RegisterEventsServer(gRPCServer, ssov2.UnimplementedEventsServer{})
+	}
+	if serviceAccountsService != nil {
+		serviceaccountsgrpc.Register(gRPCServer, serviceAccountsService)
+	} else {
+		ssov2.RegisterServiceAccountsServer(gRPCServer, ssov2.UnimplementedServiceAccountsServer{})
+	}
+	for _, service := range unsupportedServices {
+		log.Warn("service is not supported by the storage driver", slog.String("service", service))
+	}
+	healthgrpc.Register(gRPCServer, checker)
+	if withReflection {
+		reflection.Register(gRPCServer)
+		log.Info("gRPC server reflection is enabled")
+	}
+	for _, method := range authorizer.Missing(gRPCServer.GetServiceInfo()) {
+		log.Error("method has no authorization rule, calls are denied", slog.String("method", method))
+	}
+	for _, method := range deadlines.Unknown(gRPCServer.GetServiceInfo()) {
+		log.Error("timeout is set for an unknown method", slog.String("method", method))
+	}
 
 	return &App{
 		log:        log,
 		gRPCServer: gRPCServer,
+		checker:    checker,
+		inproc:     bufconn.Listen(inprocBufferSize),
 		host:       host,
 		port:       port,
 	}
@@ -48,16 +168,51 @@ func (a *App) run() error {
 	}
 	log.Info("gRPC server is running", slog.String("address", l.Addr().String()))
 
+	go func() {
+		if err := a.gRPCServer.Serve(a.inproc); err != nil {
+			log.Error("in-process listener stopped", sl.Err(err))
+		}
+	}()
+
 	if err := a.gRPCServer.Serve(l); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 	return nil
 }
 
+// Dial returns a client connection to the server that doesn't leave the process,
+// calls through it pass the same interceptors as network calls
+func (a *App) Dial() (*grpc.ClientConn, error) {
+	const op = "app.grpc.app.Dial"
+
+	conn, err := grpc.NewClient("passthrough:///inproc",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return a.inproc.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return conn, nil
+}
+
 func (a *App) Stop() {
 	const op = "app.grpc.app.Stop"
 	log := a.log.With(slog.String("op", op))
 
 	log.Info("stopping gRPC server")
-	a.gRPCServer.GracefulStop()
+	a.checker.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		a.gRPCServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		log.Warn("graceful stop timed out, closing open streams")
+		a.gRPCServer.Stop()
+	}
 }
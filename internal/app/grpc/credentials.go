@@ -0,0 +1,38 @@
+package grpcapp
+
+import (
+	"crypto/tls"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// serverCreds is TLS for network connections and plaintext for the in-process
+// listener of the HTTP gateway, whose bytes never leave the process. Without it
+// the gateway would need a client certificate whenever mTLS is required
+type serverCreds struct {
+	credentials.TransportCredentials
+}
+
+func newServerCreds(cfg *tls.Config) credentials.TransportCredentials {
+	return serverCreds{TransportCredentials: credentials.NewTLS(cfg)}
+}
+
+func (c serverCreds) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	if conn.LocalAddr().Network() == inprocNetwork {
+		return conn, inprocAuthInfo{CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}}, nil
+	}
+	return c.TransportCredentials.ServerHandshake(conn)
+}
+
+func (c serverCreds) Clone() credentials.TransportCredentials {
+	return serverCreds{TransportCredentials: c.TransportCredentials.Clone()}
+}
+
+type inprocAuthInfo struct {
+	credentials.CommonAuthInfo
+}
+
+func (inprocAuthInfo) AuthType() string {
+	return "inproc"
+}
@@ -0,0 +1,82 @@
+package httpapp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// shutdownTimeout bounds waiting for calls in flight when stopping
+const shutdownTimeout = 10 * time.Second
+
+type App struct {
+	log    *slog.Logger
+	name   string
+	server *http.Server
+	host   string
+	port   string
+}
+
+// New returns an HTTP server of handler, name tells servers apart in logs.
+// It serves HTTPS when tlsConfig is set
+func New(log *slog.Logger, name string, handler http.Handler, host string, port string, timeout time.Duration, tlsConfig *tls.Config) *App {
+	return &App{
+		log:  log,
+		name: name,
+		server: &http.Server{
+			Addr:              net.JoinHostPort(host, port),
+			Handler:           handler,
+			ReadHeaderTimeout: timeout,
+			ReadTimeout:       timeout,
+			WriteTimeout:      timeout,
+			TLSConfig:         tlsConfig,
+		},
+		host: host,
+		port: port,
+	}
+}
+
+func (a *App) MustRun() {
+	if err := a.run(); err != nil {
+		panic(err)
+	}
+}
+
+func (a *App) run() error {
+	const op = "app.http.app.Run"
+	log := a.log.With(slog.String("op", op), slog.String("server", a.name), slog.String("port", a.port))
+
+	l, err := net.Listen("tcp", a.server.Addr)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("HTTP server is running", slog.String("address", l.Addr().String()), slog.Bool("tls", a.server.TLSConfig != nil))
+
+	serve := a.server.Serve
+	if a.server.TLSConfig != nil {
+		// the certificate comes from TLSConfig
+		serve = func(l net.Listener) error { return a.server.ServeTLS(l, "", "") }
+	}
+	if err := serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (a *App) Stop() {
+	const op = "app.http.app.Stop"
+	log := a.log.With(slog.String("op", op), slog.String("server", a.name))
+
+	log.Info("stopping HTTP server")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := a.server.Shutdown(ctx); err != nil {
+		log.Warn("graceful shutdown timed out, closing connections")
+		a.server.Close()
+	}
+}
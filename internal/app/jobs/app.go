@@ -0,0 +1,74 @@
+package jobsapp
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is a background task run every Interval until the app is stopped
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type App struct {
+	log    *slog.Logger
+	jobs   []Job
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(log *slog.Logger, jobs ...Job) *App {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &App{
+		log:    log,
+		jobs:   jobs,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Run starts every job in its own goroutine and returns immediately
+func (a *App) Run() {
+	const op = "app.jobs.app.Run"
+	log := a.log.With(slog.String("op", op))
+
+	for _, job := range a.jobs {
+		a.wg.Add(1)
+		go a.loop(job)
+		log.Info("background job started", slog.String("job", job.Name), slog.Duration("interval", job.Interval))
+	}
+}
+
+func (a *App) loop(job Job) {
+	defer a.wg.Done()
+	log := a.log.With(slog.String("job", job.Name))
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		if err := job.Run(a.ctx); err != nil && a.ctx.Err() == nil {
+			log.Error("background job failed", sl.Err(err))
+		}
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop cancels running jobs and waits for them to return
+func (a *App) Stop() {
+	const op = "app.jobs.app.Stop"
+	log := a.log.With(slog.String("op", op))
+
+	log.Info("stopping background jobs")
+	a.cancel()
+	a.wg.Wait()
+}
@@ -10,24 +10,179 @@ import (
 )
 
 type Config struct {
-	Env      string        `yaml:"env" env-default:"local"`
-	TokenTTL time.Duration `yaml:"token_ttl" env-default:"1h"`
-	GRPC     `yaml:"grpc"`
-	Storage  `yaml:"storage"`
+	Env           string        `yaml:"env" env-default:"local"`
+	TokenTTL      time.Duration `yaml:"token_ttl" env-default:"1h"`
+	JWT           `yaml:"jwt"`
+	GRPC          `yaml:"grpc"`
+	HTTP          `yaml:"http"`
+	Health        `yaml:"health"`
+	Metrics       `yaml:"metrics"`
+	Tracing       `yaml:"tracing"`
+	Storage       `yaml:"storage"`
+	Accounts      `yaml:"accounts"`
+	Audit         `yaml:"audit"`
+	Webhooks      `yaml:"webhooks"`
+	Events        `yaml:"events"`
+	Hooks         `yaml:"hooks"`
+	Egress        `yaml:"egress"`
+	Sessions      `yaml:"sessions"`
+	Permissions   `yaml:"permissions"`
+	Impersonation `yaml:"impersonation"`
 }
 type Storage struct {
-	Host            string `yaml:"host" env-required:"true"`
-	Port            string `yaml:"port" env-required:"true"`
-	Dbname          string `yaml:"dbname" env-required:"true" env:"POSTGRES_DB"`
-	User            string `yaml:"user" env-required:"true" env:"POSTGRES_USER"`
-	Password        string `yaml:"password" env-required:"true" env:"POSTGRES_PASSWORD"`
+	// Driver is "postgres", "sqlite" or "memory". Sqlite and memory serve auth, permissions, apps
+	// and audit, the other services answer Unimplemented
+	Driver string `yaml:"driver" env-default:"postgres"`
+	// Path is the database file of sqlite
+	Path string `yaml:"path" env-default:"sso.db"`
+	// Host, Port, Dbname, User and Password are used by postgres
+	Host            string `yaml:"host"`
+	Port            string `yaml:"port"`
+	Dbname          string `yaml:"dbname" env:"POSTGRES_DB"`
+	User            string `yaml:"user" env:"POSTGRES_USER"`
+	Password        string `yaml:"password" env:"POSTGRES_PASSWORD"`
 	Migrations_path string `yaml:"migrations_path" env-required:"true"`
 }
 
 type GRPC struct {
+	Host string `yaml:"host" env-default:""`
+	Port string `yaml:"port" env-default:"44044"`
+	// Timeout is the deadline of every unary call unless the caller sets a sooner one
+	Timeout time.Duration `yaml:"timeout" env-default:"15s"`
+	// MethodTimeouts overrides Timeout by full method, 0 means no deadline
+	MethodTimeouts map[string]time.Duration `yaml:"method_timeouts"`
+	// Reflection exposes the schema of the services to tools like grpcurl, meant for dev
+	Reflection bool `yaml:"reflection" env-default:"false"`
+	TLS        `yaml:"tls"`
+}
+
+// TLS of the gRPC listener, off while cert_file is empty. Files are reloaded when they change
+type TLS struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile verifies client certificates, required unless ClientAuth is "none"
+	ClientCAFile string `yaml:"client_ca_file"`
+	// ClientAuth is "none", "optional" to verify certificates sent by clients or "require"
+	ClientAuth     string        `yaml:"client_auth" env-default:"none"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+	// RequireClientCert lists rules (admin, creator...) or full methods that need a client certificate
+	RequireClientCert []string `yaml:"require_client_cert"`
+}
+
+// HTTP is the HTTP/JSON gateway to the Auth, Permissions and Apps services
+type HTTP struct {
 	Host    string        `yaml:"host" env-default:""`
-	Port    string        `yaml:"port" env-default:"44044"`
+	Port    string        `yaml:"port" env-default:"8080"`
 	Timeout time.Duration `yaml:"timeout" env-default:"15s"`
+	TLS     HTTPTLS       `yaml:"tls"`
+}
+
+// HTTPTLS of the gateway listener, off while cert_file is empty. Files are reloaded when they change
+type HTTPTLS struct {
+	CertFile       string        `yaml:"cert_file"`
+	KeyFile        string        `yaml:"key_file"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+}
+
+// Health is how often the grpc.health.v1 status is refreshed from Postgres
+type Health struct {
+	Interval time.Duration `yaml:"interval" env-default:"10s"`
+	Timeout  time.Duration `yaml:"timeout" env-default:"2s"`
+}
+
+// Metrics is the Prometheus endpoint, served at /metrics on its own port
+type Metrics struct {
+	Host string `yaml:"host" env-default:""`
+	Port string `yaml:"port" env-default:"9090"`
+	// MaxApps is how many apps get their own series, the rest are labeled "other"
+	MaxApps int `yaml:"max_apps" env-default:"100"`
+}
+
+// Tracing is where OpenTelemetry spans go, trace ids are added to logs with any exporter
+type Tracing struct {
+	// Exporter is "none", "otlp", "stdout" or "file"
+	Exporter string `yaml:"exporter" env-default:"none"`
+	// Endpoint is host:port of the OTLP gRPC collector
+	Endpoint string `yaml:"endpoint" env-default:"localhost:4317"`
+	Insecure bool   `yaml:"insecure" env-default:"true"`
+	// File is used by the file exporter
+	File        string  `yaml:"file" env-default:"traces.json"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
+type JWT struct {
+	// Issuer is the iss claim of issued tokens, verifiers reject other issuers
+	Issuer string `yaml:"issuer" env-default:"sso"`
+	// ClockSkew is allowed when checking exp, nbf and iat
+	ClockSkew time.Duration `yaml:"clock_skew" env-default:"30s"`
+}
+
+type Accounts struct {
+	// Retention is how long a deleted account can be restored before it is purged
+	Retention     time.Duration `yaml:"retention" env-default:"720h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+	// PurgeMode is "anonymize" to keep an anonymous user row or "delete" to remove it
+	PurgeMode string `yaml:"purge_mode" env-default:"anonymize"`
+	// Operators are ids of users that may suspend, restore and delete any account.
+	// Accounts are shared by all apps, so being admin of an app isn't enough
+	Operators []uint64 `yaml:"operators"`
+}
+
+type Audit struct {
+	// EmailKey keys the hashes the audit log keeps instead of target emails, so the append-only
+	// log holds no plain email. Changing it hides older entries from data exports
+	EmailKey string `yaml:"email_key" env:"AUDIT_EMAIL_KEY" env-required:"true"`
+}
+
+type Webhooks struct {
+	DispatchInterval time.Duration `yaml:"dispatch_interval" env-default:"5s"`
+	// MaxAttempts is how many times a delivery is tried before it goes to the dead-letter table
+	MaxAttempts int           `yaml:"max_attempts" env-default:"8"`
+	Timeout     time.Duration `yaml:"timeout" env-default:"10s"`
+	BatchSize   int           `yaml:"batch_size" env-default:"50"`
+}
+
+type Events struct {
+	// PollInterval is how often WatchEvents streams check for events when no notification came
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"10s"`
+}
+
+type Hooks struct {
+	// DefaultTimeout is used for hooks configured without a timeout
+	DefaultTimeout time.Duration `yaml:"default_timeout" env-default:"2s"`
+	// GRPCInsecure dials gRPC hooks without TLS, by default TLS is verified with the system roots
+	GRPCInsecure bool `yaml:"grpc_insecure" env-default:"false"`
+	// ConnIdleTimeout closes connections to gRPC hook services unused for this long,
+	// like the ones of removed hooks
+	ConnIdleTimeout time.Duration `yaml:"conn_idle_timeout" env-default:"5m"`
+}
+
+// Egress limits where hooks and webhooks of apps may connect. Loopback, link-local,
+// private and reserved addresses are refused unless AllowedNets has them
+type Egress struct {
+	// AllowedNets are CIDRs or single addresses, like "10.1.0.0/16"
+	AllowedNets []string `yaml:"allowed_nets"`
+}
+
+// Sessions are defaults for apps without their own session settings, 0 disables a limit
+type Sessions struct {
+	RefreshTTL       time.Duration `yaml:"refresh_ttl" env-default:"720h"`
+	IdleTimeout      time.Duration `yaml:"idle_timeout" env-default:"0s"`
+	AbsoluteLifetime time.Duration `yaml:"absolute_lifetime" env-default:"0s"`
+	MaxSessions      int           `yaml:"max_sessions" env-default:"0"`
+	// PurgeInterval is how often ended sessions are deleted
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+}
+
+// Permissions set which roles may grant and revoke which roles, empty means
+// creators manage creators and admins, admins manage admins
+type Permissions struct {
+	Matrix map[string][]string `yaml:"matrix"`
+}
+
+type Impersonation struct {
+	// TTL is the longest lifetime of impersonation tokens, never longer than access tokens of the app
+	TTL time.Duration `yaml:"ttl" env-default:"15m"`
 }
 
 // Must - обозначает, что функция либо выполнится, либо вызовет панику
@@ -1,7 +1,46 @@
 package models
 
+import "time"
+
 type App struct {
 	ID     int
 	Name   string
 	Secret string
+	// ClaimMapping maps claim names to expressions added to tokens of the app
+	ClaimMapping map[string]string
+	Settings     AppSettings
+	CreatedAt    time.Time
+}
+
+// AppSettings are the token and session policies of the app.
+// Zero values mean the service defaults
+type AppSettings struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// IdleTimeout ends a session that was not refreshed for this long
+	IdleTimeout time.Duration
+	// AbsoluteLifetime ends a session this long after login, however often it is refreshed
+	AbsoluteLifetime time.Duration
+	// MaxSessions is how many sessions a user may have in the app, the oldest are revoked
+	MaxSessions int
+}
+
+// Or returns the settings with zero values taken from def
+func (s AppSettings) Or(def AppSettings) AppSettings {
+	if s.AccessTTL == 0 {
+		s.AccessTTL = def.AccessTTL
+	}
+	if s.RefreshTTL == 0 {
+		s.RefreshTTL = def.RefreshTTL
+	}
+	if s.IdleTimeout == 0 {
+		s.IdleTimeout = def.IdleTimeout
+	}
+	if s.AbsoluteLifetime == 0 {
+		s.AbsoluteLifetime = def.AbsoluteLifetime
+	}
+	if s.MaxSessions == 0 {
+		s.MaxSessions = def.MaxSessions
+	}
+	return s
 }
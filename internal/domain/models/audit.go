@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	AuditLogin   = "login"
+	AuditRefresh = "refresh"
+	AuditLogout  = "logout"
+	// AuditImpersonate is recorded with the operator as actor and the impersonated user as target
+	AuditImpersonate = "impersonate"
+	// AuditServiceToken is recorded when a service account exchanges an API key for a token
+	AuditServiceToken = "service_token"
+	AuditRegister     = "register"
+	AuditSetAdmin     = "set_admin"
+	AuditDelAdmin     = "del_admin"
+	AuditSetApp       = "set_app"
+	AuditUpdApp       = "upd_app"
+	AuditDelApp       = "del_app"
+
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEntry is one record of the append-only audit log.
+// Hash covers PrevHash and all stored fields except ID, which chains entries together
+type AuditEntry struct {
+	ID        uint64
+	CreatedAt time.Time
+	Action    string
+	ActorID   uint64
+	// ActorKind is UserHuman or UserService, empty means human
+	ActorKind UserKind
+	// OperatorID is the support user impersonating the actor, zero when the actor acted itself
+	OperatorID uint64
+	Target     string
+	// TargetEmail is not stored, the audit service writes its keyed hash to Target
+	TargetEmail string
+	AppName     string
+	IP          string
+	Outcome     string
+	Reason      string
+	PrevHash    []byte
+	Hash        []byte
+}
+
+// AuditFilter selects entries of the audit log, zero values mean "any"
+type AuditFilter struct {
+	AppName       string
+	Action        string
+	ActorID       uint64
+	ActorKind     UserKind
+	OperatorID    uint64
+	Outcome       string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	BeforeID      uint64
+	Limit         int
+}
+
+// WithResult sets outcome and reason of the entry from the error returned by
+// the audited operation. Only known errors are used as reason, so internal
+// details never get into the audit log
+func (e AuditEntry) WithResult(err error, known ...error) AuditEntry {
+	if err == nil {
+		e.Outcome = AuditSuccess
+		return e
+	}
+	e.Outcome = AuditFailure
+	e.Reason = "internal error"
+	for _, k := range known {
+		if errors.Is(err, k) {
+			e.Reason = k.Error()
+			break
+		}
+	}
+	return e
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventUserRegistered    = "user.registered"
+	EventUserEmailChanged  = "user.email_changed"
+	EventUserDeleted       = "user.deleted"
+	EventUserStatusChanged = "user.status_changed"
+	EventAppUpdated        = "app.updated"
+	EventAppDeleted        = "app.deleted"
+	EventAdminGranted      = "admin.granted"
+	EventAdminRevoked      = "admin.revoked"
+)
+
+// EventTypes are all events that can be subscribed to
+var EventTypes = []string{
+	EventUserRegistered,
+	EventUserEmailChanged,
+	EventUserDeleted,
+	EventUserStatusChanged,
+	EventAppUpdated,
+	EventAppDeleted,
+	EventAdminGranted,
+	EventAdminRevoked,
+}
+
+// Event is an identity change stored in the outbox. AppID is zero for
+// user events, which are delivered to the apps the user is a member of
+type Event struct {
+	ID        uint64
+	Type      string
+	AppID     int
+	Payload   json.RawMessage
+	CreatedAt time.Time
+}
+
+type Webhook struct {
+	ID         int
+	AppID      int
+	URL        string
+	Secret     string
+	EventTypes []string
+	CreatedAt  time.Time
+}
+
+// Delivery is one pending attempt to send an event to a webhook
+type Delivery struct {
+	ID       uint64
+	Webhook  Webhook
+	Event    Event
+	Attempts int
+}
+
+type DeadLetter struct {
+	ID        uint64
+	WebhookID int
+	EventID   uint64
+	EventType string
+	Attempts  int
+	LastError string
+	FailedAt  time.Time
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Hook stages, in the order they run
+const (
+	HookPreRegister  = "pre_register"
+	HookPostRegister = "post_register"
+	HookPreLogin     = "pre_login"
+	HookTokenMint    = "token_mint"
+)
+
+var HookStages = []string{HookPreRegister, HookPostRegister, HookPreLogin, HookTokenMint}
+
+// Hook kinds: how the external hook service is called
+const (
+	HookHTTP = "http"
+	HookGRPC = "grpc"
+)
+
+// Hook is an external call made by the auth service at one stage of login or registration.
+// FailOpen hooks are skipped when they fail or time out, other hooks fail the request
+type Hook struct {
+	ID       int
+	AppID    int
+	Stage    string
+	Kind     string
+	Target   string
+	Timeout  time.Duration
+	FailOpen bool
+}
+
+// HookInput is what the hook service gets. Claims are set only at the token_mint stage
+type HookInput struct {
+	Stage   string
+	AppName string
+	UserID  uint64
+	Email   string
+	IP      string
+	Claims  map[string]any
+}
+
+// HookResult is the answer of a hook service. Claims are added to the token at the token_mint stage
+type HookResult struct {
+	Deny   bool
+	Reason string
+	Claims map[string]any
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ListFilter describes one page of a keyset-paginated listing.
+// Zero values mean "no restriction".
+type ListFilter struct {
+	Prefix        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	AfterID       uint64
+	Limit         int
+}
+
+// Member is a user holding a role (admin or creator) in an app.
+type Member struct {
+	UserID    uint64
+	Email     string
+	CreatedAt time.Time
+}
@@ -0,0 +1,35 @@
+package models
+
+import "encoding/json"
+
+// Profile is a user together with its metadata in one app.
+// AppMetadata is writable only by app admins, UserMetadata by the user himself.
+type Profile struct {
+	User         User
+	AppID        int
+	AppMetadata  json.RawMessage
+	UserMetadata json.RawMessage
+}
+
+// ProfileUpdate holds the profile fields to change, nil means "keep as is"
+type ProfileUpdate struct {
+	DisplayName *string
+	Locale      *string
+	AvatarURL   *string
+}
+
+// MetadataSchemas are the optional per-app JSON Schemas of metadata
+type MetadataSchemas struct {
+	AppMetadata  json.RawMessage
+	UserMetadata json.RawMessage
+}
+
+// AppMembership is everything that links a user to one app
+type AppMembership struct {
+	AppID        int
+	AppName      string
+	IsCreator    bool
+	IsAdmin      bool
+	AppMetadata  json.RawMessage
+	UserMetadata json.RawMessage
+}
@@ -0,0 +1,24 @@
+package models
+
+const (
+	RoleCreator = "creator"
+	RoleAdmin   = "admin"
+	// RoleSupport may impersonate users of the app without roles
+	RoleSupport = "support"
+)
+
+// RoleLevels rank roles of an app, a higher level has every power of the lower ones
+var RoleLevels = map[string]int{
+	RoleSupport: 1,
+	RoleAdmin:   1,
+	RoleCreator: 2,
+}
+
+// MaxRoleLevel returns the highest level of the roles, 0 for no roles
+func MaxRoleLevel(roles []string) int {
+	level := 0
+	for _, r := range roles {
+		level = max(level, RoleLevels[r])
+	}
+	return level
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ServiceAccount is a non-human user owned by an app. It has no password
+// and authenticates with API keys. ClientID is stored as the email of the user
+type ServiceAccount struct {
+	UserID    uint64
+	ClientID  string
+	Name      string
+	AppID     int
+	AppName   string
+	CreatedBy uint64
+	Status    UserStatus
+	CreatedAt time.Time
+}
+
+// APIKey is a secret of a service account, only the sha256 of the key and its prefix are stored
+type APIKey struct {
+	ID     uint64
+	UserID uint64
+	// Prefix is the start of the key, shown to tell keys apart
+	Prefix    string
+	CreatedAt time.Time
+	// ExpiresAt is zero for keys without expiry
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	Revoked    bool
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Session is a login of the user to the app, kept alive by refresh tokens.
+// Only the sha256 of refresh tokens is stored
+type Session struct {
+	ID               uint64
+	UserID           uint64
+	AppID            int
+	RefreshHash      string
+	IP               string
+	CreatedAt        time.Time
+	LastUsedAt       time.Time
+	RefreshExpiresAt time.Time
+	// ExpiresAt is zero when the app has no absolute session lifetime
+	ExpiresAt time.Time
+	Revoked   bool
+	// Reused is set when the session was found by an already rotated refresh token
+	Reused bool
+}
+
+// Tokens are returned by login and refresh
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+}
@@ -1,7 +1,33 @@
 package models
 
+import "time"
+
+type UserStatus string
+
+const (
+	UserActive          UserStatus = "active"
+	UserSuspended       UserStatus = "suspended"
+	UserPendingDeletion UserStatus = "pending_deletion"
+	UserDeleted         UserStatus = "deleted"
+)
+
+// UserKind tells humans apart from service accounts
+type UserKind string
+
+const (
+	UserHuman   UserKind = "human"
+	UserService UserKind = "service"
+)
+
 type User struct {
-	ID       uint64
-	Email    string
-	PassHash []byte
+	ID          uint64
+	Kind        UserKind
+	Email       string
+	PassHash    []byte
+	DisplayName string
+	Locale      string
+	AvatarURL   string
+	Status      UserStatus
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
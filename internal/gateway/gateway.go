@@ -0,0 +1,300 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/clientip"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/requestid"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// maxBodySize bounds request bodies, the largest requests are metadata schemas
+const maxBodySize = 1 << 20
+
+// Service is a gRPC service mirrored under /v1/<Prefix>/
+type Service struct {
+	Prefix     string
+	Descriptor protoreflect.ServiceDescriptor
+}
+
+// route is a unary method reachable by POST /v1/<prefix>/<method in kebab case>
+type route struct {
+	path       string
+	fullMethod string
+	service    string
+	method     protoreflect.MethodDescriptor
+	input      protoreflect.MessageType
+	output     protoreflect.MessageType
+}
+
+// Gateway is an HTTP/JSON mirror of gRPC services. Calls go through the gRPC client,
+// so authorization and the rest of the interceptors apply to them as to gRPC calls
+type Gateway struct {
+	log     *slog.Logger
+	conn    grpc.ClientConnInterface
+	mux     *http.ServeMux
+	routes  []route
+	openapi []byte
+}
+
+var (
+	marshaler   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	unmarshaler = protojson.UnmarshalOptions{}
+	// detailMarshaler leaves out empty fields, like metadata of ErrorInfo
+	detailMarshaler = protojson.MarshalOptions{UseProtoNames: true}
+)
+
+func New(log *slog.Logger, conn grpc.ClientConnInterface, services ...Service) (*Gateway, error) {
+	const op = "gateway.New"
+
+	g := &Gateway{log: log, conn: conn, mux: http.NewServeMux()}
+	for _, svc := range services {
+		methods := svc.Descriptor.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			if method.IsStreamingClient() || method.IsStreamingServer() {
+				continue
+			}
+			input, err := protoregistry.GlobalTypes.FindMessageByName(method.Input().FullName())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			output, err := protoregistry.GlobalTypes.FindMessageByName(method.Output().FullName())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			g.routes = append(g.routes, route{
+				path:       "/v1/" + svc.Prefix + "/" + kebab(string(method.Name())),
+				fullMethod: fmt.Sprintf("/%s/%s", svc.Descriptor.FullName(), method.Name()),
+				service:    string(svc.Descriptor.Name()),
+				method:     method,
+				input:      input,
+				output:     output,
+			})
+		}
+	}
+
+	doc, err := openAPI(g.routes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	g.openapi = doc
+
+	for _, r := range g.routes {
+		g.mux.Handle(r.path, g.handle(r))
+	}
+	g.mux.HandleFunc("/openapi.json", g.serveOpenAPI)
+	g.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, grpcerr.New(languageContext(r), codes.NotFound, grpcerr.ReasonRouteNotFound))
+	})
+	return g, nil
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *Gateway) handle(rt route) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeStatus(w, http.StatusMethodNotAllowed, grpcerr.WithMessage(languageContext(r), codes.Unimplemented, grpcerr.ReasonMethodNotAllowed, "method not allowed, use POST"))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeError(w, grpcerr.New(languageContext(r), codes.ResourceExhausted, grpcerr.ReasonRequestTooLarge))
+				return
+			}
+			writeError(w, grpcerr.WithMessage(languageContext(r), codes.InvalidArgument, grpcerr.ReasonInvalidBody, "failed to read request body"))
+			return
+		}
+		in := rt.input.New().Interface()
+		if len(body) > 0 {
+			if err := unmarshaler.Unmarshal(body, in); err != nil {
+				writeError(w, grpcerr.WithMessage(languageContext(r), codes.InvalidArgument, grpcerr.ReasonInvalidBody, "invalid request body: "+err.Error()))
+				return
+			}
+		}
+
+		md := metadata.MD{}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			md.Set("authorization", auth)
+		}
+		if id := r.Header.Get(requestid.Header); id != "" {
+			md.Set(requestid.Header, id)
+		}
+		if lang := r.Header.Get("Accept-Language"); lang != "" {
+			md.Set(grpcerr.LanguageHeader, lang)
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			md.Set(clientip.ForwardedHeader, host)
+		}
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx = metadata.NewOutgoingContext(ctx, md)
+
+		out := rt.output.New().Interface()
+		var header metadata.MD
+		err = g.conn.Invoke(ctx, rt.fullMethod, in, out, grpc.Header(&header))
+		if ids := header.Get(requestid.Header); len(ids) > 0 {
+			w.Header().Set(requestid.Header, ids[0])
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		g.write(w, http.StatusOK, out)
+	}
+}
+
+func (g *Gateway) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet)
+		writeStatus(w, http.StatusMethodNotAllowed, grpcerr.WithMessage(languageContext(r), codes.Unimplemented, grpcerr.ReasonMethodNotAllowed, "method not allowed, use GET"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(g.openapi)
+}
+
+func (g *Gateway) write(w http.ResponseWriter, code int, msg proto.Message) {
+	body, err := marshaler.Marshal(msg)
+	if err != nil {
+		g.log.Error("failed to marshal response", sl.Err(err))
+		writeError(w, grpcerr.New(context.Background(), codes.Internal, grpcerr.ReasonInternal))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
+
+// errorBody is the JSON body of failed calls, the code and message are those of the gRPC status
+// and details are its details in JSON with their @type, like ErrorInfo with the reason
+type errorBody struct {
+	Error errorStatus `json:"error"`
+}
+
+type errorStatus struct {
+	Code    int               `json:"code"`
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Details []json.RawMessage `json:"details,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeStatus(w, HTTPStatus(status.Code(err)), err)
+}
+
+func writeStatus(w http.ResponseWriter, code int, err error) {
+	st := status.Convert(err)
+	errStatus := errorStatus{
+		Code:    code,
+		Status:  statusName(st.Code()),
+		Message: st.Message(),
+	}
+	for _, detail := range st.Proto().GetDetails() {
+		data, err := detailMarshaler.Marshal(detail)
+		if err != nil {
+			continue
+		}
+		errStatus.Details = append(errStatus.Details, data)
+	}
+	for _, detail := range st.Details() {
+		if retry, ok := detail.(*errdetails.RetryInfo); ok {
+			seconds := int(math.Ceil(retry.GetRetryDelay().AsDuration().Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		}
+	}
+	body, _ := json.Marshal(errorBody{Error: errStatus})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
+
+// languageContext carries Accept-Language of the request to messages of errors
+// that the gateway returns itself
+func languageContext(r *http.Request) context.Context {
+	return metadata.NewIncomingContext(r.Context(), metadata.Pairs(grpcerr.LanguageHeader, r.Header.Get("Accept-Language")))
+}
+
+// HTTPStatus maps a gRPC code to the HTTP status of the gateway response
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// statusName returns the code in the upper snake case of the gRPC spec, NOT_FOUND for NotFound
+func statusName(code codes.Code) string {
+	if code == codes.Canceled {
+		return "CANCELLED"
+	}
+	return strings.ToUpper(strings.ReplaceAll(kebab(code.String()), "-", "_"))
+}
+
+// kebab turns a method name into a path segment, GetUserID into get-user-id
+func kebab(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
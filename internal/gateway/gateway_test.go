@@ -0,0 +1,237 @@
+package gateway_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/neepooha/sso/internal/gateway"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.OK, http.StatusOK},
+		{codes.Canceled, 499},
+		{codes.Unknown, http.StatusInternalServerError},
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.PermissionDenied, http.StatusForbidden},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.FailedPrecondition, http.StatusBadRequest},
+		{codes.Aborted, http.StatusConflict},
+		{codes.OutOfRange, http.StatusBadRequest},
+		{codes.Unimplemented, http.StatusNotImplemented},
+		{codes.Internal, http.StatusInternalServerError},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.DataLoss, http.StatusInternalServerError},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+		{codes.Code(99), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			if got := gateway.HTTPStatus(tt.code); got != tt.want {
+				t.Errorf("HTTPStatus(%s) = %d, want %d", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+// failingConn answers every call with err
+type failingConn struct {
+	err error
+}
+
+func (c failingConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return c.err
+}
+
+func (c failingConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, c.err
+}
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		err        error
+		wantCode   int
+		wantStatus string
+		wantReason string
+	}{
+		{
+			name:       "invalid credentials",
+			err:        grpcerr.New(context.Background(), codes.InvalidArgument, grpcerr.ReasonInvalidCredentials),
+			wantCode:   http.StatusBadRequest,
+			wantStatus: "INVALID_ARGUMENT",
+			wantReason: string(grpcerr.ReasonInvalidCredentials),
+		},
+		{
+			name:       "not admin",
+			err:        grpcerr.New(context.Background(), codes.PermissionDenied, grpcerr.ReasonNotAdmin),
+			wantCode:   http.StatusForbidden,
+			wantStatus: "PERMISSION_DENIED",
+			wantReason: string(grpcerr.ReasonNotAdmin),
+		},
+		{
+			name:       "missing token",
+			err:        grpcerr.New(context.Background(), codes.Unauthenticated, grpcerr.ReasonMissingCredentials),
+			wantCode:   http.StatusUnauthorized,
+			wantStatus: "UNAUTHENTICATED",
+			wantReason: string(grpcerr.ReasonMissingCredentials),
+		},
+		{
+			name:       "cancelled",
+			err:        status.Error(codes.Canceled, "cancelled"),
+			wantCode:   499,
+			wantStatus: "CANCELLED",
+		},
+		{
+			name:       "deadline",
+			err:        status.Error(codes.DeadlineExceeded, "deadline exceeded"),
+			wantCode:   http.StatusGatewayTimeout,
+			wantStatus: "DEADLINE_EXCEEDED",
+		},
+		{
+			name:       "not a status",
+			err:        io.ErrUnexpectedEOF,
+			wantCode:   http.StatusInternalServerError,
+			wantStatus: "UNKNOWN",
+		},
+		{
+			name:       "unknown route",
+			path:       "/v1/auth/nope",
+			wantCode:   http.StatusNotFound,
+			wantStatus: "NOT_FOUND",
+			wantReason: string(grpcerr.ReasonRouteNotFound),
+		},
+		{
+			name:       "wrong http method",
+			method:     http.MethodGet,
+			wantCode:   http.StatusMethodNotAllowed,
+			wantStatus: "UNIMPLEMENTED",
+			wantReason: string(grpcerr.ReasonMethodNotAllowed),
+		},
+		{
+			name:       "invalid body",
+			body:       `{"email":`,
+			wantCode:   http.StatusBadRequest,
+			wantStatus: "INVALID_ARGUMENT",
+			wantReason: string(grpcerr.ReasonInvalidBody),
+		},
+		{
+			name:       "body too large",
+			body:       `{"email":"` + strings.Repeat("a", 1<<20) + `"}`,
+			wantCode:   http.StatusTooManyRequests,
+			wantStatus: "RESOURCE_EXHAUSTED",
+			wantReason: string(grpcerr.ReasonRequestTooLarge),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw, err := gateway.New(slog.New(slog.NewTextHandler(io.Discard, nil)), failingConn{err: tt.err},
+				gateway.Service{Prefix: "auth", Descriptor: ssov2.File_sso_auth_proto.Services().ByName("Auth")},
+			)
+			if err != nil {
+				t.Fatalf("gateway.New: %v", err)
+			}
+			method, path, body := tt.method, tt.path, tt.body
+			if method == "" {
+				method = http.MethodPost
+			}
+			if path == "" {
+				path = "/v1/auth/login"
+			}
+			if body == "" {
+				body = `{"email":"user@example.com"}`
+			}
+
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("HTTP status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			var resp struct {
+				Error struct {
+					Code    int    `json:"code"`
+					Status  string `json:"status"`
+					Details []struct {
+						Type   string `json:"@type"`
+						Reason string `json:"reason"`
+					} `json:"details"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("error body %s: %v", rec.Body, err)
+			}
+			if resp.Error.Code != tt.wantCode || resp.Error.Status != tt.wantStatus {
+				t.Errorf("error = %d %s, want %d %s", resp.Error.Code, resp.Error.Status, tt.wantCode, tt.wantStatus)
+			}
+			var reason string
+			for _, d := range resp.Error.Details {
+				if strings.HasSuffix(d.Type, "ErrorInfo") {
+					reason = d.Reason
+				}
+			}
+			if reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+		want  string
+	}{
+		{name: "whole seconds", delay: 30 * time.Second, want: "30"},
+		{name: "rounded up", delay: 1500 * time.Millisecond, want: "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := status.New(codes.ResourceExhausted, "too many attempts").
+				WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(tt.delay)})
+			if err != nil {
+				t.Fatalf("WithDetails: %v", err)
+			}
+			gw, err := gateway.New(slog.New(slog.NewTextHandler(io.Discard, nil)), failingConn{err: st.Err()},
+				gateway.Service{Prefix: "auth", Descriptor: ssov2.File_sso_auth_proto.Services().ByName("Auth")},
+			)
+			if err != nil {
+				t.Fatalf("gateway.New: %v", err)
+			}
+
+			rec := httptest.NewRecorder()
+			gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/auth/login", strings.NewReader(`{}`)))
+
+			if rec.Code != http.StatusTooManyRequests {
+				t.Errorf("HTTP status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.want {
+				t.Errorf("Retry-After = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"encoding/json"
+	"github.com/neepooha/sso/internal/grpc/authz"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// schema is the subset of the OpenAPI schema object the gateway needs
+type schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	AdditionalProperties *schema            `json:"additionalProperties,omitempty"`
+}
+
+// openAPI returns the OpenAPI 3 document of the routes, request and response
+// schemas follow the protojson encoding used by the gateway
+func openAPI(routes []route) ([]byte, error) {
+	schemas := map[string]*schema{
+		"Error": {
+			Type: "object",
+			Properties: map[string]*schema{
+				"error": {
+					Type: "object",
+					Properties: map[string]*schema{
+						"code":    {Type: "integer", Format: "int32"},
+						"status":  {Type: "string"},
+						"message": {Type: "string"},
+						"details": {Type: "array", Items: &schema{
+							Type:                 "object",
+							Properties:           map[string]*schema{"@type": {Type: "string"}},
+							AdditionalProperties: &schema{},
+						}},
+					},
+				},
+			},
+		},
+	}
+	paths := map[string]any{}
+	for _, rt := range routes {
+		operation := map[string]any{
+			"operationId": rt.service + "_" + string(rt.method.Name()),
+			"tags":        []string{rt.service},
+			"requestBody": map[string]any{
+				"required": true,
+				"content":  jsonContent(messageSchema(rt.method.Input(), schemas)),
+			},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     jsonContent(messageSchema(rt.method.Output(), schemas)),
+				},
+				"default": map[string]any{
+					"description": "Error with the gRPC status of the call",
+					"content":     jsonContent(&schema{Ref: "#/components/schemas/Error"}),
+				},
+			},
+		}
+		if authz.Rules[rt.fullMethod] != authz.Public {
+			operation["security"] = []map[string][]string{{"bearer": {}}, {"apiKey": {}}}
+		}
+		paths[rt.path] = map[string]any{"post": operation}
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": "sso", "version": "v1"},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey": map[string]string{"type": "apiKey", "in": "header", "name": "Authorization",
+					"description": `service account key as "ApiKey <key>"`},
+			},
+		},
+	}, "", "  ")
+}
+
+func jsonContent(s *schema) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": s}}
+}
+
+// messageSchema adds the schema of the message and the messages it uses to schemas
+// and returns a reference to it. Well-known types are inlined as protojson encodes them
+func messageSchema(msg protoreflect.MessageDescriptor, schemas map[string]*schema) *schema {
+	switch msg.FullName() {
+	case "google.protobuf.Timestamp":
+		return &schema{Type: "string", Format: "date-time"}
+	case "google.protobuf.Duration":
+		return &schema{Type: "string"}
+	case "google.protobuf.Struct":
+		return &schema{Type: "object"}
+	case "google.protobuf.Value":
+		return &schema{}
+	case "google.protobuf.ListValue":
+		return &schema{Type: "array", Items: &schema{}}
+	}
+
+	name := string(msg.FullName())
+	ref := &schema{Ref: "#/components/schemas/" + name}
+	if _, ok := schemas[name]; ok {
+		return ref
+	}
+	s := &schema{Type: "object", Properties: map[string]*schema{}}
+	schemas[name] = s
+	fields := msg.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		s.Properties[string(field.Name())] = fieldSchema(field, schemas)
+	}
+	return ref
+}
+
+func fieldSchema(field protoreflect.FieldDescriptor, schemas map[string]*schema) *schema {
+	if field.IsMap() {
+		return &schema{Type: "object", AdditionalProperties: fieldSchema(field.MapValue(), schemas)}
+	}
+	s := kindSchema(field, schemas)
+	if field.IsList() {
+		return &schema{Type: "array", Items: s}
+	}
+	return s
+}
+
+func kindSchema(field protoreflect.FieldDescriptor, schemas map[string]*schema) *schema {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return &schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &schema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &schema{Type: "integer", Format: "uint32"}
+	// protojson encodes 64-bit integers as strings
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &schema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &schema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &schema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &schema{Type: "number", Format: "double"}
+	case protoreflect.BytesKind:
+		return &schema{Type: "string", Format: "byte"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		s := &schema{Type: "string"}
+		for i := 0; i < values.Len(); i++ {
+			s.Enum = append(s.Enum, string(values.Get(i).Name()))
+		}
+		return s
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSchema(field.Message(), schemas)
+	}
+	return &schema{Type: "string"}
+}
@@ -0,0 +1,150 @@
+package accounts
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/services/accounts"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type Accounts interface {
+	SetUserStatus(ctx context.Context, appName string, userID uint64, status models.UserStatus) (models.UserStatus, error)
+	DeleteUser(ctx context.Context, appName string, userID uint64) (purgeAfter time.Time, err error)
+	ExportUserData(ctx context.Context, appName string, userID uint64) (archive []byte, err error)
+	ChangeEmail(ctx context.Context, appName string, email string, password string) (string, error)
+}
+
+type SetUserStatusReq struct {
+	AppName string `validate:"required"`
+	UserID  uint64 `validate:"required"`
+	Status  string `validate:"required,oneof=active suspended"`
+}
+
+type DeleteUserReq struct {
+	AppName string `validate:"required"`
+}
+
+type ExportUserDataReq struct {
+	AppName string `validate:"required"`
+}
+
+type ChangeEmailReq struct {
+	AppName  string `validate:"required"`
+	Email    string `validate:"required,email"`
+	Password string `validate:"required"`
+}
+
+var errs = grpcerr.Table{
+	{Err: accounts.ErrNotOperator, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotOperator},
+	{Err: accounts.ErrInvalidStatus, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidStatus},
+	{Err: accounts.ErrUserExists, Code: codes.AlreadyExists, Reason: grpcerr.ReasonUserExists},
+	{Err: accounts.ErrInvalidCredentials, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+}
+
+type serverAPI struct {
+	ssov2.UnimplementedAccountsServer
+	accounts Accounts
+}
+
+func Register(gRPC *grpc.Server, accounts Accounts) {
+	ssov2.RegisterAccountsServer(gRPC, &serverAPI{accounts: accounts})
+}
+
+func (s *serverAPI) SetUserStatus(ctx context.Context, req *ssov2.SetUserStatusRequest) (*ssov2.SetUserStatusResponse, error) {
+	if err := ValidateSetStatus(ctx, req); err != nil {
+		return nil, err
+	}
+
+	userStatus, err := s.accounts.SetUserStatus(ctx, req.GetAppName(), req.GetUserId(), models.UserStatus(req.GetStatus()))
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.SetUserStatusResponse{Status: string(userStatus)}, nil
+}
+
+func (s *serverAPI) DeleteUser(ctx context.Context, req *ssov2.DeleteUserRequest) (*ssov2.DeleteUserResponse, error) {
+	if err := ValidateDelete(ctx, req); err != nil {
+		return nil, err
+	}
+
+	purgeAfter, err := s.accounts.DeleteUser(ctx, req.GetAppName(), req.GetUserId())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.DeleteUserResponse{PurgeAfter: pagination.Timestamp(purgeAfter)}, nil
+}
+
+func (s *serverAPI) ExportUserData(ctx context.Context, req *ssov2.ExportUserDataRequest) (*ssov2.ExportUserDataResponse, error) {
+	if err := ValidateExport(ctx, req); err != nil {
+		return nil, err
+	}
+
+	archive, err := s.accounts.ExportUserData(ctx, req.GetAppName(), req.GetUserId())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.ExportUserDataResponse{Archive: archive, ContentType: "application/json"}, nil
+}
+
+func (s *serverAPI) ChangeEmail(ctx context.Context, req *ssov2.ChangeEmailRequest) (*ssov2.ChangeEmailResponse, error) {
+	if err := ValidateChangeEmail(ctx, req); err != nil {
+		return nil, err
+	}
+
+	email, err := s.accounts.ChangeEmail(ctx, req.GetAppName(), req.GetEmail(), req.GetPassword())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.ChangeEmailResponse{Email: email}, nil
+}
+
+func ValidateSetStatus(ctx context.Context, req *ssov2.SetUserStatusRequest) error {
+	var reqStruct SetUserStatusReq
+	reqStruct.AppName = req.GetAppName()
+	reqStruct.UserID = req.GetUserId()
+	reqStruct.Status = req.GetStatus()
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateDelete(ctx context.Context, req *ssov2.DeleteUserRequest) error {
+	var reqStruct DeleteUserReq
+	reqStruct.AppName = req.GetAppName()
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateExport(ctx context.Context, req *ssov2.ExportUserDataRequest) error {
+	var reqStruct ExportUserDataReq
+	reqStruct.AppName = req.GetAppName()
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateChangeEmail(ctx context.Context, req *ssov2.ChangeEmailRequest) error {
+	var reqStruct ChangeEmailReq
+	reqStruct.AppName = req.GetAppName()
+	reqStruct.Email = req.GetEmail()
+	reqStruct.Password = req.GetPassword()
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
@@ -3,15 +3,18 @@ package permissions
 import (
 	"context"
 	"errors"
-	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/claimmap"
+	"github.com/neepooha/sso/internal/lib/jsonstruct"
+	"github.com/neepooha/sso/internal/lib/pagination"
 	"github.com/neepooha/sso/internal/services/apps"
-	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	ssov2 "github.com/neepooha/protos/gen/go/sso"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type Apps interface {
@@ -19,6 +22,14 @@ type Apps interface {
 	SetApp(ctx context.Context, email string, appName string, appSecret string) (int, error)
 	UpdApp(ctx context.Context, appName string, newAppName string, newAppSecret string) (bool, error)
 	DelApp(ctx context.Context, appName string) (bool, error)
+	ListApps(ctx context.Context, filter models.ListFilter, creatorEmail string) ([]models.App, string, error)
+	SetMetadataSchema(ctx context.Context, appName string, schemas models.MetadataSchemas) (bool, error)
+	SetHooks(ctx context.Context, appName string, hooks []models.Hook) (bool, error)
+	ListHooks(ctx context.Context, appName string) ([]models.Hook, error)
+	SetClaimMapping(ctx context.Context, appName string, mapping map[string]string) (bool, error)
+	GetClaimMapping(ctx context.Context, appName string) (map[string]string, error)
+	SetAppSettings(ctx context.Context, appName string, settings models.AppSettings) (bool, error)
+	GetAppSettings(ctx context.Context, appName string) (models.AppSettings, error)
 }
 
 type GetAppIDReq struct {
@@ -41,6 +52,31 @@ type DelAppReq struct {
 	AppName string `validate:"required"`
 }
 
+type SetMetadataSchemaReq struct {
+	AppName string `validate:"required"`
+}
+
+type AppReq struct {
+	AppName string `validate:"required"`
+}
+
+type ListAppsReq struct {
+	AppName      string `validate:"required"`
+	CreatorEmail string `validate:"omitempty,email"`
+}
+
+var errs = grpcerr.Table{
+	{Err: apps.ErrInvalidCredentials, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+	{Err: apps.ErrNotCreator, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotCreator},
+	{Err: apps.ErrUserNotCreator, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotCreator},
+	{Err: apps.ErrNotOperator, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotOperator},
+	{Err: apps.ErrAppExists, Code: codes.AlreadyExists, Reason: grpcerr.ReasonAppExists},
+	{Err: apps.ErrInvalidSchema, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidSchema},
+	{Err: apps.ErrInvalidSettings, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidSettings},
+	{Err: apps.ErrInvalidHook, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidHook},
+	{Err: apps.ErrInvalidMapping, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidClaimMapping},
+}
+
 type serverAPI struct {
 	ssov2.UnimplementedAppsServer
 	apps Apps
@@ -51,138 +87,281 @@ func Register(gRPC *grpc.Server, apps Apps) {
 }
 
 func (s *serverAPI) GetAppID(ctx context.Context, req *ssov2.GetAppRequest) (*ssov2.GetAppResponse, error) {
-	if err := ValidateGet(req); err != nil {
+	if err := ValidateGet(ctx, req); err != nil {
 		return nil, err
 	}
 
 	appID, appName, err := s.apps.GetAppID(ctx, req.GetAppName())
 	if err != nil {
-		if errors.Is(err, apps.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, errs.Error(ctx, err)
 	}
 
 	return &ssov2.GetAppResponse{AppId: int32(appID), AppName: appName}, nil
 }
 
 func (s *serverAPI) SetApp(ctx context.Context, req *ssov2.SetAppRequest) (*ssov2.SetAppResponse, error) {
-	if err := ValidateSet(req); err != nil {
+	if err := ValidateSet(ctx, req); err != nil {
 		return nil, err
 	}
 
 	appID, err := s.apps.SetApp(ctx, req.GetEmail(), req.GetAppName(), req.GetAppSecret())
 	if err != nil {
-		if errors.Is(err, apps.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
-		}
-		if errors.Is(err, apps.ErrAppExists) {
-			return nil, status.Error(codes.InvalidArgument, "An application with the same name exists")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, errs.Error(ctx, err)
 	}
 
 	return &ssov2.SetAppResponse{AppID: int32(appID)}, nil
 }
 
 func (s *serverAPI) UpdApp(ctx context.Context, req *ssov2.UpdAppRequest) (*ssov2.UpdAppResponse, error) {
-	if err := ValidateUpd(req); err != nil {
+	if err := ValidateUpd(ctx, req); err != nil {
 		return nil, err
 	}
 	isUpdApp, err := s.apps.UpdApp(ctx, req.GetAppName(), req.GetNewAppName(), req.GetNewAppSecret())
 	if err != nil {
-		if errors.Is(err, apps.ErrUserNotCreator) {
-			return nil, status.Error(codes.Unauthenticated, "You are not creator")
-		}
-		if errors.Is(err, apps.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
-		}
-		if errors.Is(err, apps.ErrAppExists) {
-			return nil, status.Error(codes.InvalidArgument, "an application with the same name exists")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, errs.Error(ctx, err)
 	}
 	return &ssov2.UpdAppResponse{IsUpdApp: isUpdApp}, nil
 }
 
 func (s *serverAPI) DelApp(ctx context.Context, req *ssov2.DelAppRequest) (*ssov2.DelAppResponse, error) {
-	if err := ValidateDel(req); err != nil {
+	if err := ValidateDel(ctx, req); err != nil {
 		return nil, err
 	}
 
 	isDelApp, err := s.apps.DelApp(ctx, req.GetAppName())
 	if err != nil {
-		if errors.Is(err, apps.ErrUserNotCreator) {
-			return nil, status.Error(codes.Unauthenticated, "You are not creator")
-		}
-		if errors.Is(err, apps.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, errs.Error(ctx, err)
 	}
 
 	return &ssov2.DelAppResponse{IsDelApp: isDelApp}, nil
 }
 
-func ValidateGet(req *ssov2.GetAppRequest) error {
+func (s *serverAPI) ListApps(ctx context.Context, req *ssov2.ListAppsRequest) (*ssov2.ListAppsResponse, error) {
+	if err := ValidateList(ctx, req); err != nil {
+		return nil, err
+	}
+	filter, err := pagination.Filter(req.GetNamePrefix(), req.GetCreatedAfter(), req.GetCreatedBefore(), req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, grpcerr.Pagination(ctx, err)
+	}
+
+	list, next, err := s.apps.ListApps(ctx, filter, req.GetCreatorEmail())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+
+	resp := &ssov2.ListAppsResponse{NextPageToken: next}
+	for _, app := range list {
+		resp.Apps = append(resp.Apps, &ssov2.App{
+			AppId:     int32(app.ID),
+			AppName:   app.Name,
+			CreatedAt: pagination.Timestamp(app.CreatedAt),
+		})
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) SetMetadataSchema(ctx context.Context, req *ssov2.SetMetadataSchemaRequest) (*ssov2.SetMetadataSchemaResponse, error) {
+	if err := ValidateSetMetadataSchema(ctx, req); err != nil {
+		return nil, err
+	}
+	var schemas models.MetadataSchemas
+	var err error
+	if schemas.AppMetadata, err = jsonstruct.ToJSON(req.GetAppMetadataSchema()); err != nil {
+		return nil, grpcerr.InvalidField(ctx, grpcerr.ReasonInvalidSchema, "app_metadata_schema", "is not a valid JSON object")
+	}
+	if schemas.UserMetadata, err = jsonstruct.ToJSON(req.GetUserMetadataSchema()); err != nil {
+		return nil, grpcerr.InvalidField(ctx, grpcerr.ReasonInvalidSchema, "user_metadata_schema", "is not a valid JSON object")
+	}
+
+	isSet, err := s.apps.SetMetadataSchema(ctx, req.GetAppName(), schemas)
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.SetMetadataSchemaResponse{IsSet: isSet}, nil
+}
+
+func (s *serverAPI) SetHooks(ctx context.Context, req *ssov2.SetHooksRequest) (*ssov2.SetHooksResponse, error) {
+	if err := ValidateApp(ctx, req.GetAppName()); err != nil {
+		return nil, err
+	}
+	hooks := make([]models.Hook, 0, len(req.GetHooks()))
+	for _, h := range req.GetHooks() {
+		hooks = append(hooks, models.Hook{
+			Stage:    h.GetStage(),
+			Kind:     h.GetKind(),
+			Target:   h.GetTarget(),
+			Timeout:  time.Duration(h.GetTimeoutMs()) * time.Millisecond,
+			FailOpen: h.GetFailOpen(),
+		})
+	}
+
+	isSet, err := s.apps.SetHooks(ctx, req.GetAppName(), hooks)
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.SetHooksResponse{IsSet: isSet}, nil
+}
+
+func (s *serverAPI) ListHooks(ctx context.Context, req *ssov2.ListHooksRequest) (*ssov2.ListHooksResponse, error) {
+	if err := ValidateApp(ctx, req.GetAppName()); err != nil {
+		return nil, err
+	}
+
+	hooks, err := s.apps.ListHooks(ctx, req.GetAppName())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	resp := &ssov2.ListHooksResponse{}
+	for _, h := range hooks {
+		resp.Hooks = append(resp.Hooks, &ssov2.AppHook{
+			Stage:     h.Stage,
+			Kind:      h.Kind,
+			Target:    h.Target,
+			TimeoutMs: int32(h.Timeout.Milliseconds()),
+			FailOpen:  h.FailOpen,
+		})
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) SetClaimMapping(ctx context.Context, req *ssov2.SetClaimMappingRequest) (*ssov2.SetClaimMappingResponse, error) {
+	if err := ValidateApp(ctx, req.GetAppName()); err != nil {
+		return nil, err
+	}
+
+	isSet, err := s.apps.SetClaimMapping(ctx, req.GetAppName(), req.GetClaimMapping())
+	if err != nil {
+		var mapErr *claimmap.Error
+		if errors.Is(err, apps.ErrInvalidMapping) && errors.As(err, &mapErr) {
+			return nil, grpcerr.WithMessage(ctx, codes.InvalidArgument, grpcerr.ReasonInvalidClaimMapping, "invalid claim mapping: "+mapErr.Error())
+		}
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.SetClaimMappingResponse{IsSet: isSet}, nil
+}
+
+func (s *serverAPI) GetClaimMapping(ctx context.Context, req *ssov2.GetClaimMappingRequest) (*ssov2.GetClaimMappingResponse, error) {
+	if err := ValidateApp(ctx, req.GetAppName()); err != nil {
+		return nil, err
+	}
+
+	mapping, err := s.apps.GetClaimMapping(ctx, req.GetAppName())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.GetClaimMappingResponse{ClaimMapping: mapping}, nil
+}
+
+func (s *serverAPI) SetAppSettings(ctx context.Context, req *ssov2.SetAppSettingsRequest) (*ssov2.SetAppSettingsResponse, error) {
+	if err := ValidateApp(ctx, req.GetAppName()); err != nil {
+		return nil, err
+	}
+
+	in := req.GetSettings()
+	settings := models.AppSettings{
+		AccessTTL:        time.Duration(in.GetAccessTokenTtlSeconds()) * time.Second,
+		RefreshTTL:       time.Duration(in.GetRefreshTokenTtlSeconds()) * time.Second,
+		IdleTimeout:      time.Duration(in.GetIdleTimeoutSeconds()) * time.Second,
+		AbsoluteLifetime: time.Duration(in.GetAbsoluteLifetimeSeconds()) * time.Second,
+		MaxSessions:      int(in.GetMaxSessions()),
+	}
+	isSet, err := s.apps.SetAppSettings(ctx, req.GetAppName(), settings)
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.SetAppSettingsResponse{IsSet: isSet}, nil
+}
+
+func (s *serverAPI) GetAppSettings(ctx context.Context, req *ssov2.GetAppSettingsRequest) (*ssov2.GetAppSettingsResponse, error) {
+	if err := ValidateApp(ctx, req.GetAppName()); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.apps.GetAppSettings(ctx, req.GetAppName())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.GetAppSettingsResponse{Settings: &ssov2.AppSettings{
+		AccessTokenTtlSeconds:   int64(settings.AccessTTL.Seconds()),
+		RefreshTokenTtlSeconds:  int64(settings.RefreshTTL.Seconds()),
+		IdleTimeoutSeconds:      int64(settings.IdleTimeout.Seconds()),
+		AbsoluteLifetimeSeconds: int64(settings.AbsoluteLifetime.Seconds()),
+		MaxSessions:             int32(settings.MaxSessions),
+	}}, nil
+}
+
+func ValidateGet(ctx context.Context, req *ssov2.GetAppRequest) error {
 	var reqStruct GetAppIDReq
 	reqStruct.AppName = req.GetAppName()
 
 	if err := validator.New().Struct(reqStruct); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidateSet(req *ssov2.SetAppRequest) error {
+func ValidateSet(ctx context.Context, req *ssov2.SetAppRequest) error {
 	var reqStruct SetAppReq
 	reqStruct.Email = req.GetEmail()
 	reqStruct.AppName = req.GetAppName()
 	reqStruct.AppSecret = req.GetAppSecret()
 
 	if err := validator.New().Struct(reqStruct); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidateUpd(req *ssov2.UpdAppRequest) error {
+func ValidateUpd(ctx context.Context, req *ssov2.UpdAppRequest) error {
 	var reqStruct UpdAppReq
 	reqStruct.AppName = req.GetAppName()
 	reqStruct.NewAppName = req.GetNewAppName()
 	reqStruct.NewAppSecret = req.GetNewAppSecret()
 
 	if err := validator.New().Struct(reqStruct); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidateDel(req *ssov2.DelAppRequest) error {
+func ValidateDel(ctx context.Context, req *ssov2.DelAppRequest) error {
 	var reqStruct DelAppReq
 	reqStruct.AppName = req.GetAppName()
 
 	if err := validator.New().Struct(reqStruct); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidationError(errs validator.ValidationErrors) error {
-	var errMsgs []string
+func ValidateSetMetadataSchema(ctx context.Context, req *ssov2.SetMetadataSchemaRequest) error {
+	var reqStruct SetMetadataSchemaReq
+	reqStruct.AppName = req.GetAppName()
 
-	for _, err := range errs {
-		switch err.ActualTag() {
-		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
-		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid", err.Field()))
-		}
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
 	}
+	return nil
+}
+
+func ValidateApp(ctx context.Context, appName string) error {
+	var reqStruct AppReq
+	reqStruct.AppName = appName
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateList(ctx context.Context, req *ssov2.ListAppsRequest) error {
+	var reqStruct ListAppsReq
+	reqStruct.AppName = req.GetAppName()
+	reqStruct.CreatorEmail = req.GetCreatorEmail()
 
-	return errors.New(strings.Join(errMsgs, ", "))
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
 }
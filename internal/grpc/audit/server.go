@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/services/audit"
+
+	"github.com/go-playground/validator/v10"
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type Audit interface {
+	QueryAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, string, error)
+}
+
+type QueryAuditReq struct {
+	AppName   string `validate:"required"`
+	Outcome   string `validate:"omitempty,oneof=success failure"`
+	ActorKind string `validate:"omitempty,oneof=human service"`
+}
+
+var errs = grpcerr.Table{
+	{Err: audit.ErrNotCreator, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotCreator},
+	{Err: audit.ErrInvalidCredentials, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+}
+
+type serverAPI struct {
+	ssov2.UnimplementedAuditServer
+	audit Audit
+}
+
+func Register(gRPC *grpc.Server, audit Audit) {
+	ssov2.RegisterAuditServer(gRPC, &serverAPI{audit: audit})
+}
+
+func (s *serverAPI) QueryAudit(ctx context.Context, req *ssov2.QueryAuditRequest) (*ssov2.QueryAuditResponse, error) {
+	if err := ValidateQuery(ctx, req); err != nil {
+		return nil, err
+	}
+	page, err := pagination.Filter("", req.GetCreatedAfter(), req.GetCreatedBefore(), req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, grpcerr.Pagination(ctx, err)
+	}
+
+	filter := models.AuditFilter{
+		AppName:       req.GetAppName(),
+		Action:        req.GetAction(),
+		ActorID:       req.GetActorId(),
+		ActorKind:     models.UserKind(req.GetActorKind()),
+		OperatorID:    req.GetOperatorId(),
+		Outcome:       req.GetOutcome(),
+		CreatedAfter:  page.CreatedAfter,
+		CreatedBefore: page.CreatedBefore,
+		BeforeID:      page.AfterID,
+		Limit:         page.Limit,
+	}
+	entries, next, err := s.audit.QueryAudit(ctx, filter)
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+
+	resp := &ssov2.QueryAuditResponse{NextPageToken: next}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, &ssov2.AuditEntry{
+			Id:         e.ID,
+			CreatedAt:  pagination.Timestamp(e.CreatedAt),
+			Action:     e.Action,
+			ActorId:    e.ActorID,
+			ActorKind:  string(e.ActorKind),
+			OperatorId: e.OperatorID,
+			Target:     e.Target,
+			AppName:    e.AppName,
+			Ip:         e.IP,
+			Outcome:    e.Outcome,
+			Reason:     e.Reason,
+			PrevHash:   e.PrevHash,
+			Hash:       e.Hash,
+		})
+	}
+	return resp, nil
+}
+
+func ValidateQuery(ctx context.Context, req *ssov2.QueryAuditRequest) error {
+	var reqStruct QueryAuditReq
+	reqStruct.AppName = req.GetAppName()
+	reqStruct.Outcome = req.GetOutcome()
+	reqStruct.ActorKind = req.GetActorKind()
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
@@ -2,24 +2,61 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/claimmap"
+	"github.com/neepooha/sso/internal/lib/jsonstruct"
+	"github.com/neepooha/sso/internal/lib/pagination"
 	"github.com/neepooha/sso/internal/services/auth"
-	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	ssov2 "github.com/neepooha/protos/gen/go/sso"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type Auth interface {
-	Login(ctx context.Context, email string, password string, appName string) (token string, err error)
-	RegisterNewUser(ctx context.Context, email string, password string) (userID uint64, err error)
+	Login(ctx context.Context, email string, password string, appName string, join bool) (tokens models.Tokens, err error)
+	Refresh(ctx context.Context, appName string, refreshToken string) (tokens models.Tokens, err error)
+	Logout(ctx context.Context, appName string, refreshToken string) (isLoggedOut bool, err error)
+	RegisterNewUser(ctx context.Context, email string, password string, appName string) (userID uint64, err error)
 	GetUserID(ctx context.Context, email string) (userID uint64, err error)
+	ListUsers(ctx context.Context, appName string, filter models.ListFilter) (users []models.User, nextPageToken string, err error)
+	DryRunToken(ctx context.Context, appName string, userID uint64, mapping map[string]string) (claims map[string]any, err error)
+	Impersonate(ctx context.Context, appName string, email string) (token string, expiresAt time.Time, err error)
 }
 
+// hookRetryAfter is how soon a call that failed on a hook may be retried
+const hookRetryAfter = time.Second
+
+var errs = grpcerr.Table{
+	{Err: auth.ErrInvalidCredentials, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+	{Err: auth.ErrUserExists, Code: codes.AlreadyExists, Reason: grpcerr.ReasonUserExists},
+	{Err: auth.ErrUserNotFound, Code: codes.NotFound, Reason: grpcerr.ReasonUserNotFound},
+	{Err: auth.ErrNotCreator, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotCreator},
+	{Err: auth.ErrUserSuspended, Code: codes.PermissionDenied, Reason: grpcerr.ReasonUserSuspended},
+	{Err: auth.ErrUserDeleted, Code: codes.PermissionDenied, Reason: grpcerr.ReasonUserDeleted},
+	{Err: auth.ErrInvalidSession, Code: codes.Unauthenticated, Reason: grpcerr.ReasonInvalidRefreshToken},
+	{Err: auth.ErrSessionExpired, Code: codes.Unauthenticated, Reason: grpcerr.ReasonSessionExpired},
+	{Err: auth.ErrInvalidMapping, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidClaimMapping},
+	{Err: auth.ErrClaimMapping, Code: codes.FailedPrecondition, Reason: grpcerr.ReasonClaimMappingFailed},
+	{Err: auth.ErrNotSupport, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotSupport},
+	{Err: auth.ErrNestedImpersonation, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNestedImpersonation},
+	{Err: auth.ErrPrivilegedTarget, Code: codes.PermissionDenied, Reason: grpcerr.ReasonPrivilegedTarget},
+	{Err: auth.ErrHookFailed, Code: codes.Unavailable, Reason: grpcerr.ReasonHookFailed, RetryAfter: hookRetryAfter},
+	{Err: auth.ErrAppRequired, Code: codes.InvalidArgument, Reason: grpcerr.ReasonAppRequired},
+}
+
+// impersonateErrors reports the state of the impersonated user as a precondition,
+// the caller itself is allowed
+var impersonateErrors = append(grpcerr.Table{
+	{Err: auth.ErrUserSuspended, Code: codes.FailedPrecondition, Reason: grpcerr.ReasonUserSuspended},
+	{Err: auth.ErrUserDeleted, Code: codes.FailedPrecondition, Reason: grpcerr.ReasonUserDeleted},
+}, errs...)
+
 type serverAPI struct {
 	ssov2.UnimplementedAuthServer
 	auth Auth
@@ -30,114 +67,257 @@ func Register(gRPC *grpc.Server, auth Auth) {
 }
 
 type LoginRequest struct {
-	Email   string `validate:"required,email"`
-	Pass    string `validate:"required"`
-	AppName string `validate:"required"`
+	Email    string `validate:"required,email"`
+	Password string `validate:"required"`
+	AppName  string `validate:"required"`
+}
+
+type RefreshRequest struct {
+	AppName      string `validate:"required"`
+	RefreshToken string `validate:"required"`
 }
 
 type RegisterRequest struct {
-	Email string `validate:"required,email"`
-	Pass  string `validate:"required,gt=7"`
+	Email    string `validate:"required,email"`
+	Password string `validate:"required,gt=7"`
 }
 
 type GetUserIDRequest struct {
 	Email string `validate:"required,email"`
 }
 
+type DryRunTokenRequest struct {
+	AppName string `validate:"required"`
+}
+
+type ImpersonateRequest struct {
+	AppName string `validate:"required"`
+	Email   string `validate:"required,email"`
+}
+
+type ListUsersRequest struct {
+	AppName string `validate:"required"`
+}
+
 func (s *serverAPI) Login(ctx context.Context, req *ssov2.LoginRequest) (*ssov2.LoginResponse, error) {
-	if err := ValidateLogin(req); err != nil {
+	if err := ValidateLogin(ctx, req); err != nil {
 		return nil, err
 	}
 
-	token, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword(), req.GetAppName())
+	tokens, err := s.auth.Login(ctx, req.GetEmail(), req.GetPassword(), req.GetAppName(), req.GetJoin())
 	if err != nil {
-		if errors.Is(err, auth.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, statusError(ctx, err)
 	}
 
-	return &ssov2.LoginResponse{Token: token}, nil
+	return &ssov2.LoginResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken}, nil
+}
+
+func (s *serverAPI) Refresh(ctx context.Context, req *ssov2.RefreshRequest) (*ssov2.RefreshResponse, error) {
+	if err := ValidateRefresh(ctx, req.GetAppName(), req.GetRefreshToken()); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.auth.Refresh(ctx, req.GetAppName(), req.GetRefreshToken())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return &ssov2.RefreshResponse{Token: tokens.AccessToken, RefreshToken: tokens.RefreshToken}, nil
+}
+
+func (s *serverAPI) Logout(ctx context.Context, req *ssov2.LogoutRequest) (*ssov2.LogoutResponse, error) {
+	if err := ValidateRefresh(ctx, req.GetAppName(), req.GetRefreshToken()); err != nil {
+		return nil, err
+	}
+
+	isLoggedOut, err := s.auth.Logout(ctx, req.GetAppName(), req.GetRefreshToken())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return &ssov2.LogoutResponse{IsLoggedOut: isLoggedOut}, nil
 }
 
 func (s *serverAPI) Register(ctx context.Context, req *ssov2.RegisterRequest) (*ssov2.RegisterResponse, error) {
-	if err := ValidateRegister(req); err != nil {
+	if err := ValidateRegister(ctx, req); err != nil {
 		return nil, err
 	}
-	userID, err := s.auth.RegisterNewUser(ctx, req.GetEmail(), req.GetPassword())
+	userID, err := s.auth.RegisterNewUser(ctx, req.GetEmail(), req.GetPassword(), req.GetAppName())
 	if err != nil {
-		if errors.Is(err, auth.ErrUserExists) {
-			return nil, status.Error(codes.AlreadyExists, "user already exists")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, statusError(ctx, err)
 	}
 	return &ssov2.RegisterResponse{UserId: userID}, nil
 }
 
+func (s *serverAPI) DryRunToken(ctx context.Context, req *ssov2.DryRunTokenRequest) (*ssov2.DryRunTokenResponse, error) {
+	if err := ValidateDryRunToken(ctx, req); err != nil {
+		return nil, err
+	}
+
+	claims, err := s.auth.DryRunToken(ctx, req.GetAppName(), req.GetUserId(), req.GetClaimMapping())
+	if err != nil {
+		var mapErr *claimmap.Error
+		if errors.Is(err, auth.ErrInvalidMapping) && errors.As(err, &mapErr) {
+			return nil, grpcerr.WithMessage(ctx, codes.InvalidArgument, grpcerr.ReasonInvalidClaimMapping, "invalid claim mapping: "+mapErr.Error())
+		}
+		if errors.Is(err, auth.ErrClaimMapping) && errors.As(err, &mapErr) {
+			return nil, grpcerr.WithMessage(ctx, codes.FailedPrecondition, grpcerr.ReasonClaimMappingFailed, "claim mapping failed: "+mapErr.Error())
+		}
+		return nil, statusError(ctx, err)
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, grpcerr.Internal(ctx, err)
+	}
+	resp := &ssov2.DryRunTokenResponse{}
+	if resp.Claims, err = jsonstruct.FromJSON(data); err != nil {
+		return nil, grpcerr.Internal(ctx, err)
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) Impersonate(ctx context.Context, req *ssov2.ImpersonateRequest) (*ssov2.ImpersonateResponse, error) {
+	if err := ValidateImpersonate(ctx, req); err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := s.auth.Impersonate(ctx, req.GetAppName(), req.GetEmail())
+	if err != nil {
+		if err := hookError(ctx, err); err != nil {
+			return nil, err
+		}
+		return nil, impersonateErrors.Error(ctx, err)
+	}
+
+	return &ssov2.ImpersonateResponse{Token: token, ExpiresAt: pagination.Timestamp(expiresAt)}, nil
+}
+
+// statusError maps errors of the auth service to gRPC errors
+func statusError(ctx context.Context, err error) error {
+	if err := hookError(ctx, err); err != nil {
+		return err
+	}
+	return errs.Error(ctx, err)
+}
+
+// hookError returns the denial of a hook with its message, nil means err isn't a denial
+func hookError(ctx context.Context, err error) error {
+	var denied *auth.HookDeniedError
+	if errors.As(err, &denied) {
+		return grpcerr.WithMessage(ctx, codes.PermissionDenied, grpcerr.ReasonHookDenied, denied.Error())
+	}
+	return nil
+}
+
 func (s *serverAPI) GetUserID(ctx context.Context, req *ssov2.GetUserIDRequest) (*ssov2.GetUserIDResponse, error) {
-	if err := ValidateGetUserID(req); err != nil {
+	if err := ValidateGetUserID(ctx, req); err != nil {
 		return nil, err
 	}
 
 	id, err := s.auth.GetUserID(ctx, req.GetEmail())
 	if err != nil {
-		if errors.Is(err, auth.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, statusError(ctx, err)
 	}
 
 	return &ssov2.GetUserIDResponse{UserId: id}, nil
 }
 
-func ValidateLogin(req *ssov2.LoginRequest) error {
+func (s *serverAPI) ListUsers(ctx context.Context, req *ssov2.ListUsersRequest) (*ssov2.ListUsersResponse, error) {
+	if err := ValidateListUsers(ctx, req); err != nil {
+		return nil, err
+	}
+	filter, err := pagination.Filter(req.GetEmailPrefix(), req.GetCreatedAfter(), req.GetCreatedBefore(), req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, grpcerr.Pagination(ctx, err)
+	}
+
+	users, next, err := s.auth.ListUsers(ctx, req.GetAppName(), filter)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	resp := &ssov2.ListUsersResponse{NextPageToken: next}
+	for _, user := range users {
+		resp.Users = append(resp.Users, &ssov2.User{
+			Id:        user.ID,
+			Email:     user.Email,
+			CreatedAt: pagination.Timestamp(user.CreatedAt),
+			Status:    string(user.Status),
+		})
+	}
+	return resp, nil
+}
+
+func ValidateLogin(ctx context.Context, req *ssov2.LoginRequest) error {
 	var loginReq LoginRequest
 	loginReq.Email = req.GetEmail()
-	loginReq.Pass = req.GetPassword()
+	loginReq.Password = req.GetPassword()
 	loginReq.AppName = req.GetAppName()
 
 	if err := validator.New().Struct(loginReq); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateRefresh(ctx context.Context, appName string, refreshToken string) error {
+	var refreshReq RefreshRequest
+	refreshReq.AppName = appName
+	refreshReq.RefreshToken = refreshToken
+
+	if err := validator.New().Struct(refreshReq); err != nil {
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidateRegister(req *ssov2.RegisterRequest) error {
+func ValidateRegister(ctx context.Context, req *ssov2.RegisterRequest) error {
 	var regiserReq RegisterRequest
 	regiserReq.Email = req.GetEmail()
-	regiserReq.Pass = req.GetPassword()
+	regiserReq.Password = req.GetPassword()
 	if err := validator.New().Struct(regiserReq); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidateGetUserID(req *ssov2.GetUserIDRequest) error {
+func ValidateGetUserID(ctx context.Context, req *ssov2.GetUserIDRequest) error {
 	var loginReq GetUserIDRequest
 	loginReq.Email = req.GetEmail()
 
 	if err := validator.New().Struct(loginReq); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidationError(errs validator.ValidationErrors) error {
-	var errMsgs []string
+func ValidateDryRunToken(ctx context.Context, req *ssov2.DryRunTokenRequest) error {
+	var dryRunReq DryRunTokenRequest
+	dryRunReq.AppName = req.GetAppName()
 
-	for _, err := range errs {
-		switch err.ActualTag() {
-		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
-		case "email":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid email", err.Field()))
-		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid", err.Field()))
-		}
+	if err := validator.New().Struct(dryRunReq); err != nil {
+		return grpcerr.Validation(ctx, err)
 	}
+	return nil
+}
 
-	return errors.New(strings.Join(errMsgs, ", "))
+func ValidateImpersonate(ctx context.Context, req *ssov2.ImpersonateRequest) error {
+	var impReq ImpersonateRequest
+	impReq.AppName = req.GetAppName()
+	impReq.Email = req.GetEmail()
+
+	if err := validator.New().Struct(impReq); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateListUsers(ctx context.Context, req *ssov2.ListUsersRequest) error {
+	var listReq ListUsersRequest
+	listReq.AppName = req.GetAppName()
+
+	if err := validator.New().Struct(listReq); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
 }
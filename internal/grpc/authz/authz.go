@@ -0,0 +1,331 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/clientcert"
+	"github.com/neepooha/sso/internal/lib/jwt"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/services/serviceaccounts"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// roleErrors maps the errors of role checks to statuses
+var roleErrors = grpcerr.Table{
+	{Err: storage.ErrAdminNotFound, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotAdmin},
+	{Err: storage.ErrCreatorNotFound, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotCreator},
+	{Err: storage.ErrUserNotFound, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+	{Err: storage.ErrAppNotFound, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+}
+
+type AppProvider interface {
+	GetApp(ctx context.Context, appName string) (models.App, error)
+}
+
+type RoleProvider interface {
+	IsAdmin(ctx context.Context, userID uint64, appName string) error
+	IsCreator(ctx context.Context, userID uint64, appName string) error
+}
+
+// UserProvider returns the current account of the caller, tokens of users that
+// are suspended or deleted since the token was issued are refused
+type UserProvider interface {
+	GetUserByID(ctx context.Context, userID uint64) (models.User, error)
+}
+
+// KeyAuthenticator resolves API keys of service accounts
+type KeyAuthenticator interface {
+	Authenticate(ctx context.Context, appName string, apiKey string) (models.ServiceAccount, error)
+}
+
+// Authorizer verifies the bearer token or the API key of a request once, checks
+// the rule of the method and puts the caller into the context as principal.Principal
+type Authorizer struct {
+	log          *slog.Logger
+	appProvider  AppProvider
+	roleProvider RoleProvider
+	userProvider UserProvider
+	keys         KeyAuthenticator
+	rules        map[string]Rule
+	clientCerts  ClientCertPolicy
+}
+
+// New returns a new instanse of the Authorizer
+func New(log *slog.Logger, appProvider AppProvider, roleProvider RoleProvider, userProvider UserProvider, keys KeyAuthenticator, rules map[string]Rule, clientCerts ClientCertPolicy) *Authorizer {
+	return &Authorizer{
+		log:          log,
+		appProvider:  appProvider,
+		roleProvider: roleProvider,
+		userProvider: userProvider,
+		keys:         keys,
+		rules:        rules,
+		clientCerts:  clientCerts,
+	}
+}
+
+// Missing returns registered methods without a rule, calls to them are always denied
+func (a *Authorizer) Missing(services map[string]grpc.ServiceInfo) []string {
+	var missing []string
+	for name, info := range services {
+		for _, m := range info.Methods {
+			method := "/" + name + "/" + m.Name
+			if _, ok := a.rules[method]; !ok {
+				missing = append(missing, method)
+			}
+		}
+	}
+	return missing
+}
+
+// appRequest is implemented by every request of a non-public method
+type appRequest interface {
+	GetAppName() string
+}
+
+// Unary returns the interceptor for unary methods
+func (a *Authorizer) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authorize(ctx, info.FullMethod, req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns the interceptor for streaming methods. The request of a server
+// stream is read by the handler, so it is authorized on the first received message
+func (a *Authorizer) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		rule, ok := a.rules[info.FullMethod]
+		if !ok {
+			a.log.Error("no authorization rule", slog.String("method", info.FullMethod))
+			return grpcerr.New(ss.Context(), codes.PermissionDenied, grpcerr.ReasonMethodNotAllowed)
+		}
+		if rule == Public {
+			if _, err := a.clientCert(ss.Context(), info.FullMethod, rule); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}
+		return handler(srv, &authorizedStream{ServerStream: ss, authz: a, method: info.FullMethod, ctx: ss.Context()})
+	}
+}
+
+type authorizedStream struct {
+	grpc.ServerStream
+	authz      *Authorizer
+	method     string
+	ctx        context.Context
+	authorized bool
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authorizedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.authorized {
+		return nil
+	}
+	ctx, err := s.authz.authorize(s.ctx, s.method, m)
+	if err != nil {
+		return err
+	}
+	s.ctx = ctx
+	s.authorized = true
+	return nil
+}
+
+func (a *Authorizer) authorize(ctx context.Context, method string, req any) (context.Context, error) {
+	const op = "grpc.authz.authorize"
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx), slog.String("method", method))
+
+	rule, ok := a.rules[method]
+	if !ok {
+		log.Error("no authorization rule")
+		return nil, grpcerr.New(ctx, codes.PermissionDenied, grpcerr.ReasonMethodNotAllowed)
+	}
+	certIdentity, err := a.clientCert(ctx, method, rule)
+	if err != nil {
+		return nil, err
+	}
+	if rule == Public {
+		return ctx, nil
+	}
+
+	r, ok := req.(appRequest)
+	if !ok || r.GetAppName() == "" {
+		return nil, grpcerr.InvalidField(ctx, grpcerr.ReasonValidation, "app_name", "is a required field")
+	}
+	appName := r.GetAppName()
+
+	scheme, credential, err := extractCredential(ctx)
+	if err != nil {
+		log.Warn("no token", sl.Err(err))
+		return nil, grpcerr.WithMessage(ctx, codes.Unauthenticated, grpcerr.ReasonMissingCredentials, err.Error())
+	}
+	app, err := a.appProvider.GetApp(ctx, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return nil, grpcerr.New(ctx, codes.InvalidArgument, grpcerr.ReasonInvalidCredentials)
+		}
+		log.Error("failed to get app", sl.Err(err))
+		return nil, grpcerr.Internal(ctx, err)
+	}
+	var p principal.Principal
+	if scheme == apiKeyScheme {
+		p, err = a.keyPrincipal(ctx, log, credential, app)
+	} else {
+		p, err = tokenPrincipal(ctx, log, credential, app)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.ClientCert = certIdentity
+	for _, uid := range []uint64{p.UserID, p.ActorID} {
+		if uid == 0 {
+			continue
+		}
+		if err := a.checkStatus(ctx, log, uid); err != nil {
+			return nil, err
+		}
+	}
+	if p.ActorID != 0 && NoImpersonation[method] {
+		log.Warn("method denied to impersonation token", slog.Uint64("uid", p.UserID), slog.Uint64("act", p.ActorID))
+		return nil, grpcerr.New(ctx, codes.PermissionDenied, grpcerr.ReasonImpersonationDenied)
+	}
+
+	switch rule {
+	case Admin:
+		err = a.roleProvider.IsAdmin(ctx, p.UserID, appName)
+	case Creator:
+		err = a.roleProvider.IsCreator(ctx, p.UserID, appName)
+	}
+	if err != nil {
+		log.Warn("caller has no role", slog.String("rule", rule.String()), slog.Uint64("uid", p.UserID), sl.Err(err))
+		return nil, roleErrors.Error(ctx, err)
+	}
+	return principal.NewContext(ctx, p), nil
+}
+
+// checkStatus refuses callers whose account is no longer active, tokens are
+// valid until they expire, so a suspension or deletion is seen here first
+func (a *Authorizer) checkStatus(ctx context.Context, log *slog.Logger, userID uint64) error {
+	user, err := a.userProvider.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", slog.Uint64("uid", userID), sl.Err(err))
+			return grpcerr.New(ctx, codes.Unauthenticated, grpcerr.ReasonInvalidToken)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return grpcerr.Internal(ctx, err)
+	}
+	switch user.Status {
+	case models.UserSuspended:
+		log.Warn("user is suspended", slog.Uint64("uid", userID))
+		return grpcerr.New(ctx, codes.PermissionDenied, grpcerr.ReasonUserSuspended)
+	case models.UserPendingDeletion, models.UserDeleted:
+		log.Warn("user is deleted", slog.Uint64("uid", userID))
+		return grpcerr.New(ctx, codes.PermissionDenied, grpcerr.ReasonUserDeleted)
+	}
+	return nil
+}
+
+// clientCert returns the identity of the client certificate, it fails when the
+// policy needs a certificate for the method and the caller sent none
+func (a *Authorizer) clientCert(ctx context.Context, method string, rule Rule) (string, error) {
+	identity, ok := clientcert.FromContext(ctx)
+	if !ok && a.clientCerts.Requires(method, rule) {
+		a.log.Warn("no client certificate", slog.String("method", method))
+		return "", grpcerr.New(ctx, codes.Unauthenticated, grpcerr.ReasonClientCertRequired)
+	}
+	return identity, nil
+}
+
+// tokenPrincipal verifies the access token, sub of service accounts starts with jwt.ServicePrefix
+func tokenPrincipal(ctx context.Context, log *slog.Logger, tokenStr string, app models.App) (principal.Principal, error) {
+	claims, err := jwt.Verify(tokenStr, app)
+	if err != nil {
+		log.Warn("invalid token", sl.Err(err))
+		return principal.Principal{}, grpcerr.New(ctx, codes.Unauthenticated, grpcerr.ReasonInvalidToken)
+	}
+	uid, ok := claims["uid"].(float64)
+	if !ok {
+		log.Warn("token without uid")
+		return principal.Principal{}, grpcerr.New(ctx, codes.Unauthenticated, grpcerr.ReasonInvalidToken)
+	}
+	p := principal.Principal{UserID: uint64(uid), Kind: models.UserHuman, App: app, Claims: claims}
+	if sub, _ := claims["sub"].(string); strings.HasPrefix(sub, jwt.ServicePrefix) {
+		p.Kind = models.UserService
+	}
+	if act, ok := claims["act"].(map[string]any); ok {
+		sub, _ := act["sub"].(string)
+		p.ActorID, err = strconv.ParseUint(sub, 10, 64)
+		if err != nil {
+			log.Warn("invalid act claim", sl.Err(err))
+			return principal.Principal{}, grpcerr.New(ctx, codes.Unauthenticated, grpcerr.ReasonInvalidToken)
+		}
+		// every call made on behalf of a user is logged with the real operator
+		log.Info("impersonated call", slog.Uint64("uid", p.UserID), slog.Uint64("act", p.ActorID), slog.String("app", app.Name))
+	}
+	return p, nil
+}
+
+// keyPrincipal resolves the API key of a service account of the app
+func (a *Authorizer) keyPrincipal(ctx context.Context, log *slog.Logger, apiKey string, app models.App) (principal.Principal, error) {
+	account, err := a.keys.Authenticate(ctx, app.Name, apiKey)
+	if err != nil {
+		if errors.Is(err, serviceaccounts.ErrInvalidKey) {
+			log.Warn("invalid api key", sl.Err(err))
+			return principal.Principal{}, grpcerr.New(ctx, codes.Unauthenticated, grpcerr.ReasonInvalidAPIKey)
+		}
+		log.Error("failed to authenticate api key", sl.Err(err))
+		return principal.Principal{}, grpcerr.Internal(ctx, err)
+	}
+	return principal.Principal{UserID: account.UserID, Kind: models.UserService, App: app}, nil
+}
+
+const (
+	bearerScheme = "Bearer"
+	apiKeyScheme = "ApiKey"
+)
+
+// extractCredential returns the scheme and the credential of the authorization
+// header, "Bearer <token>" for access tokens or "ApiKey <key>" for service accounts
+func extractCredential(ctx context.Context) (string, string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", "", errors.New("no headers in request")
+	}
+	authHeaders, ok := md["authorization"]
+	if !ok {
+		return "", "", errors.New("no authorization header in request")
+	}
+	if len(authHeaders) != 1 {
+		return "", "", errors.New("more than 1 authorization header in request")
+	}
+	scheme, credential, ok := strings.Cut(authHeaders[0], " ")
+	if !ok || (scheme != bearerScheme && scheme != apiKeyScheme) {
+		return "", "", errors.New(`missing "Bearer " or "ApiKey " prefix in "Authorization" header`)
+	}
+	if credential == "" {
+		return "", "", errors.New(`missing token in "Authorization" header`)
+	}
+	return scheme, credential, nil
+}
@@ -0,0 +1,53 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ClientCertPolicy lists the methods that need a verified client certificate (mTLS)
+// on top of their rule, either by rule, like every admin method, or one by one
+type ClientCertPolicy struct {
+	rules   map[Rule]bool
+	methods map[string]bool
+}
+
+// ParseClientCertPolicy reads entries that are rule names (public, authenticated,
+// admin, creator) or full method names like /apps.Apps/DelApp
+func ParseClientCertPolicy(entries []string) (ClientCertPolicy, error) {
+	p := ClientCertPolicy{rules: map[Rule]bool{}, methods: map[string]bool{}}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry, "/") {
+			if _, ok := Rules[entry]; !ok {
+				return ClientCertPolicy{}, fmt.Errorf("unknown method %s in client certificate policy", entry)
+			}
+			p.methods[entry] = true
+			continue
+		}
+		rule, ok := ruleByName(entry)
+		if !ok {
+			return ClientCertPolicy{}, fmt.Errorf("unknown rule %s in client certificate policy", entry)
+		}
+		p.rules[rule] = true
+	}
+	return p, nil
+}
+
+// Requires reports whether calls to the method must come with a client certificate
+func (p ClientCertPolicy) Requires(method string, rule Rule) bool {
+	return p.methods[method] || p.rules[rule]
+}
+
+// Empty reports whether no method needs a client certificate
+func (p ClientCertPolicy) Empty() bool {
+	return len(p.methods) == 0 && len(p.rules) == 0
+}
+
+func ruleByName(name string) (Rule, bool) {
+	for _, rule := range []Rule{Public, Authenticated, Admin, Creator} {
+		if rule.String() == name {
+			return rule, true
+		}
+	}
+	return 0, false
+}
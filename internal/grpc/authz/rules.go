@@ -0,0 +1,115 @@
+package authz
+
+import (
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// Rule is who may call a method
+type Rule int
+
+const (
+	// Public methods need no token
+	Public Rule = iota
+	// Authenticated methods need a valid token of the app named in the request
+	Authenticated
+	// Admin methods need the caller to be admin of the app
+	Admin
+	// Creator methods need the caller to be creator of the app
+	Creator
+)
+
+func (r Rule) String() string {
+	switch r {
+	case Public:
+		return "public"
+	case Authenticated:
+		return "authenticated"
+	case Admin:
+		return "admin"
+	case Creator:
+		return "creator"
+	}
+	return "unknown"
+}
+
+// Rules are the authorization rules of every method. Methods missing here are denied,
+// so a new rpc can't be exposed by mistake. Finer checks, like "the user itself or an admin",
+// stay in the services
+var Rules = map[string]Rule{
+	ssov2.Auth_Register_FullMethodName:    Public,
+	ssov2.Auth_Login_FullMethodName:       Public,
+	ssov2.Auth_Refresh_FullMethodName:     Public,
+	ssov2.Auth_Logout_FullMethodName:      Public,
+	ssov2.Auth_GetUserID_FullMethodName:   Public,
+	ssov2.Auth_ListUsers_FullMethodName:   Creator,
+	ssov2.Auth_DryRunToken_FullMethodName: Creator,
+	ssov2.Auth_Impersonate_FullMethodName: Authenticated,
+
+	ssov2.Permissions_SetAdmin_FullMethodName:     Authenticated,
+	ssov2.Permissions_DelAdmin_FullMethodName:     Authenticated,
+	ssov2.Permissions_GrantRole_FullMethodName:    Authenticated,
+	ssov2.Permissions_RevokeRole_FullMethodName:   Authenticated,
+	ssov2.Permissions_IsAdmin_FullMethodName:      Public,
+	ssov2.Permissions_IsCreator_FullMethodName:    Public,
+	ssov2.Permissions_ListAdmins_FullMethodName:   Creator,
+	ssov2.Permissions_ListCreators_FullMethodName: Creator,
+
+	ssov2.Apps_GetAppID_FullMethodName:          Public,
+	ssov2.Apps_SetApp_FullMethodName:            Public,
+	ssov2.Apps_ListApps_FullMethodName:          Authenticated,
+	ssov2.Apps_UpdApp_FullMethodName:            Creator,
+	ssov2.Apps_DelApp_FullMethodName:            Creator,
+	ssov2.Apps_SetMetadataSchema_FullMethodName: Creator,
+	ssov2.Apps_SetHooks_FullMethodName:          Creator,
+	ssov2.Apps_ListHooks_FullMethodName:         Creator,
+	ssov2.Apps_SetClaimMapping_FullMethodName:   Creator,
+	ssov2.Apps_GetClaimMapping_FullMethodName:   Creator,
+	ssov2.Apps_SetAppSettings_FullMethodName:    Creator,
+	ssov2.Apps_GetAppSettings_FullMethodName:    Creator,
+
+	ssov2.Profiles_GetProfile_FullMethodName:        Authenticated,
+	ssov2.Profiles_UpdateProfile_FullMethodName:     Authenticated,
+	ssov2.Profiles_UpdateAppMetadata_FullMethodName: Admin,
+
+	ssov2.Accounts_SetUserStatus_FullMethodName:  Authenticated,
+	ssov2.Accounts_DeleteUser_FullMethodName:     Authenticated,
+	ssov2.Accounts_ExportUserData_FullMethodName: Authenticated,
+	ssov2.Accounts_ChangeEmail_FullMethodName:    Authenticated,
+
+	ssov2.Audit_QueryAudit_FullMethodName: Creator,
+
+	ssov2.Webhooks_CreateWebhook_FullMethodName:     Creator,
+	ssov2.Webhooks_ListWebhooks_FullMethodName:      Creator,
+	ssov2.Webhooks_DeleteWebhook_FullMethodName:     Creator,
+	ssov2.Webhooks_ListDeadLetters_FullMethodName:   Creator,
+	ssov2.Webhooks_ReplayDeadLetters_FullMethodName: Creator,
+
+	ssov2.Events_WatchEvents_FullMethodName: Admin,
+
+	ssov2.ServiceAccounts_CreateServiceAccount_FullMethodName: Creator,
+	ssov2.ServiceAccounts_ListServiceAccounts_FullMethodName:  Creator,
+	ssov2.ServiceAccounts_DeleteServiceAccount_FullMethodName: Creator,
+	ssov2.ServiceAccounts_CreateAPIKey_FullMethodName:         Creator,
+	ssov2.ServiceAccounts_RevokeAPIKey_FullMethodName:         Creator,
+	ssov2.ServiceAccounts_IssueToken_FullMethodName:           Public,
+
+	healthpb.Health_Check_FullMethodName: Public,
+	healthpb.Health_Watch_FullMethodName: Public,
+
+	// reflection is registered only when grpc.reflection is on
+	reflectionv1.ServerReflection_ServerReflectionInfo_FullMethodName:      Public,
+	reflectionv1alpha.ServerReflection_ServerReflectionInfo_FullMethodName: Public,
+}
+
+// NoImpersonation are methods denied to impersonation tokens: a support user may
+// act as the user in apps, but can't delete the account, change its email or
+// profile, or take its data
+var NoImpersonation = map[string]bool{
+	ssov2.Accounts_DeleteUser_FullMethodName:     true,
+	ssov2.Accounts_ChangeEmail_FullMethodName:    true,
+	ssov2.Accounts_ExportUserData_FullMethodName: true,
+	ssov2.Profiles_UpdateProfile_FullMethodName:  true,
+}
@@ -0,0 +1,137 @@
+package authz_test
+
+import (
+	"fmt"
+	"github.com/neepooha/sso/internal/grpc/authz"
+	"strings"
+	"testing"
+
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// clientServices are services of the protos that sso calls and doesn't serve
+var clientServices = map[string]bool{
+	"hooks.HookService": true,
+}
+
+// ssoMethods returns full names of every rpc declared in the sso protos
+func ssoMethods(t *testing.T) []string {
+	t.Helper()
+	// the generated code registers the files once the package is linked
+	_ = ssov2.File_sso_auth_proto
+
+	var methods []string
+	protoregistry.GlobalFiles.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+		if !strings.HasPrefix(file.Path(), "sso/") {
+			return true
+		}
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			service := services.Get(i)
+			if clientServices[string(service.FullName())] {
+				continue
+			}
+			rpcs := service.Methods()
+			for j := 0; j < rpcs.Len(); j++ {
+				methods = append(methods, fmt.Sprintf("/%s/%s", service.FullName(), rpcs.Get(j).Name()))
+			}
+		}
+		return true
+	})
+	if len(methods) == 0 {
+		t.Fatal("no rpc found in the sso protos")
+	}
+	return methods
+}
+
+func TestRulesCoverEveryRPC(t *testing.T) {
+	for _, method := range ssoMethods(t) {
+		t.Run(method, func(t *testing.T) {
+			if _, ok := authz.Rules[method]; !ok {
+				t.Errorf("%s has no rule, calls are denied", method)
+			}
+		})
+	}
+}
+
+func TestRulesNameExistingRPCs(t *testing.T) {
+	known := map[string]bool{
+		healthpb.Health_Check_FullMethodName:                                   true,
+		healthpb.Health_Watch_FullMethodName:                                   true,
+		reflectionv1.ServerReflection_ServerReflectionInfo_FullMethodName:      true,
+		reflectionv1alpha.ServerReflection_ServerReflectionInfo_FullMethodName: true,
+	}
+	for _, method := range ssoMethods(t) {
+		known[method] = true
+	}
+	for method := range authz.Rules {
+		if !known[method] {
+			t.Errorf("rule of %s names no rpc", method)
+		}
+	}
+}
+
+func TestMissing(t *testing.T) {
+	server := grpc.NewServer()
+	ssov2.RegisterAuthServer(server, ssov2.UnimplementedAuthServer{})
+	ssov2.RegisterPermissionsServer(server, ssov2.UnimplementedPermissionsServer{})
+	ssov2.RegisterAppsServer(server, ssov2.UnimplementedAppsServer{})
+	ssov2.RegisterProfilesServer(server, ssov2.UnimplementedProfilesServer{})
+	ssov2.RegisterAccountsServer(server, ssov2.UnimplementedAccountsServer{})
+	ssov2.RegisterAuditServer(server, ssov2.UnimplementedAuditServer{})
+	ssov2.RegisterWebhooksServer(server, ssov2.UnimplementedWebhooksServer{})
+	ssov2.RegisterEventsServer(server, ssov2.UnimplementedEventsServer{})
+	ssov2.RegisterServiceAccountsServer(server, ssov2.UnimplementedServiceAccountsServer{})
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+
+	tests := []struct {
+		name    string
+		rules   map[string]authz.Rule
+		missing []string
+	}{
+		{name: "rules of the server", rules: authz.Rules},
+		{name: "rule removed", rules: without(authz.Rules, ssov2.Auth_Login_FullMethodName), missing: []string{ssov2.Auth_Login_FullMethodName}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := authz.New(nil, nil, nil, nil, nil, tt.rules, authz.ClientCertPolicy{})
+			got := a.Missing(server.GetServiceInfo())
+			if fmt.Sprint(got) != fmt.Sprint(tt.missing) {
+				t.Errorf("Missing() = %v, want %v", got, tt.missing)
+			}
+		})
+	}
+}
+
+func TestNoImpersonation(t *testing.T) {
+	for method := range authz.NoImpersonation {
+		rule, ok := authz.Rules[method]
+		if !ok {
+			t.Errorf("%s has no rule", method)
+			continue
+		}
+		// public methods take no token, so an impersonation token is never checked there
+		if rule == authz.Public {
+			t.Errorf("%s is public, impersonation can't be denied", method)
+		}
+	}
+}
+
+func without(rules map[string]authz.Rule, method string) map[string]authz.Rule {
+	out := make(map[string]authz.Rule, len(rules))
+	for m, r := range rules {
+		if m != method {
+			out[m] = r
+		}
+	}
+	return out
+}
@@ -0,0 +1,114 @@
+package deadline
+
+import (
+	"context"
+	"errors"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/metrics"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Deadlines bounds how long a call may run. The context of the call carries the deadline
+// down to every query, so a slow database can't hold requests forever
+type Deadlines struct {
+	timeout   time.Duration
+	overrides map[string]time.Duration
+}
+
+// New returns deadlines with timeout for every unary call and overrides by full method,
+// like /auth.Auth/Login. An override applies to streams too, an override of 0 leaves
+// the method without a deadline of the server
+func New(timeout time.Duration, overrides map[string]time.Duration) *Deadlines {
+	return &Deadlines{timeout: timeout, overrides: overrides}
+}
+
+// Unknown returns the overridden methods that the server doesn't have,
+// to catch typos in the config
+func (d *Deadlines) Unknown(services map[string]grpc.ServiceInfo) []string {
+	known := map[string]bool{}
+	for name, info := range services {
+		for _, m := range info.Methods {
+			known["/"+name+"/"+m.Name] = true
+		}
+	}
+	var unknown []string
+	for method := range d.overrides {
+		if !known[method] {
+			unknown = append(unknown, method)
+		}
+	}
+	return unknown
+}
+
+// Unary returns the interceptor for unary methods. A deadline set by the caller is kept
+// when it is sooner than the one of the server
+func (d *Deadlines) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		timeout, ok := d.overrides[info.FullMethod]
+		if !ok {
+			timeout = d.timeout
+		}
+		ctx, cancel, server := withTimeout(ctx, timeout)
+		defer cancel()
+		resp, err := handler(ctx, req)
+		return resp, contextError(ctx, info.FullMethod, server, err)
+	}
+}
+
+// Stream returns the interceptor for streaming methods. Streams like WatchEvents stay
+// open as long as the caller wants, so only overrides apply to them
+func (d *Deadlines) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		timeout := d.overrides[info.FullMethod]
+		ctx, cancel, server := withTimeout(ss.Context(), timeout)
+		defer cancel()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		return contextError(ctx, info.FullMethod, server, err)
+	}
+}
+
+// withTimeout reports whether the deadline of the server is the one in effect
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc, bool) {
+	if timeout <= 0 {
+		return ctx, func() {}, false
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= timeout {
+		return ctx, func() {}, false
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, true
+}
+
+// contextError replaces the error of a call whose context ended, the handler
+// most likely reported the failed query as Internal
+func contextError(ctx context.Context, method string, server bool, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	switch status.Code(err) {
+	case codes.Internal, codes.Unknown, codes.DeadlineExceeded, codes.Canceled:
+	default:
+		return err
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		by := metrics.DeadlineClient
+		if server {
+			by = metrics.DeadlineServer
+		}
+		metrics.Timeouts.WithLabelValues(method, by).Inc()
+	}
+	return grpcerr.FromContextError(ctx, ctx.Err())
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
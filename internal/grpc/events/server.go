@@ -0,0 +1,78 @@
+package events
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/jsonstruct"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/services/events"
+
+	"github.com/go-playground/validator/v10"
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type Events interface {
+	Watch(ctx context.Context, appName string, types []string, afterID uint64, send func(models.Event) error) error
+}
+
+type WatchEventsReq struct {
+	AppName string `validate:"required"`
+}
+
+var errs = grpcerr.Table{
+	{Err: events.ErrNotAdmin, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotAdmin},
+	{Err: events.ErrAppDeleted, Code: codes.NotFound, Reason: grpcerr.ReasonAppDeleted},
+	{Err: events.ErrInvalidEventType, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidEventType},
+	{Err: events.ErrInvalidCredentials, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+}
+
+type serverAPI struct {
+	ssov2.UnimplementedEventsServer
+	events Events
+}
+
+func Register(gRPC *grpc.Server, events Events) {
+	ssov2.RegisterEventsServer(gRPC, &serverAPI{events: events})
+}
+
+func (s *serverAPI) WatchEvents(req *ssov2.WatchEventsRequest, stream ssov2.Events_WatchEventsServer) error {
+	ctx := stream.Context()
+	if err := ValidateWatch(ctx, req); err != nil {
+		return err
+	}
+	afterID, err := pagination.DecodeToken(req.GetResumeToken())
+	if err != nil {
+		return grpcerr.InvalidField(ctx, grpcerr.ReasonInvalidResumeToken, "resume_token", "is not a valid resume token")
+	}
+
+	err = s.events.Watch(ctx, req.GetAppName(), req.GetEventTypes(), afterID, func(e models.Event) error {
+		data, err := jsonstruct.FromJSON(e.Payload)
+		if err != nil {
+			return err
+		}
+		return stream.Send(&ssov2.Event{
+			Id:          e.ID,
+			Type:        e.Type,
+			CreatedAt:   pagination.Timestamp(e.CreatedAt),
+			Data:        data,
+			ResumeToken: pagination.EncodeToken(e.ID),
+		})
+	})
+	if err != nil {
+		return errs.Error(ctx, err)
+	}
+	return nil
+}
+
+func ValidateWatch(ctx context.Context, req *ssov2.WatchEventsRequest) error {
+	var reqStruct WatchEventsReq
+	reqStruct.AppName = req.GetAppName()
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package grpcerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Domain is the domain of ErrorInfo of every error of the server
+const Domain = "sso"
+
+// Mapping translates one service error to a status
+type Mapping struct {
+	Err    error
+	Code   codes.Code
+	Reason Reason
+	// RetryAfter, when set, is sent as RetryInfo, for errors that may pass when retried
+	RetryAfter time.Duration
+}
+
+// Table maps the errors of a service to statuses, the first mapping that matches
+// the error wins. Every handler package has one, so a service error gets the same
+// code and reason whatever method returned it
+type Table []Mapping
+
+// Error returns the status error of err. Errors missing in the table are Internal,
+// their text never reaches the client
+func (t Table) Error(ctx context.Context, err error) error {
+	for _, m := range t {
+		if errors.Is(err, m.Err) {
+			return build(ctx, m.Code, m.Reason, message(m.Reason), m.RetryAfter)
+		}
+	}
+	return Internal(ctx, err)
+}
+
+// Internal returns Internal for an unexpected error, or DeadlineExceeded and Canceled
+// when err comes from a context that ended
+func Internal(ctx context.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return FromContextError(ctx, err)
+	}
+	return New(ctx, codes.Internal, ReasonInternal)
+}
+
+// New returns a status error with the message of the reason
+func New(ctx context.Context, code codes.Code, reason Reason) error {
+	return build(ctx, code, reason, message(reason), 0)
+}
+
+// WithMessage returns a status error with its own message, for errors that
+// carry details like the text of a hook denial
+func WithMessage(ctx context.Context, code codes.Code, reason Reason, msg string) error {
+	return build(ctx, code, reason, msg, 0)
+}
+
+// FromContextError returns DeadlineExceeded or Canceled for an error of an ended context
+func FromContextError(ctx context.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return New(ctx, codes.DeadlineExceeded, ReasonDeadlineExceeded)
+	}
+	return New(ctx, codes.Canceled, ReasonCanceled)
+}
+
+// InvalidField returns InvalidArgument with a BadRequest violation of one field that
+// the validator can't check, field is the name of the field in the proto file
+func InvalidField(ctx context.Context, reason Reason, field string, description string) error {
+	st := status.New(codes.InvalidArgument, fmt.Sprintf(fieldFormats[defaultLocale], field, description))
+	return attach(st, errorInfo(reason), localizedMessage(ctx, reason, st.Message()),
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}}},
+	)
+}
+
+func build(ctx context.Context, code codes.Code, reason Reason, msg string, retryAfter time.Duration) error {
+	st := status.New(code, msg)
+	details := []protoadapt.MessageV1{errorInfo(reason), localizedMessage(ctx, reason, msg)}
+	if retryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	}
+	return attach(st, details...)
+}
+
+func attach(st *status.Status, details ...protoadapt.MessageV1) error {
+	withDetails, err := st.WithDetails(details...)
+	if err != nil {
+		// details are well-known messages, so this can't happen, but the
+		// code and message are still right without them
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+func errorInfo(reason Reason) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{Reason: string(reason), Domain: Domain}
+}
+
+func localizedMessage(ctx context.Context, reason Reason, msg string) *errdetails.LocalizedMessage {
+	locale := Locale(ctx)
+	return &errdetails.LocalizedMessage{Locale: locale, Message: localize(locale, reason, msg)}
+}
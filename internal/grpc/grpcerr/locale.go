@@ -0,0 +1,41 @@
+package grpcerr
+
+import (
+	"context"
+
+	"golang.org/x/text/language"
+	"google.golang.org/grpc/metadata"
+)
+
+// LanguageHeader is the metadata key with the languages the client prefers,
+// in the format of the HTTP Accept-Language header
+const LanguageHeader = "accept-language"
+
+// locales are the locales of messages, the first one is the fallback
+var locales = []string{defaultLocale, "ru"}
+
+var matcher = func() language.Matcher {
+	tags := make([]language.Tag, 0, len(locales))
+	for _, locale := range locales {
+		tags = append(tags, language.MustParse(locale))
+	}
+	return language.NewMatcher(tags)
+}()
+
+// Locale returns the locale of messages that best matches the accept-language
+// metadata of the call, the default locale without it
+func Locale(ctx context.Context) string {
+	values := metadata.ValueFromIncomingContext(ctx, LanguageHeader)
+	if len(values) == 0 {
+		return defaultLocale
+	}
+	tags, _, err := language.ParseAcceptLanguage(values[0])
+	if err != nil || len(tags) == 0 {
+		return defaultLocale
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return defaultLocale
+	}
+	return locales[index]
+}
@@ -0,0 +1,162 @@
+package grpcerr
+
+// defaultLocale is the language of status messages, LocalizedMessage is in the
+// language the client asked for with accept-language
+const defaultLocale = "en"
+
+// messages are the texts of reasons by locale. A reason missing in a locale
+// falls back to the status message
+var messages = map[string]map[Reason]string{
+	"en": {
+		ReasonInternal:            "internal error",
+		ReasonDeadlineExceeded:    "deadline exceeded",
+		ReasonCanceled:            "request cancelled",
+		ReasonValidation:          "request has invalid fields",
+		ReasonRequestTooLarge:     "request body too large",
+		ReasonMethodNotAllowed:    "method is not allowed",
+		ReasonRouteNotFound:       "route not found",
+		ReasonInvalidBody:         "invalid request body",
+		ReasonNotSupported:        "service is not supported by the storage driver",
+		ReasonInvalidCredentials:  "invalid credentials",
+		ReasonMissingCredentials:  "missing credentials",
+		ReasonInvalidToken:        "invalid token",
+		ReasonInvalidAPIKey:       "invalid api key",
+		ReasonClientCertRequired:  "client certificate required",
+		ReasonInvalidRefreshToken: "invalid refresh token",
+		ReasonSessionExpired:      "session expired",
+		ReasonInvalidPageToken:    "invalid page token",
+		ReasonInvalidTimeRange:    "created_after must be before created_before",
+		ReasonInvalidResumeToken:  "invalid resume token",
+		ReasonNotAdmin:            "you are not admin",
+		ReasonNotCreator:          "you are not creator",
+		ReasonNotSupport:          "you are not support",
+		ReasonNotOperator:         "you are not operator",
+		ReasonNestedImpersonation: "impersonation token can't impersonate",
+		ReasonImpersonationDenied: "not allowed to impersonation tokens",
+		ReasonPrivilegedTarget:    "user with a role can't be impersonated",
+		ReasonUnknownRole:         "unknown role",
+		ReasonRoleNotAllowed:      "you can't manage this role",
+		ReasonRoleEscalation:      "you can't manage roles above your own",
+		ReasonLastCreator:         "can't remove the last creator",
+		ReasonUserExists:          "user already exists",
+		ReasonUserNotFound:        "user not found",
+		ReasonUserSuspended:       "account is suspended",
+		ReasonUserDeleted:         "account is pending deletion",
+		ReasonInvalidStatus:       "invalid status",
+		ReasonAppExists:           "an application with the same name exists",
+		ReasonAppDeleted:          "app deleted",
+		ReasonInvalidSchema:       "invalid metadata schema",
+		ReasonInvalidMetadata:     "metadata doesn't match schema",
+		ReasonInvalidSettings:     "invalid app settings",
+		ReasonInvalidHook:         "invalid hook",
+		ReasonInvalidClaimMapping: "invalid claim mapping",
+		ReasonClaimMappingFailed:  "claim mapping of the app failed",
+		ReasonHookDenied:          "denied by hook",
+		ReasonHookFailed:          "hook failed",
+		ReasonAppRequired:         "app_name is required, registration hooks are configured",
+		ReasonInvalidEventType:    "invalid event type",
+		ReasonWebhookNotFound:     "webhook not found",
+		ReasonInvalidWebhookURL:   "invalid webhook url",
+		ReasonAccountExists:       "service account already exists",
+		ReasonAccountNotFound:     "service account not found",
+		ReasonInvalidAccountName:  "name must be lowercase letters, digits and dashes",
+		ReasonAPIKeyNotFound:      "api key not found",
+		ReasonInvalidAPIKeyTTL:    "ttl must not be negative",
+		ReasonInvalidClientSecret: "invalid client credentials",
+	},
+	"ru": {
+		ReasonInternal:            "внутренняя ошибка",
+		ReasonDeadlineExceeded:    "время ожидания истекло",
+		ReasonCanceled:            "запрос отменён",
+		ReasonValidation:          "в запросе есть неверные поля",
+		ReasonRequestTooLarge:     "слишком большое тело запроса",
+		ReasonMethodNotAllowed:    "метод не разрешён",
+		ReasonRouteNotFound:       "путь не найден",
+		ReasonInvalidBody:         "неверное тело запроса",
+		ReasonNotSupported:        "сервис не поддерживается драйвером хранилища",
+		ReasonInvalidCredentials:  "неверные учётные данные",
+		ReasonMissingCredentials:  "нет учётных данных",
+		ReasonInvalidToken:        "неверный токен",
+		ReasonInvalidAPIKey:       "неверный api-ключ",
+		ReasonClientCertRequired:  "нужен клиентский сертификат",
+		ReasonInvalidRefreshToken: "неверный refresh-токен",
+		ReasonSessionExpired:      "сессия истекла",
+		ReasonInvalidPageToken:    "неверный токен страницы",
+		ReasonInvalidTimeRange:    "created_after должен быть раньше created_before",
+		ReasonInvalidResumeToken:  "неверный токен продолжения",
+		ReasonNotAdmin:            "вы не администратор",
+		ReasonNotCreator:          "вы не создатель",
+		ReasonNotSupport:          "вы не сотрудник поддержки",
+		ReasonNotOperator:         "вы не оператор",
+		ReasonNestedImpersonation: "токен имперсонации не может имперсонировать",
+		ReasonImpersonationDenied: "недоступно токену имперсонации",
+		ReasonPrivilegedTarget:    "пользователя с ролью нельзя имперсонировать",
+		ReasonUnknownRole:         "неизвестная роль",
+		ReasonRoleNotAllowed:      "вы не можете управлять этой ролью",
+		ReasonRoleEscalation:      "вы не можете управлять ролями выше своей",
+		ReasonLastCreator:         "нельзя удалить последнего создателя",
+		ReasonUserExists:          "пользователь уже существует",
+		ReasonUserNotFound:        "пользователь не найден",
+		ReasonUserSuspended:       "аккаунт заблокирован",
+		ReasonUserDeleted:         "аккаунт ожидает удаления",
+		ReasonInvalidStatus:       "неверный статус",
+		ReasonAppExists:           "приложение с таким именем уже существует",
+		ReasonAppDeleted:          "приложение удалено",
+		ReasonInvalidSchema:       "неверная схема метаданных",
+		ReasonInvalidMetadata:     "метаданные не соответствуют схеме",
+		ReasonInvalidSettings:     "неверные настройки приложения",
+		ReasonInvalidHook:         "неверный хук",
+		ReasonInvalidClaimMapping: "неверное сопоставление claims",
+		ReasonClaimMappingFailed:  "не удалось сопоставить claims приложения",
+		ReasonHookDenied:          "отклонено хуком",
+		ReasonHookFailed:          "ошибка хука",
+		ReasonAppRequired:         "нужен app_name, настроены хуки регистрации",
+		ReasonInvalidEventType:    "неверный тип события",
+		ReasonWebhookNotFound:     "вебхук не найден",
+		ReasonInvalidWebhookURL:   "неверный url вебхука",
+		ReasonAccountExists:       "сервисный аккаунт уже существует",
+		ReasonAccountNotFound:     "сервисный аккаунт не найден",
+		ReasonInvalidAccountName:  "имя может содержать только строчные буквы, цифры и дефисы",
+		ReasonAPIKeyNotFound:      "api-ключ не найден",
+		ReasonInvalidAPIKeyTTL:    "ttl не может быть отрицательным",
+		ReasonInvalidClientSecret: "неверные данные клиента",
+	},
+}
+
+// fieldMessages are the descriptions of field violations by locale and validation tag,
+// %s is the parameter of the tag
+var fieldMessages = map[string]map[string]string{
+	"en": {
+		"required":           "is a required field",
+		"email":              "is not a valid email",
+		"url":                "is not a valid URL",
+		"oneof":              "must be one of: %s",
+		"gt":                 "must be longer than %s characters",
+		"max":                "must be at most %s characters",
+		"bcp47_language_tag": "is not a valid locale",
+		"":                   "is not valid",
+	},
+	"ru": {
+		"required":           "обязательное поле",
+		"email":              "неверный email",
+		"url":                "неверный URL",
+		"oneof":              "должно быть одним из: %s",
+		"gt":                 "должно быть длиннее %s символов",
+		"max":                "должно быть не длиннее %s символов",
+		"bcp47_language_tag": "неверная локаль",
+		"":                   "неверное значение",
+	},
+}
+
+func message(reason Reason) string {
+	return messages[defaultLocale][reason]
+}
+
+func localize(locale string, reason Reason, msg string) string {
+	if locale != defaultLocale {
+		if localized, ok := messages[locale][reason]; ok {
+			return localized
+		}
+	}
+	return msg
+}
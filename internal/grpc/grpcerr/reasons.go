@@ -0,0 +1,64 @@
+package grpcerr
+
+// Reason is the ErrorInfo reason of an error. Clients branch on it instead of
+// the message, so a reason never changes once released
+type Reason string
+
+const (
+	ReasonInternal         Reason = "INTERNAL"
+	ReasonDeadlineExceeded Reason = "DEADLINE_EXCEEDED"
+	ReasonCanceled         Reason = "CANCELLED"
+	ReasonValidation       Reason = "VALIDATION_FAILED"
+	ReasonRequestTooLarge  Reason = "REQUEST_TOO_LARGE"
+	ReasonMethodNotAllowed Reason = "METHOD_NOT_ALLOWED"
+	ReasonRouteNotFound    Reason = "ROUTE_NOT_FOUND"
+	ReasonInvalidBody      Reason = "INVALID_BODY"
+	ReasonNotSupported     Reason = "NOT_SUPPORTED_BY_STORAGE"
+
+	ReasonInvalidCredentials  Reason = "INVALID_CREDENTIALS"
+	ReasonMissingCredentials  Reason = "MISSING_CREDENTIALS"
+	ReasonInvalidToken        Reason = "INVALID_TOKEN"
+	ReasonInvalidAPIKey       Reason = "INVALID_API_KEY"
+	ReasonClientCertRequired  Reason = "CLIENT_CERT_REQUIRED"
+	ReasonInvalidRefreshToken Reason = "INVALID_REFRESH_TOKEN"
+	ReasonSessionExpired      Reason = "SESSION_EXPIRED"
+	ReasonInvalidPageToken    Reason = "INVALID_PAGE_TOKEN"
+	ReasonInvalidTimeRange    Reason = "INVALID_TIME_RANGE"
+	ReasonInvalidResumeToken  Reason = "INVALID_RESUME_TOKEN"
+	ReasonNotAdmin            Reason = "NOT_ADMIN"
+	ReasonNotCreator          Reason = "NOT_CREATOR"
+	ReasonNotSupport          Reason = "NOT_SUPPORT"
+	ReasonNotOperator         Reason = "NOT_OPERATOR"
+	ReasonNestedImpersonation Reason = "NESTED_IMPERSONATION"
+	ReasonImpersonationDenied Reason = "IMPERSONATION_DENIED"
+	ReasonPrivilegedTarget    Reason = "PRIVILEGED_TARGET"
+	ReasonUnknownRole         Reason = "UNKNOWN_ROLE"
+	ReasonRoleNotAllowed      Reason = "ROLE_NOT_ALLOWED"
+	ReasonRoleEscalation      Reason = "ROLE_ESCALATION"
+	ReasonLastCreator         Reason = "LAST_CREATOR"
+	ReasonUserExists          Reason = "USER_EXISTS"
+	ReasonUserNotFound        Reason = "USER_NOT_FOUND"
+	ReasonUserSuspended       Reason = "USER_SUSPENDED"
+	ReasonUserDeleted         Reason = "USER_DELETED"
+	ReasonInvalidStatus       Reason = "INVALID_STATUS"
+	ReasonAppExists           Reason = "APP_EXISTS"
+	ReasonAppDeleted          Reason = "APP_DELETED"
+	ReasonInvalidSchema       Reason = "INVALID_METADATA_SCHEMA"
+	ReasonInvalidMetadata     Reason = "INVALID_METADATA"
+	ReasonInvalidSettings     Reason = "INVALID_APP_SETTINGS"
+	ReasonInvalidHook         Reason = "INVALID_HOOK"
+	ReasonInvalidClaimMapping Reason = "INVALID_CLAIM_MAPPING"
+	ReasonClaimMappingFailed  Reason = "CLAIM_MAPPING_FAILED"
+	ReasonHookDenied          Reason = "HOOK_DENIED"
+	ReasonHookFailed          Reason = "HOOK_FAILED"
+	ReasonAppRequired         Reason = "APP_NAME_REQUIRED"
+	ReasonInvalidEventType    Reason = "INVALID_EVENT_TYPE"
+	ReasonWebhookNotFound     Reason = "WEBHOOK_NOT_FOUND"
+	ReasonInvalidWebhookURL   Reason = "INVALID_WEBHOOK_URL"
+	ReasonAccountExists       Reason = "SERVICE_ACCOUNT_EXISTS"
+	ReasonAccountNotFound     Reason = "SERVICE_ACCOUNT_NOT_FOUND"
+	ReasonInvalidAccountName  Reason = "INVALID_SERVICE_ACCOUNT_NAME"
+	ReasonAPIKeyNotFound      Reason = "API_KEY_NOT_FOUND"
+	ReasonInvalidAPIKeyTTL    Reason = "INVALID_API_KEY_TTL"
+	ReasonInvalidClientSecret Reason = "INVALID_CLIENT_CREDENTIALS"
+)
@@ -0,0 +1,86 @@
+package grpcerr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fieldFormats are how a violation reads in the message of the status by locale
+var fieldFormats = map[string]string{
+	"en": "field %s %s",
+	"ru": "поле %s: %s",
+}
+
+// Validation returns InvalidArgument with a BadRequest violation per field that failed
+// validation. Fields of request structs are named like the fields of the proto request
+// in Go, AppName is reported as app_name
+func Validation(ctx context.Context, err error) error {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return New(ctx, codes.Internal, ReasonInternal)
+	}
+
+	locale := Locale(ctx)
+	var violations []*errdetails.BadRequest_FieldViolation
+	var msgs, localized []string
+	for _, fe := range errs {
+		field := protoName(fe.Field())
+		description := fieldMessage(defaultLocale, fe)
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+		msgs = append(msgs, fmt.Sprintf(fieldFormats[defaultLocale], field, description))
+		localized = append(localized, fmt.Sprintf(fieldFormats[locale], field, fieldMessage(locale, fe)))
+	}
+
+	st := status.New(codes.InvalidArgument, strings.Join(msgs, ", "))
+	return attach(st,
+		errorInfo(ReasonValidation),
+		&errdetails.LocalizedMessage{Locale: locale, Message: strings.Join(localized, ", ")},
+		&errdetails.BadRequest{FieldViolations: violations},
+	)
+}
+
+// Pagination returns InvalidArgument for an error of pagination.Filter
+// or pagination.DecodeToken
+func Pagination(ctx context.Context, err error) error {
+	if errors.Is(err, pagination.ErrInvalidRange) {
+		return InvalidField(ctx, ReasonInvalidTimeRange, "created_after", "must be before created_before")
+	}
+	return InvalidField(ctx, ReasonInvalidPageToken, "page_token", "is not a valid page token")
+}
+
+func fieldMessage(locale string, fe validator.FieldError) string {
+	msg, ok := fieldMessages[locale][fe.ActualTag()]
+	if !ok {
+		msg = fieldMessages[locale][""]
+	}
+	if strings.Contains(msg, "%s") {
+		return fmt.Sprintf(msg, fe.Param())
+	}
+	return msg
+}
+
+// protoName turns a Go field name into the proto one, UserID into user_id
+func protoName(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := !unicode.IsUpper(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
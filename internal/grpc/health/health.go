@@ -0,0 +1,128 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type MigrationProvider interface {
+	MigrationVersion(ctx context.Context) (uint, bool, error)
+}
+
+var (
+	ErrDirtyMigration    = errors.New("last migration failed, database is dirty")
+	ErrMigrationsPending = errors.New("database schema is behind the migrations")
+)
+
+// Checker serves grpc.health.v1 and sets the status of every registered service
+// from a ping of Postgres and the state of migrations. All services need Postgres,
+// so they go NOT_SERVING together
+type Checker struct {
+	log        *slog.Logger
+	server     *health.Server
+	pinger     Pinger
+	migrations MigrationProvider
+	version    uint
+	timeout    time.Duration
+
+	mu       sync.Mutex
+	services []string
+	serving  bool
+}
+
+// New returns a checker that reports NOT_SERVING until the first successful check,
+// version is the latest migration the schema must have
+func New(log *slog.Logger, pinger Pinger, migrations MigrationProvider, version uint, timeout time.Duration) *Checker {
+	server := health.NewServer()
+	server.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return &Checker{
+		log:        log,
+		server:     server,
+		pinger:     pinger,
+		migrations: migrations,
+		version:    version,
+		timeout:    timeout,
+	}
+}
+
+// Register adds the health service to the server, call it after the other services
+// are registered so they get a status too
+func Register(gRPC *grpc.Server, checker *Checker) {
+	checker.mu.Lock()
+	for name := range gRPC.GetServiceInfo() {
+		checker.services = append(checker.services, name)
+		checker.server.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	checker.mu.Unlock()
+	healthpb.RegisterHealthServer(gRPC, checker.server)
+}
+
+// Check pings Postgres and checks migrations, then updates the status of services.
+// It runs as a background job
+func (c *Checker) Check(ctx context.Context) error {
+	const op = "grpc.health.Check"
+	log := c.log.With(slog.String("op", op))
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	err := c.check(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	serving := err == nil
+	if serving != c.serving {
+		if serving {
+			log.Info("services are serving")
+		} else {
+			log.Warn("services are not serving", slog.String("reason", err.Error()))
+		}
+	}
+	c.serving = serving
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	c.server.SetServingStatus("", status)
+	for _, name := range c.services {
+		c.server.SetServingStatus(name, status)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (c *Checker) check(ctx context.Context) error {
+	if err := c.pinger.Ping(ctx); err != nil {
+		return err
+	}
+	version, dirty, err := c.migrations.MigrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return ErrDirtyMigration
+	}
+	if version < c.version {
+		return ErrMigrationsPending
+	}
+	return nil
+}
+
+// Shutdown sets every service NOT_SERVING for good, so probes stop routing
+// to the server while it drains
+func (c *Checker) Shutdown() {
+	c.server.Shutdown()
+}
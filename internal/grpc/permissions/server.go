@@ -2,16 +2,15 @@ package permissions
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/pagination"
 	perm "github.com/neepooha/sso/internal/services/permissions"
-	"strings"
 
 	"github.com/go-playground/validator/v10"
 	ssov2 "github.com/neepooha/protos/gen/go/sso"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
 type Perm interface {
@@ -19,6 +18,10 @@ type Perm interface {
 	DelAdmin(ctx context.Context, email string, appName string) (bool, error)
 	IsAdmin(ctx context.Context, userID uint64, appName string) (bool, error)
 	IsCreator(ctx context.Context, userID uint64, appName string) (bool, error)
+	ListAdmins(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, string, error)
+	ListCreators(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, string, error)
+	GrantRole(ctx context.Context, appName string, email string, role string) (bool, error)
+	RevokeRole(ctx context.Context, appName string, email string, role string) (bool, error)
 }
 
 type SetDelAdminReq struct {
@@ -26,6 +29,13 @@ type SetDelAdminReq struct {
 	AppName string `validate:"required"`
 }
 
+// RoleReq takes an email of a human or a client id of a service account
+type RoleReq struct {
+	AppName string `validate:"required"`
+	Email   string `validate:"required"`
+	Role    string `validate:"required"`
+}
+
 type IsAdmin struct {
 	UserID  uint64 `validate:"required"`
 	AppName string `validate:"required"`
@@ -36,6 +46,19 @@ type IsCreator struct {
 	AppName string `validate:"required"`
 }
 
+type ListMembersReq struct {
+	AppName string `validate:"required"`
+}
+
+var errs = grpcerr.Table{
+	{Err: perm.ErrInvalidCredentials, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+	{Err: perm.ErrNotCreator, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotCreator},
+	{Err: perm.ErrUnknownRole, Code: codes.InvalidArgument, Reason: grpcerr.ReasonUnknownRole},
+	{Err: perm.ErrNotAllowed, Code: codes.PermissionDenied, Reason: grpcerr.ReasonRoleNotAllowed},
+	{Err: perm.ErrEscalation, Code: codes.PermissionDenied, Reason: grpcerr.ReasonRoleEscalation},
+	{Err: perm.ErrLastOwner, Code: codes.FailedPrecondition, Reason: grpcerr.ReasonLastCreator},
+}
+
 type serverAPI struct {
 	ssov2.UnimplementedPermissionsServer
 	perm Perm
@@ -46,127 +69,172 @@ func Register(gRPC *grpc.Server, perm Perm) {
 }
 
 func (s *serverAPI) IsAdmin(ctx context.Context, req *ssov2.IsAdminRequest) (*ssov2.IsAdminResponse, error) {
-	if err := ValidateIsAdm(req); err != nil {
+	if err := ValidateIsAdm(ctx, req); err != nil {
 		return nil, err
 	}
 
 	isadmin, err := s.perm.IsAdmin(ctx, req.GetUserId(), req.AppName)
 	if err != nil {
-		if errors.Is(err, perm.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, errs.Error(ctx, err)
 	}
 	return &ssov2.IsAdminResponse{IsAdmin: isadmin}, nil
 }
 
 func (s *serverAPI) IsCreator(ctx context.Context, req *ssov2.IsCreatorRequest) (*ssov2.IsCreatorResponse, error) {
-	if err := ValidateIsCreator(req); err != nil {
+	if err := ValidateIsCreator(ctx, req); err != nil {
 		return nil, err
 	}
 	iscreator, err := s.perm.IsCreator(ctx, req.GetUserId(), req.GetAppName())
 	if err != nil {
-		if errors.Is(err, perm.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, errs.Error(ctx, err)
 	}
 	return &ssov2.IsCreatorResponse{IsCreator: iscreator}, nil
 }
 
 func (s *serverAPI) SetAdmin(ctx context.Context, req *ssov2.SetAdminRequest) (*ssov2.SetAdminResponse, error) {
-	if err := ValidateSet(req); err != nil {
+	if err := ValidateSet(ctx, req); err != nil {
 		return nil, err
 	}
 
 	setAdmin, err := s.perm.SetAdmin(ctx, req.GetEmail(), req.GetAppName())
 	if err != nil {
-		if errors.Is(err, perm.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, errs.Error(ctx, err)
 	}
 
 	return &ssov2.SetAdminResponse{SetAdmin: setAdmin}, nil
 }
 
 func (s *serverAPI) DelAdmin(ctx context.Context, req *ssov2.DelAdminRequest) (*ssov2.DelAdminResponse, error) {
-	if err := ValidateDel(req); err != nil {
+	if err := ValidateDel(ctx, req); err != nil {
 		return nil, err
 	}
 
 	delAdmin, err := s.perm.DelAdmin(ctx, req.GetEmail(), req.GetAppName())
 	if err != nil {
-		if errors.Is(err, perm.ErrInvalidCredentials) {
-			return nil, status.Error(codes.InvalidArgument, "invalid credentials")
-		}
-		return nil, status.Error(codes.Internal, "internal error")
+		return nil, errs.Error(ctx, err)
 	}
 
 	return &ssov2.DelAdminResponse{DelAdmin: delAdmin}, nil
 }
 
-func ValidateSet(req *ssov2.SetAdminRequest) error {
+func (s *serverAPI) GrantRole(ctx context.Context, req *ssov2.GrantRoleRequest) (*ssov2.GrantRoleResponse, error) {
+	if err := ValidateRole(ctx, req.GetAppName(), req.GetEmail(), req.GetRole()); err != nil {
+		return nil, err
+	}
+
+	granted, err := s.perm.GrantRole(ctx, req.GetAppName(), req.GetEmail(), req.GetRole())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.GrantRoleResponse{IsGranted: granted}, nil
+}
+
+func (s *serverAPI) RevokeRole(ctx context.Context, req *ssov2.RevokeRoleRequest) (*ssov2.RevokeRoleResponse, error) {
+	if err := ValidateRole(ctx, req.GetAppName(), req.GetEmail(), req.GetRole()); err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.perm.RevokeRole(ctx, req.GetAppName(), req.GetEmail(), req.GetRole())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.RevokeRoleResponse{IsRevoked: revoked}, nil
+}
+
+func (s *serverAPI) ListAdmins(ctx context.Context, req *ssov2.ListMembersRequest) (*ssov2.ListMembersResponse, error) {
+	return s.listMembers(ctx, req, s.perm.ListAdmins)
+}
+
+func (s *serverAPI) ListCreators(ctx context.Context, req *ssov2.ListMembersRequest) (*ssov2.ListMembersResponse, error) {
+	return s.listMembers(ctx, req, s.perm.ListCreators)
+}
+
+type memberLister func(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, string, error)
+
+func (s *serverAPI) listMembers(ctx context.Context, req *ssov2.ListMembersRequest, list memberLister) (*ssov2.ListMembersResponse, error) {
+	if err := ValidateListMembers(ctx, req); err != nil {
+		return nil, err
+	}
+	filter, err := pagination.Filter(req.GetEmailPrefix(), req.GetCreatedAfter(), req.GetCreatedBefore(), req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, grpcerr.Pagination(ctx, err)
+	}
+
+	members, next, err := list(ctx, req.GetAppName(), filter)
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+
+	resp := &ssov2.ListMembersResponse{NextPageToken: next}
+	for _, member := range members {
+		resp.Members = append(resp.Members, &ssov2.Member{
+			UserId:    member.UserID,
+			Email:     member.Email,
+			CreatedAt: pagination.Timestamp(member.CreatedAt),
+		})
+	}
+	return resp, nil
+}
+
+func ValidateSet(ctx context.Context, req *ssov2.SetAdminRequest) error {
 	var reqStruct SetDelAdminReq
 	reqStruct.Email = req.GetEmail()
 	reqStruct.AppName = req.GetAppName()
 
 	if err := validator.New().Struct(reqStruct); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidateDel(req *ssov2.DelAdminRequest) error {
+func ValidateDel(ctx context.Context, req *ssov2.DelAdminRequest) error {
 	var reqStruct SetDelAdminReq
 	reqStruct.Email = req.GetEmail()
 	reqStruct.AppName = req.GetAppName()
 
 	if err := validator.New().Struct(reqStruct); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateRole(ctx context.Context, appName string, email string, role string) error {
+	reqStruct := RoleReq{AppName: appName, Email: email, Role: role}
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidateIsAdm(req *ssov2.IsAdminRequest) error {
+func ValidateIsAdm(ctx context.Context, req *ssov2.IsAdminRequest) error {
 	var reqStruct IsAdmin
 	reqStruct.UserID = req.GetUserId()
 	reqStruct.AppName = req.GetAppName()
 
 	if err := validator.New().Struct(reqStruct); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidateIsCreator(req *ssov2.IsCreatorRequest) error {
+func ValidateIsCreator(ctx context.Context, req *ssov2.IsCreatorRequest) error {
 	var reqStruct IsCreator
 	reqStruct.UserID = req.GetUserId()
 	reqStruct.AppName = req.GetAppName()
 
 	if err := validator.New().Struct(reqStruct); err != nil {
-		validateErr := err.(validator.ValidationErrors)
-		return ValidationError(validateErr)
+		return grpcerr.Validation(ctx, err)
 	}
 	return nil
 }
 
-func ValidationError(errs validator.ValidationErrors) error {
-	var errMsgs []string
+func ValidateListMembers(ctx context.Context, req *ssov2.ListMembersRequest) error {
+	var reqStruct ListMembersReq
+	reqStruct.AppName = req.GetAppName()
 
-	for _, err := range errs {
-		switch err.ActualTag() {
-		case "required":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
-		case "email":
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid email", err.Field()))
-		default:
-			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid", err.Field()))
-		}
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
 	}
-
-	return errors.New(strings.Join(errMsgs, ", "))
+	return nil
 }
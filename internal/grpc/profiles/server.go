@@ -0,0 +1,155 @@
+package profiles
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/jsonstruct"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/services/profiles"
+
+	"github.com/go-playground/validator/v10"
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type Profiles interface {
+	GetProfile(ctx context.Context, appName string, userID uint64) (models.Profile, error)
+	UpdateProfile(ctx context.Context, appName string, upd models.ProfileUpdate, userMetadata []byte) (models.Profile, error)
+	UpdateAppMetadata(ctx context.Context, appName string, userID uint64, appMetadata []byte) (models.Profile, error)
+}
+
+type GetProfileReq struct {
+	AppName string `validate:"required"`
+}
+
+type UpdateProfileReq struct {
+	AppName     string  `validate:"required"`
+	DisplayName *string `validate:"omitempty,max=128"`
+	Locale      *string `validate:"omitempty,bcp47_language_tag"`
+	AvatarURL   *string `validate:"omitempty,url"`
+}
+
+type UpdateAppMetadataReq struct {
+	AppName string `validate:"required"`
+	UserID  uint64 `validate:"required"`
+}
+
+var errs = grpcerr.Table{
+	{Err: profiles.ErrNotAdmin, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotAdmin},
+	{Err: profiles.ErrInvalidMetadata, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidMetadata},
+	{Err: profiles.ErrInvalidCredentials, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+}
+
+type serverAPI struct {
+	ssov2.UnimplementedProfilesServer
+	profiles Profiles
+}
+
+func Register(gRPC *grpc.Server, profiles Profiles) {
+	ssov2.RegisterProfilesServer(gRPC, &serverAPI{profiles: profiles})
+}
+
+func (s *serverAPI) GetProfile(ctx context.Context, req *ssov2.GetProfileRequest) (*ssov2.GetProfileResponse, error) {
+	if err := ValidateGet(ctx, req); err != nil {
+		return nil, err
+	}
+
+	profile, err := s.profiles.GetProfile(ctx, req.GetAppName(), req.GetUserId())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.GetProfileResponse{Profile: toProto(profile)}, nil
+}
+
+func (s *serverAPI) UpdateProfile(ctx context.Context, req *ssov2.UpdateProfileRequest) (*ssov2.UpdateProfileResponse, error) {
+	if err := ValidateUpdate(ctx, req); err != nil {
+		return nil, err
+	}
+	userMetadata, err := jsonstruct.ToJSON(req.GetUserMetadata())
+	if err != nil {
+		return nil, grpcerr.InvalidField(ctx, grpcerr.ReasonInvalidMetadata, "user_metadata", "is not a valid JSON object")
+	}
+
+	upd := models.ProfileUpdate{
+		DisplayName: req.DisplayName,
+		Locale:      req.Locale,
+		AvatarURL:   req.AvatarUrl,
+	}
+	profile, err := s.profiles.UpdateProfile(ctx, req.GetAppName(), upd, userMetadata)
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.UpdateProfileResponse{Profile: toProto(profile)}, nil
+}
+
+func (s *serverAPI) UpdateAppMetadata(ctx context.Context, req *ssov2.UpdateAppMetadataRequest) (*ssov2.UpdateAppMetadataResponse, error) {
+	if err := ValidateUpdateAppMetadata(ctx, req); err != nil {
+		return nil, err
+	}
+	appMetadata, err := jsonstruct.ToJSON(req.GetAppMetadata())
+	if err != nil {
+		return nil, grpcerr.InvalidField(ctx, grpcerr.ReasonInvalidMetadata, "app_metadata", "is not a valid JSON object")
+	}
+	if appMetadata == nil {
+		appMetadata = []byte("{}")
+	}
+
+	profile, err := s.profiles.UpdateAppMetadata(ctx, req.GetAppName(), req.GetUserId(), appMetadata)
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.UpdateAppMetadataResponse{Profile: toProto(profile)}, nil
+}
+
+func toProto(profile models.Profile) *ssov2.Profile {
+	// metadata is stored as JSONB, so it is always a valid JSON object
+	appMetadata, _ := jsonstruct.FromJSON(profile.AppMetadata)
+	userMetadata, _ := jsonstruct.FromJSON(profile.UserMetadata)
+	return &ssov2.Profile{
+		UserId:       profile.User.ID,
+		Email:        profile.User.Email,
+		DisplayName:  profile.User.DisplayName,
+		Locale:       profile.User.Locale,
+		AvatarUrl:    profile.User.AvatarURL,
+		CreatedAt:    pagination.Timestamp(profile.User.CreatedAt),
+		UpdatedAt:    pagination.Timestamp(profile.User.UpdatedAt),
+		AppMetadata:  appMetadata,
+		UserMetadata: userMetadata,
+	}
+}
+
+func ValidateGet(ctx context.Context, req *ssov2.GetProfileRequest) error {
+	var reqStruct GetProfileReq
+	reqStruct.AppName = req.GetAppName()
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateUpdate(ctx context.Context, req *ssov2.UpdateProfileRequest) error {
+	var reqStruct UpdateProfileReq
+	reqStruct.AppName = req.GetAppName()
+	reqStruct.DisplayName = req.DisplayName
+	reqStruct.Locale = req.Locale
+	reqStruct.AvatarURL = req.AvatarUrl
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateUpdateAppMetadata(ctx context.Context, req *ssov2.UpdateAppMetadataRequest) error {
+	var reqStruct UpdateAppMetadataReq
+	reqStruct.AppName = req.GetAppName()
+	reqStruct.UserID = req.GetUserId()
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
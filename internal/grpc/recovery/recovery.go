@@ -0,0 +1,45 @@
+package recovery
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"log/slog"
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// UnaryServerInterceptor turns a panic of the handler into codes.Internal and logs it
+// with the stack, so one bad request can't crash the process
+func UnaryServerInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ctx, log, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams
+func StreamServerInterceptor(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ss.Context(), log, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+func recovered(ctx context.Context, log *slog.Logger, r any) error {
+	sl.FromContext(ctx, log).Error("panic in handler",
+		slog.Any("panic", r),
+		slog.String("stack", string(debug.Stack())),
+	)
+	return grpcerr.New(ctx, codes.Internal, grpcerr.ReasonInternal)
+}
@@ -0,0 +1,81 @@
+package requestlog
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/lib/clientip"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/requestid"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor takes the request ID from x-request-id metadata or assigns one,
+// returns it in the response header, puts a logger with it into the context and writes
+// one access log line per call
+func UnaryServerInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, reqLog := begin(ctx, log, info.FullMethod)
+		resp, err := handler(ctx, req)
+		access(ctx, reqLog, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streams, the access log line
+// is written when the stream ends
+func StreamServerInterceptor(log *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, reqLog := begin(ss.Context(), log, info.FullMethod)
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		access(ctx, reqLog, start, err)
+		return err
+	}
+}
+
+func begin(ctx context.Context, log *slog.Logger, method string) (context.Context, *slog.Logger) {
+	var id string
+	if ids := metadata.ValueFromIncomingContext(ctx, requestid.Header); len(ids) == 1 && requestid.Valid(ids[0]) {
+		id = ids[0]
+	} else {
+		id = requestid.New()
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestid.Header, id)); err != nil {
+		log.Warn("failed to set request id header", sl.Err(err))
+	}
+
+	reqLog := log.With(slog.String("request_id", id), slog.String("method", method))
+	ctx = requestid.NewContext(ctx, id)
+	ctx = sl.NewContext(ctx, reqLog)
+	return ctx, reqLog
+}
+
+func access(ctx context.Context, log *slog.Logger, start time.Time, err error) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("peer", clientip.FromContext(ctx)),
+	}
+	level := slog.LevelInfo
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		level = slog.LevelError
+	}
+	log.LogAttrs(ctx, level, "request handled", attrs...)
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,172 @@
+package serviceaccounts
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/services/serviceaccounts"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type ServiceAccounts interface {
+	CreateServiceAccount(ctx context.Context, appName string, name string) (models.ServiceAccount, error)
+	ListServiceAccounts(ctx context.Context, appName string, filter models.ListFilter) ([]models.ServiceAccount, string, error)
+	DeleteServiceAccount(ctx context.Context, appName string, userID uint64) (bool, error)
+	CreateAPIKey(ctx context.Context, appName string, userID uint64, ttl time.Duration) (string, models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, appName string, userID uint64, keyID uint64) (bool, error)
+	IssueToken(ctx context.Context, appName string, clientID string, clientSecret string) (string, time.Time, error)
+}
+
+type CreateReq struct {
+	AppName string `validate:"required"`
+	Name    string `validate:"required"`
+}
+
+type AppReq struct {
+	AppName string `validate:"required"`
+}
+
+type AccountReq struct {
+	AppName string `validate:"required"`
+	UserID  uint64 `validate:"required"`
+}
+
+type RevokeKeyReq struct {
+	AppName string `validate:"required"`
+	UserID  uint64 `validate:"required"`
+	KeyID   uint64 `validate:"required"`
+}
+
+type IssueTokenReq struct {
+	AppName      string `validate:"required"`
+	ClientID     string `validate:"required"`
+	ClientSecret string `validate:"required"`
+}
+
+var errs = grpcerr.Table{
+	{Err: serviceaccounts.ErrInvalidName, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidAccountName},
+	{Err: serviceaccounts.ErrInvalidTTL, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidAPIKeyTTL},
+	{Err: serviceaccounts.ErrAccountExists, Code: codes.AlreadyExists, Reason: grpcerr.ReasonAccountExists},
+	{Err: serviceaccounts.ErrAccountNotFound, Code: codes.NotFound, Reason: grpcerr.ReasonAccountNotFound},
+	{Err: serviceaccounts.ErrKeyNotFound, Code: codes.NotFound, Reason: grpcerr.ReasonAPIKeyNotFound},
+	{Err: serviceaccounts.ErrInvalidKey, Code: codes.Unauthenticated, Reason: grpcerr.ReasonInvalidClientSecret},
+	{Err: serviceaccounts.ErrInvalidCredentials, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+}
+
+type serverAPI struct {
+	ssov2.UnimplementedServiceAccountsServer
+	accounts ServiceAccounts
+}
+
+func Register(gRPC *grpc.Server, accounts ServiceAccounts) {
+	ssov2.RegisterServiceAccountsServer(gRPC, &serverAPI{accounts: accounts})
+}
+
+func (s *serverAPI) CreateServiceAccount(ctx context.Context, req *ssov2.CreateServiceAccountRequest) (*ssov2.CreateServiceAccountResponse, error) {
+	if err := validate(ctx, CreateReq{AppName: req.GetAppName(), Name: req.GetName()}); err != nil {
+		return nil, err
+	}
+
+	account, err := s.accounts.CreateServiceAccount(ctx, req.GetAppName(), req.GetName())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.CreateServiceAccountResponse{ServiceAccount: toProto(account)}, nil
+}
+
+func (s *serverAPI) ListServiceAccounts(ctx context.Context, req *ssov2.ListServiceAccountsRequest) (*ssov2.ListServiceAccountsResponse, error) {
+	if err := validate(ctx, AppReq{AppName: req.GetAppName()}); err != nil {
+		return nil, err
+	}
+	filter, err := pagination.Filter(req.GetNamePrefix(), req.GetCreatedAfter(), req.GetCreatedBefore(), req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, grpcerr.Pagination(ctx, err)
+	}
+
+	accounts, next, err := s.accounts.ListServiceAccounts(ctx, req.GetAppName(), filter)
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	resp := &ssov2.ListServiceAccountsResponse{NextPageToken: next}
+	for _, account := range accounts {
+		resp.ServiceAccounts = append(resp.ServiceAccounts, toProto(account))
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) DeleteServiceAccount(ctx context.Context, req *ssov2.DeleteServiceAccountRequest) (*ssov2.DeleteServiceAccountResponse, error) {
+	if err := validate(ctx, AccountReq{AppName: req.GetAppName(), UserID: req.GetUserId()}); err != nil {
+		return nil, err
+	}
+
+	isDeleted, err := s.accounts.DeleteServiceAccount(ctx, req.GetAppName(), req.GetUserId())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.DeleteServiceAccountResponse{IsDeleted: isDeleted}, nil
+}
+
+func (s *serverAPI) CreateAPIKey(ctx context.Context, req *ssov2.CreateAPIKeyRequest) (*ssov2.CreateAPIKeyResponse, error) {
+	if err := validate(ctx, AccountReq{AppName: req.GetAppName(), UserID: req.GetUserId()}); err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(req.GetTtlSeconds()) * time.Second
+	secret, key, err := s.accounts.CreateAPIKey(ctx, req.GetAppName(), req.GetUserId(), ttl)
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.CreateAPIKeyResponse{
+		KeyId:     key.ID,
+		Key:       secret,
+		Prefix:    key.Prefix,
+		ExpiresAt: pagination.Timestamp(key.ExpiresAt),
+	}, nil
+}
+
+func (s *serverAPI) RevokeAPIKey(ctx context.Context, req *ssov2.RevokeAPIKeyRequest) (*ssov2.RevokeAPIKeyResponse, error) {
+	if err := validate(ctx, RevokeKeyReq{AppName: req.GetAppName(), UserID: req.GetUserId(), KeyID: req.GetKeyId()}); err != nil {
+		return nil, err
+	}
+
+	isRevoked, err := s.accounts.RevokeAPIKey(ctx, req.GetAppName(), req.GetUserId(), req.GetKeyId())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.RevokeAPIKeyResponse{IsRevoked: isRevoked}, nil
+}
+
+func (s *serverAPI) IssueToken(ctx context.Context, req *ssov2.IssueTokenRequest) (*ssov2.IssueTokenResponse, error) {
+	if err := validate(ctx, IssueTokenReq{AppName: req.GetAppName(), ClientID: req.GetClientId(), ClientSecret: req.GetClientSecret()}); err != nil {
+		return nil, err
+	}
+
+	token, expiresAt, err := s.accounts.IssueToken(ctx, req.GetAppName(), req.GetClientId(), req.GetClientSecret())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.IssueTokenResponse{Token: token, ExpiresAt: pagination.Timestamp(expiresAt)}, nil
+}
+
+func toProto(a models.ServiceAccount) *ssov2.ServiceAccount {
+	return &ssov2.ServiceAccount{
+		UserId:    a.UserID,
+		ClientId:  a.ClientID,
+		Name:      a.Name,
+		CreatedBy: a.CreatedBy,
+		CreatedAt: pagination.Timestamp(a.CreatedAt),
+	}
+}
+
+func validate(ctx context.Context, req any) error {
+	if err := validator.New().Struct(req); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
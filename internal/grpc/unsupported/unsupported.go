@@ -0,0 +1,54 @@
+// Package unsupported fails calls of the gRPC services the storage driver doesn't
+// have with codes.Unimplemented, so clients can tell a missing feature from a bug
+package unsupported
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// Services are the unsupported services by full name, like profiles.Profiles
+type Services struct {
+	names map[string]bool
+}
+
+func New(services ...string) *Services {
+	names := make(map[string]bool, len(services))
+	for _, name := range services {
+		names[name] = true
+	}
+	return &Services{names: names}
+}
+
+// Unary returns the interceptor for unary methods. It runs before authorization,
+// so a call is refused the same way whoever makes it
+func (s *Services) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := s.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream is Unary for streams
+func (s *Services) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := s.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (s *Services) check(ctx context.Context, method string) error {
+	service, _, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	if s.names[service] {
+		return grpcerr.New(ctx, codes.Unimplemented, grpcerr.ReasonNotSupported)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package webhooks
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/grpc/grpcerr"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/services/webhooks"
+
+	"github.com/go-playground/validator/v10"
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type Webhooks interface {
+	CreateWebhook(ctx context.Context, appName string, url string, eventTypes []string) (models.Webhook, error)
+	ListWebhooks(ctx context.Context, appName string) ([]models.Webhook, error)
+	DelWebhook(ctx context.Context, appName string, webhookID int) (bool, error)
+	ListDeadLetters(ctx context.Context, appName string, filter models.ListFilter) ([]models.DeadLetter, string, error)
+	ReplayDeadLetters(ctx context.Context, appName string, ids []uint64) (int, error)
+}
+
+type CreateWebhookReq struct {
+	AppName string `validate:"required"`
+	URL     string `validate:"required,url"`
+}
+
+type AppReq struct {
+	AppName string `validate:"required"`
+}
+
+type DeleteWebhookReq struct {
+	AppName   string `validate:"required"`
+	WebhookID int64  `validate:"required"`
+}
+
+var errs = grpcerr.Table{
+	{Err: webhooks.ErrNotCreator, Code: codes.PermissionDenied, Reason: grpcerr.ReasonNotCreator},
+	{Err: webhooks.ErrWebhookNotFound, Code: codes.NotFound, Reason: grpcerr.ReasonWebhookNotFound},
+	{Err: webhooks.ErrInvalidURL, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidWebhookURL},
+	{Err: webhooks.ErrInvalidEventType, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidEventType},
+	{Err: webhooks.ErrInvalidCredentials, Code: codes.InvalidArgument, Reason: grpcerr.ReasonInvalidCredentials},
+}
+
+type serverAPI struct {
+	ssov2.UnimplementedWebhooksServer
+	webhooks Webhooks
+}
+
+func Register(gRPC *grpc.Server, webhooks Webhooks) {
+	ssov2.RegisterWebhooksServer(gRPC, &serverAPI{webhooks: webhooks})
+}
+
+func (s *serverAPI) CreateWebhook(ctx context.Context, req *ssov2.CreateWebhookRequest) (*ssov2.CreateWebhookResponse, error) {
+	if err := ValidateCreate(ctx, req); err != nil {
+		return nil, err
+	}
+
+	webhook, err := s.webhooks.CreateWebhook(ctx, req.GetAppName(), req.GetUrl(), req.GetEventTypes())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.CreateWebhookResponse{Webhook: toProto(webhook), Secret: webhook.Secret}, nil
+}
+
+func (s *serverAPI) ListWebhooks(ctx context.Context, req *ssov2.ListWebhooksRequest) (*ssov2.ListWebhooksResponse, error) {
+	if err := ValidateApp(ctx, req.GetAppName()); err != nil {
+		return nil, err
+	}
+
+	list, err := s.webhooks.ListWebhooks(ctx, req.GetAppName())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	resp := &ssov2.ListWebhooksResponse{}
+	for _, w := range list {
+		resp.Webhooks = append(resp.Webhooks, toProto(w))
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) DeleteWebhook(ctx context.Context, req *ssov2.DeleteWebhookRequest) (*ssov2.DeleteWebhookResponse, error) {
+	if err := ValidateDelete(ctx, req); err != nil {
+		return nil, err
+	}
+
+	isDeleted, err := s.webhooks.DelWebhook(ctx, req.GetAppName(), int(req.GetWebhookId()))
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.DeleteWebhookResponse{IsDeleted: isDeleted}, nil
+}
+
+func (s *serverAPI) ListDeadLetters(ctx context.Context, req *ssov2.ListDeadLettersRequest) (*ssov2.ListDeadLettersResponse, error) {
+	if err := ValidateApp(ctx, req.GetAppName()); err != nil {
+		return nil, err
+	}
+	filter, err := pagination.Filter("", nil, nil, req.GetPageSize(), req.GetPageToken())
+	if err != nil {
+		return nil, grpcerr.Pagination(ctx, err)
+	}
+
+	letters, next, err := s.webhooks.ListDeadLetters(ctx, req.GetAppName(), filter)
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	resp := &ssov2.ListDeadLettersResponse{NextPageToken: next}
+	for _, l := range letters {
+		resp.DeadLetters = append(resp.DeadLetters, &ssov2.DeadLetter{
+			Id:        l.ID,
+			WebhookId: int64(l.WebhookID),
+			EventId:   l.EventID,
+			EventType: l.EventType,
+			Attempts:  int32(l.Attempts),
+			LastError: l.LastError,
+			FailedAt:  pagination.Timestamp(l.FailedAt),
+		})
+	}
+	return resp, nil
+}
+
+func (s *serverAPI) ReplayDeadLetters(ctx context.Context, req *ssov2.ReplayDeadLettersRequest) (*ssov2.ReplayDeadLettersResponse, error) {
+	if err := ValidateApp(ctx, req.GetAppName()); err != nil {
+		return nil, err
+	}
+
+	replayed, err := s.webhooks.ReplayDeadLetters(ctx, req.GetAppName(), req.GetIds())
+	if err != nil {
+		return nil, errs.Error(ctx, err)
+	}
+	return &ssov2.ReplayDeadLettersResponse{Replayed: int32(replayed)}, nil
+}
+
+func toProto(w models.Webhook) *ssov2.Webhook {
+	return &ssov2.Webhook{
+		Id:         int64(w.ID),
+		Url:        w.URL,
+		EventTypes: w.EventTypes,
+		CreatedAt:  pagination.Timestamp(w.CreatedAt),
+	}
+}
+
+func ValidateCreate(ctx context.Context, req *ssov2.CreateWebhookRequest) error {
+	var reqStruct CreateWebhookReq
+	reqStruct.AppName = req.GetAppName()
+	reqStruct.URL = req.GetUrl()
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateApp(ctx context.Context, appName string) error {
+	var reqStruct AppReq
+	reqStruct.AppName = appName
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
+
+func ValidateDelete(ctx context.Context, req *ssov2.DeleteWebhookRequest) error {
+	var reqStruct DeleteWebhookReq
+	reqStruct.AppName = req.GetAppName()
+	reqStruct.WebhookID = req.GetWebhookId()
+
+	if err := validator.New().Struct(reqStruct); err != nil {
+		return grpcerr.Validation(ctx, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Package claimmap evaluates per-app claim mappings: claim name to an expression
+// (https://expr-lang.org) over the user, the app, roles and metadata of the user.
+// Expressions can't call out of the sandbox, so mappings are safe to store per app.
+package claimmap
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+const (
+	MaxClaims     = 50
+	MaxExprLength = 1024
+)
+
+// Error tells which claim of the mapping is invalid or failed to evaluate
+type Error struct {
+	Claim  string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	if e.Claim == "" {
+		return e.Reason
+	}
+	return "claim " + e.Claim + ": " + e.Reason
+}
+
+// Reserved claims are used by sso itself and can't be mapped. sub tells service
+// accounts from users and aud binds the token to its app, so both stay as sso sets them
+var Reserved = map[string]bool{"uid": true, "app_id": true, "iss": true, "sub": true, "aud": true, "exp": true, "iat": true, "nbf": true, "jti": true, "sid": true, "act": true}
+
+var claimName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.:/-]{0,127}$`)
+
+// Env is the data available to expressions
+type Env struct {
+	User     map[string]any
+	App      map[string]any
+	Roles    []string
+	Metadata map[string]any
+	Claims   map[string]any
+}
+
+func (e Env) vars() map[string]any {
+	return map[string]any{
+		"user":     e.User,
+		"app":      e.App,
+		"roles":    e.Roles,
+		"metadata": e.Metadata,
+		"claims":   e.Claims,
+	}
+}
+
+// sample has every variable, so compilation rejects unknown ones
+var sample = Env{
+	User:     map[string]any{},
+	App:      map[string]any{},
+	Roles:    []string{},
+	Metadata: map[string]any{},
+	Claims:   map[string]any{},
+}
+
+// Validate checks claim names and compiles every expression
+func Validate(mapping map[string]string) error {
+	_, err := compile(mapping)
+	return err
+}
+
+// Apply evaluates the mapping. Expressions returning nil leave the claim out
+func Apply(mapping map[string]string, env Env) (map[string]any, error) {
+	programs, err := compile(mapping)
+	if err != nil {
+		return nil, err
+	}
+	vars := env.vars()
+	claims := make(map[string]any, len(programs))
+	for name, program := range programs {
+		v, err := vm.Run(program, vars)
+		if err != nil {
+			return nil, &Error{Claim: name, Reason: err.Error()}
+		}
+		if v != nil {
+			claims[name] = v
+		}
+	}
+	return claims, nil
+}
+
+func compile(mapping map[string]string) (map[string]*vm.Program, error) {
+	if len(mapping) > MaxClaims {
+		return nil, &Error{Reason: fmt.Sprintf("more than %d claims", MaxClaims)}
+	}
+	env := sample.vars()
+	programs := make(map[string]*vm.Program, len(mapping))
+	for name, code := range mapping {
+		if !claimName.MatchString(name) {
+			return nil, &Error{Claim: name, Reason: "bad claim name"}
+		}
+		if Reserved[name] {
+			return nil, &Error{Claim: name, Reason: "claim is reserved"}
+		}
+		if len(code) > MaxExprLength {
+			return nil, &Error{Claim: name, Reason: fmt.Sprintf("expression is longer than %d", MaxExprLength)}
+		}
+		program, err := expr.Compile(code, expr.Env(env))
+		if err != nil {
+			return nil, &Error{Claim: name, Reason: err.Error()}
+		}
+		programs[name] = program
+	}
+	return programs, nil
+}
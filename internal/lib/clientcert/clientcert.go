@@ -0,0 +1,37 @@
+package clientcert
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// FromContext returns the identity of the verified client certificate of the gRPC
+// request, false when the client sent none or the connection isn't TLS
+func FromContext(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return Identity(info.State.VerifiedChains[0][0]), true
+}
+
+// Identity names the owner of the certificate: the first URI SAN, like a SPIFFE ID,
+// then the first DNS or email SAN, then the common name
+func Identity(cert *x509.Certificate) string {
+	switch {
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		return cert.EmailAddresses[0]
+	}
+	return cert.Subject.CommonName
+}
@@ -0,0 +1,34 @@
+package clientip
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ForwardedHeader carries the client address of calls made by the HTTP gateway
+const ForwardedHeader = "x-forwarded-for"
+
+// inprocNetwork is the network of the in-process listener of the gateway,
+// the forwarded header is trusted only on it
+const inprocNetwork = "bufconn"
+
+// FromContext returns the address of the client of the gRPC request
+func FromContext(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if p.Addr.Network() == inprocNetwork {
+		if forwarded := metadata.ValueFromIncomingContext(ctx, ForwardedHeader); len(forwarded) == 1 {
+			return forwarded[0]
+		}
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
@@ -0,0 +1,46 @@
+package hashchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"github.com/neepooha/sso/internal/domain/models"
+	"strconv"
+	"time"
+)
+
+// Sum returns the hash of the audit entry chained to the previous hash.
+// Every field is length-prefixed so that field boundaries can't be shifted
+func Sum(prev []byte, e models.AuditEntry) []byte {
+	h := sha256.New()
+	write := func(b []byte) {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+		h.Write(n[:])
+		h.Write(b)
+	}
+	write(prev)
+	write([]byte(e.CreatedAt.UTC().Format(time.RFC3339Nano)))
+	write([]byte(e.Action))
+	write([]byte(strconv.FormatUint(e.ActorID, 10)))
+	write([]byte(e.Target))
+	write([]byte(e.AppName))
+	write([]byte(e.IP))
+	write([]byte(e.Outcome))
+	write([]byte(e.Reason))
+	// entries written before service accounts had no kind, humans keep hashing the same way
+	if e.ActorKind != "" && e.ActorKind != models.UserHuman {
+		write([]byte(e.ActorKind))
+	}
+	// entries without an operator keep hashing the same way as before impersonation
+	if e.OperatorID != 0 {
+		write([]byte("operator:" + strconv.FormatUint(e.OperatorID, 10)))
+	}
+	return h.Sum(nil)
+}
+
+// Verify reports whether the entry is chained to the previous hash and its hash
+// still covers its fields
+func Verify(prev []byte, e models.AuditEntry) bool {
+	return bytes.Equal(e.PrevHash, prev) && bytes.Equal(e.Hash, Sum(prev, e))
+}
@@ -0,0 +1,117 @@
+package hashchain_test
+
+import (
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/hashchain"
+	"testing"
+	"time"
+)
+
+// chain links the entries the way storages append them
+func chain(entries ...models.AuditEntry) []models.AuditEntry {
+	prev := []byte{}
+	for i := range entries {
+		entries[i].ID = uint64(i + 1)
+		entries[i].PrevHash = prev
+		entries[i].Hash = hashchain.Sum(prev, entries[i])
+		prev = entries[i].Hash
+	}
+	return entries
+}
+
+// verify returns the index of the first entry that fails verification, -1 if the chain is intact
+func verify(entries []models.AuditEntry) int {
+	prev := []byte{}
+	for i, e := range entries {
+		if !hashchain.Verify(prev, e) {
+			return i
+		}
+		prev = e.Hash
+	}
+	return -1
+}
+
+func entries() []models.AuditEntry {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC)
+	return chain(
+		models.AuditEntry{CreatedAt: at, Action: "login", ActorID: 1, AppName: "app", IP: "10.0.0.1", Outcome: models.AuditSuccess},
+		models.AuditEntry{CreatedAt: at.Add(time.Second), Action: "set_admin", ActorID: 1, Target: "hash", AppName: "app", Outcome: models.AuditFailure, Reason: "not creator"},
+		models.AuditEntry{CreatedAt: at.Add(2 * time.Second), Action: "login", ActorID: 2, ActorKind: models.UserService, AppName: "app", IP: "10.0.0.2", Outcome: models.AuditSuccess},
+		models.AuditEntry{CreatedAt: at.Add(3 * time.Second), Action: "refresh", ActorID: 3, OperatorID: 1, AppName: "app", Outcome: models.AuditSuccess},
+	)
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(entries []models.AuditEntry) []models.AuditEntry
+		// bad is the index of the first entry that must fail, -1 for an intact chain
+		bad int
+	}{
+		{name: "intact", tamper: func(e []models.AuditEntry) []models.AuditEntry { return e }, bad: -1},
+		{name: "action changed", tamper: func(e []models.AuditEntry) []models.AuditEntry { e[1].Action = "login"; return e }, bad: 1},
+		{name: "actor changed", tamper: func(e []models.AuditEntry) []models.AuditEntry { e[0].ActorID = 9; return e }, bad: 0},
+		{name: "actor kind changed", tamper: func(e []models.AuditEntry) []models.AuditEntry { e[2].ActorKind = models.UserHuman; return e }, bad: 2},
+		{name: "operator removed", tamper: func(e []models.AuditEntry) []models.AuditEntry { e[3].OperatorID = 0; return e }, bad: 3},
+		{name: "target changed", tamper: func(e []models.AuditEntry) []models.AuditEntry { e[1].Target = "other"; return e }, bad: 1},
+		{name: "app changed", tamper: func(e []models.AuditEntry) []models.AuditEntry { e[2].AppName = "other"; return e }, bad: 2},
+		{name: "ip changed", tamper: func(e []models.AuditEntry) []models.AuditEntry { e[0].IP = "10.0.0.2"; return e }, bad: 0},
+		{name: "outcome changed", tamper: func(e []models.AuditEntry) []models.AuditEntry { e[1].Outcome = models.AuditSuccess; return e }, bad: 1},
+		{name: "reason changed", tamper: func(e []models.AuditEntry) []models.AuditEntry { e[1].Reason = ""; return e }, bad: 1},
+		{name: "time changed", tamper: func(e []models.AuditEntry) []models.AuditEntry {
+			e[2].CreatedAt = e[2].CreatedAt.Add(time.Microsecond)
+			return e
+		}, bad: 2},
+		{name: "hash recomputed", tamper: func(e []models.AuditEntry) []models.AuditEntry {
+			e[1].Action = "login"
+			e[1].Hash = hashchain.Sum(e[1].PrevHash, e[1])
+			return e
+		}, bad: 2},
+		{name: "entry removed", tamper: func(e []models.AuditEntry) []models.AuditEntry { return append(e[:1], e[2:]...) }, bad: 1},
+		{name: "entries swapped", tamper: func(e []models.AuditEntry) []models.AuditEntry { e[1], e[2] = e[2], e[1]; return e }, bad: 1},
+		{name: "first entry removed", tamper: func(e []models.AuditEntry) []models.AuditEntry { return e[1:] }, bad: 0},
+		// the chain can't tell a cut tail, VerifyAudit reports how many entries it checked
+		{name: "last entry removed", tamper: func(e []models.AuditEntry) []models.AuditEntry { return e[:len(e)-1] }, bad: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verify(tt.tamper(entries())); got != tt.bad {
+				t.Errorf("first bad entry = %d, want %d", got, tt.bad)
+			}
+		})
+	}
+}
+
+func TestSumCompatible(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	base := models.AuditEntry{CreatedAt: at, Action: "login", ActorID: 1, AppName: "app", Outcome: models.AuditSuccess}
+	prev := []byte("prev")
+
+	tests := []struct {
+		name  string
+		entry func(e models.AuditEntry) models.AuditEntry
+		same  bool
+	}{
+		// entries written before actor kinds and impersonation must keep their hash
+		{name: "empty kind is human", entry: func(e models.AuditEntry) models.AuditEntry { e.ActorKind = models.UserHuman; return e }, same: true},
+		{name: "zero operator", entry: func(e models.AuditEntry) models.AuditEntry { e.OperatorID = 0; return e }, same: true},
+		{name: "other time zone", entry: func(e models.AuditEntry) models.AuditEntry {
+			e.CreatedAt = at.In(time.FixedZone("UTC+3", 3*3600))
+			return e
+		}, same: true},
+		{name: "id is not hashed", entry: func(e models.AuditEntry) models.AuditEntry { e.ID = 42; return e }, same: true},
+		{name: "service kind", entry: func(e models.AuditEntry) models.AuditEntry { e.ActorKind = models.UserService; return e }},
+		{name: "operator", entry: func(e models.AuditEntry) models.AuditEntry { e.OperatorID = 1; return e }},
+		// fields are length-prefixed, moving bytes between them changes the hash
+		{name: "shifted boundary", entry: func(e models.AuditEntry) models.AuditEntry { e.Action = "logi"; e.Target = "n"; return e }},
+	}
+	want := string(hashchain.Sum(prev, base))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(hashchain.Sum(prev, tt.entry(base)))
+			if (got == want) != tt.same {
+				t.Errorf("same hash = %v, want %v", got == want, tt.same)
+			}
+		})
+	}
+}
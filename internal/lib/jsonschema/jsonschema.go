@@ -0,0 +1,47 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const schemaURL = "metadata.json"
+
+// Compile checks that schema is a valid JSON Schema document
+func Compile(schema []byte) (*jsonschema.Schema, error) {
+	const op = "lib.jsonschema.Compile"
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaURL, bytes.NewReader(schema)); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	compiled, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return compiled, nil
+}
+
+// Validate checks doc against schema. Empty schema accepts every document
+func Validate(schema []byte, doc []byte) error {
+	const op = "lib.jsonschema.Validate"
+	if len(schema) == 0 {
+		return nil
+	}
+
+	compiled, err := Compile(schema)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	var v any
+	if err := json.Unmarshal(doc, &v); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := compiled.Validate(v); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package jsonstruct
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ToJSON encodes a protobuf Struct as a JSON object, nil Struct gives nil
+func ToJSON(s *structpb.Struct) ([]byte, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return protojson.Marshal(s)
+}
+
+// FromJSON decodes a JSON object into a protobuf Struct, empty input gives nil
+func FromJSON(data []byte) (*structpb.Struct, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var s structpb.Struct
+	if err := protojson.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
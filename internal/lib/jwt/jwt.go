@@ -1,19 +1,101 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/neepooha/sso/internal/domain/models"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 )
 
-func NewToken(user models.User, app models.App, duration time.Duration) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpired      = errors.New("token is expired")
+	ErrNotYetValid  = errors.New("token is not valid yet")
+	ErrIssuer       = errors.New("token has wrong issuer")
+	ErrAudience     = errors.New("token has wrong audience")
+)
+
+// Options are shared by all issuers and verifiers of the service
+type Options struct {
+	Issuer string
+	// ClockSkew is how far clocks of sso and verifiers may differ
+	ClockSkew time.Duration
+}
+
+var (
+	mu   sync.RWMutex
+	opts = Options{Issuer: "sso", ClockSkew: 30 * time.Second}
+)
+
+// Configure sets the options, it is called once on start
+func Configure(o Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	opts = o
+}
+
+func options() Options {
+	mu.RLock()
+	defer mu.RUnlock()
+	return opts
+}
+
+// Claims returns the default claims of the token of the user for the app
+func Claims(user models.User, app models.App, duration time.Duration) map[string]any {
+	now := time.Now()
+	claims := map[string]any{}
+	claims["iss"] = options().Issuer
+	claims["sub"] = Subject(user)
+	claims["aud"] = app.Name
+	claims["iat"] = now.Unix()
+	claims["nbf"] = now.Unix()
+	claims["exp"] = now.Add(duration).Unix()
+	claims["jti"] = newID()
 	claims["uid"] = user.ID
 	claims["email"] = user.Email
-	claims["exp"] = time.Now().Add(duration).Unix()
 	claims["app_id"] = app.ID
+	if user.DisplayName != "" {
+		claims["name"] = user.DisplayName
+	}
+	if user.Locale != "" {
+		claims["locale"] = user.Locale
+	}
+	if user.AvatarURL != "" {
+		claims["picture"] = user.AvatarURL
+	}
+	return claims
+}
+
+// ServicePrefix starts the sub claim of service accounts, sub of humans is the bare user id
+const ServicePrefix = "svc:"
+
+// Subject returns the sub claim of the user
+func Subject(user models.User) string {
+	if user.Kind == models.UserService {
+		return ServicePrefix + strconv.FormatUint(user.ID, 10)
+	}
+	return strconv.FormatUint(user.ID, 10)
+}
+
+// NewToken signs the claims with the app secret. Extra claims are added
+// to them, but never replace existing or registered claims
+func NewToken(app models.App, claims map[string]any, extra map[string]any) (string, error) {
+	signed := jwt.MapClaims{}
+	for k, v := range claims {
+		signed[k] = v
+	}
+	for k, v := range extra {
+		if _, ok := signed[k]; !ok && !registered[k] {
+			signed[k] = v
+		}
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, signed)
 
 	tokenString, err := token.SignedString([]byte(app.Secret))
 	if err != nil {
@@ -21,3 +103,83 @@ func NewToken(user models.User, app models.App, duration time.Duration) (string,
 	}
 	return tokenString, nil
 }
+
+// Verify checks the signature of the token with the app secret and its registered
+// claims: iss must be the configured issuer, aud must contain the app name, exp, nbf
+// and iat are checked with the allowed clock skew, sub and jti must be present
+func Verify(tokenStr string, app models.App) (map[string]any, error) {
+	parser := jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}, SkipClaimsValidation: true}
+	token, err := parser.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) { return []byte(app.Secret), nil })
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	claims := token.Claims.(jwt.MapClaims)
+
+	o := options()
+	now := time.Now()
+	if iss, _ := claims["iss"].(string); iss != o.Issuer {
+		return nil, ErrIssuer
+	}
+	if !hasAudience(claims["aud"], app.Name) {
+		return nil, ErrAudience
+	}
+	exp, ok := unix(claims["exp"])
+	if !ok {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if now.After(exp.Add(o.ClockSkew)) {
+		return nil, ErrExpired
+	}
+	for _, name := range []string{"nbf", "iat"} {
+		t, ok := unix(claims[name])
+		if !ok {
+			return nil, fmt.Errorf("%w: missing %s", ErrInvalidToken, name)
+		}
+		if now.Add(o.ClockSkew).Before(t) {
+			return nil, ErrNotYetValid
+		}
+	}
+	for _, name := range []string{"sub", "jti"} {
+		if v, _ := claims[name].(string); v == "" {
+			return nil, fmt.Errorf("%w: missing %s", ErrInvalidToken, name)
+		}
+	}
+	return claims, nil
+}
+
+// registered are claims that only sso may set, even if Claims left them out
+var registered = map[string]bool{
+	"uid": true, "email": true, "exp": true, "app_id": true,
+	"name": true, "locale": true, "picture": true,
+	"iss": true, "sub": true, "aud": true, "nbf": true, "iat": true, "jti": true,
+	"sid": true, "act": true,
+}
+
+func hasAudience(aud any, appName string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == appName
+	case []any:
+		for _, a := range aud {
+			if a == appName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unix(v any) (time.Time, bool) {
+	f, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	// crypto/rand never fails on supported platforms
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
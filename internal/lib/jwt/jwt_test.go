@@ -0,0 +1,115 @@
+package jwt_test
+
+import (
+	"errors"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/jwt"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const skew = 30 * time.Second
+	jwt.Configure(jwt.Options{Issuer: "sso", ClockSkew: skew})
+
+	app := models.App{ID: 1, Name: "app", Secret: "secret"}
+	user := models.User{ID: 7, Email: "user@example.com"}
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		// edit changes the default claims of the token
+		edit    func(claims map[string]any)
+		secret  string
+		wantErr error
+	}{
+		{name: "valid", edit: func(map[string]any) {}},
+		{name: "audience in list", edit: func(c map[string]any) { c["aud"] = []any{"other", "app"} }},
+		{name: "wrong secret", edit: func(map[string]any) {}, secret: "other", wantErr: jwt.ErrInvalidToken},
+		{name: "wrong issuer", edit: func(c map[string]any) { c["iss"] = "evil" }, wantErr: jwt.ErrIssuer},
+		{name: "missing issuer", edit: func(c map[string]any) { delete(c, "iss") }, wantErr: jwt.ErrIssuer},
+		{name: "wrong audience", edit: func(c map[string]any) { c["aud"] = "other" }, wantErr: jwt.ErrAudience},
+		{name: "audience list without app", edit: func(c map[string]any) { c["aud"] = []any{"other"} }, wantErr: jwt.ErrAudience},
+		{name: "missing audience", edit: func(c map[string]any) { delete(c, "aud") }, wantErr: jwt.ErrAudience},
+		{name: "expired within skew", edit: func(c map[string]any) { c["exp"] = now.Add(-skew / 2).Unix() }},
+		{name: "expired past skew", edit: func(c map[string]any) { c["exp"] = now.Add(-2 * skew).Unix() }, wantErr: jwt.ErrExpired},
+		{name: "missing exp", edit: func(c map[string]any) { delete(c, "exp") }, wantErr: jwt.ErrInvalidToken},
+		{name: "nbf within skew", edit: func(c map[string]any) { c["nbf"] = now.Add(skew / 2).Unix() }},
+		{name: "nbf past skew", edit: func(c map[string]any) { c["nbf"] = now.Add(2 * skew).Unix() }, wantErr: jwt.ErrNotYetValid},
+		{name: "iat past skew", edit: func(c map[string]any) { c["iat"] = now.Add(2 * skew).Unix() }, wantErr: jwt.ErrNotYetValid},
+		{name: "missing nbf", edit: func(c map[string]any) { delete(c, "nbf") }, wantErr: jwt.ErrInvalidToken},
+		{name: "missing iat", edit: func(c map[string]any) { delete(c, "iat") }, wantErr: jwt.ErrInvalidToken},
+		{name: "missing sub", edit: func(c map[string]any) { delete(c, "sub") }, wantErr: jwt.ErrInvalidToken},
+		{name: "missing jti", edit: func(c map[string]any) { delete(c, "jti") }, wantErr: jwt.ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := jwt.Claims(user, app, time.Hour)
+			tt.edit(claims)
+			signer := app
+			if tt.secret != "" {
+				signer.Secret = tt.secret
+			}
+			token, err := jwt.NewToken(signer, claims, nil)
+			if err != nil {
+				t.Fatalf("NewToken: %v", err)
+			}
+
+			got, err := jwt.Verify(token, app)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got["sub"] != jwt.Subject(user) {
+				t.Errorf("sub = %v, want %v", got["sub"], jwt.Subject(user))
+			}
+		})
+	}
+}
+
+func TestVerifyIssuer(t *testing.T) {
+	app := models.App{ID: 1, Name: "app", Secret: "secret"}
+
+	jwt.Configure(jwt.Options{Issuer: "old", ClockSkew: time.Second})
+	token, err := jwt.NewToken(app, jwt.Claims(models.User{ID: 1}, app, time.Hour), nil)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+
+	jwt.Configure(jwt.Options{Issuer: "new", ClockSkew: time.Second})
+	t.Cleanup(func() { jwt.Configure(jwt.Options{Issuer: "sso", ClockSkew: 30 * time.Second}) })
+	if _, err := jwt.Verify(token, app); !errors.Is(err, jwt.ErrIssuer) {
+		t.Fatalf("Verify error = %v, want %v", err, jwt.ErrIssuer)
+	}
+}
+
+func TestNewTokenKeepsRegisteredClaims(t *testing.T) {
+	jwt.Configure(jwt.Options{Issuer: "sso", ClockSkew: 30 * time.Second})
+	app := models.App{ID: 1, Name: "app", Secret: "secret"}
+	user := models.User{ID: 7, Email: "user@example.com"}
+
+	extra := map[string]any{"sub": "0", "aud": "other", "email": "evil@example.com", "sid": "1", "role": "admin"}
+	token, err := jwt.NewToken(app, jwt.Claims(user, app, time.Hour), extra)
+	if err != nil {
+		t.Fatalf("NewToken: %v", err)
+	}
+	claims, err := jwt.Verify(token, app)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	tests := []struct {
+		claim string
+		want  any
+	}{
+		{"sub", "7"},
+		{"aud", "app"},
+		{"email", "user@example.com"},
+		{"sid", nil},
+		{"role", "admin"},
+	}
+	for _, tt := range tests {
+		if got := claims[tt.claim]; got != tt.want {
+			t.Errorf("claim %s = %v, want %v", tt.claim, got, tt.want)
+		}
+	}
+}
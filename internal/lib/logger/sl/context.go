@@ -0,0 +1,21 @@
+package sl
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying the request-scoped logger
+func NewContext(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, log)
+}
+
+// FromContext returns the logger of the request, or fallback outside of requests, e.g. in jobs
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if log, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return log
+	}
+	return fallback
+}
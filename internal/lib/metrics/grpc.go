@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// The method label is the full method of a registered service, calls to unknown
+// methods never reach interceptors, so the label is bounded by the proto files
+var (
+	RPCs = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Handled RPCs by method and status code.",
+	}, []string{"method", "code"})
+
+	RPCDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Latency of RPCs by method, for streams the time the stream was open.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// UnaryServerInterceptor counts and times unary calls
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeRPC(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor counts and times streams
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeRPC(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observeRPC(method string, start time.Time, err error) {
+	Since(RPCDuration.WithLabelValues(method), start)
+	RPCs.WithLabelValues(method, status.Code(err).String()).Inc()
+}
+
+// Deadline labels of Timeouts, whose deadline ran out
+const (
+	DeadlineServer = "server"
+	DeadlineClient = "client"
+)
+
+// Timeouts counts calls that ran out of time, by method and by whose deadline it was,
+// the server's grpc.timeout or the caller's
+var Timeouts = factory.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "grpc_timeouts_total",
+	Help:      "RPCs that exceeded their deadline by method and owner of the deadline.",
+}, []string{"method", "deadline"})
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "sso"
+
+// OtherApp is the app label of apps beyond the limit of SetMaxApps
+const OtherApp = "other"
+
+// NoApp is the app label of calls without an app
+const NoApp = "none"
+
+// Registry holds every metric of sso, it is served by the metrics HTTP server
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	Logins = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by app, result and reason of failure.",
+	}, []string{"app", "result", "reason"})
+
+	Registrations = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Registration attempts by app, result and reason of failure.",
+	}, []string{"app", "result", "reason"})
+
+	Purges = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "user_purges_total",
+		Help:      "Users purged by the retention job by result and reason of failure.",
+	}, []string{"result", "reason"})
+
+	Bcrypt = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "bcrypt_duration_seconds",
+		Help:      "Time spent hashing and comparing passwords.",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"op"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Since observes the time passed from start
+func Since(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
+
+// Result returns the result and reason labels of a call, reason is empty on success
+func Result(reason string) (string, string) {
+	if reason == "" {
+		return "success", ""
+	}
+	return "failure", reason
+}
+
+// apps bounds the app label. App names come from requests and anyone can create apps,
+// so only the first maxApps names seen get their own series and the rest share OtherApp
+var apps = struct {
+	sync.Mutex
+	max  int
+	seen map[string]struct{}
+}{max: 100, seen: map[string]struct{}{}}
+
+// SetMaxApps sets how many distinct apps get their own series
+func SetMaxApps(n int) {
+	apps.Lock()
+	defer apps.Unlock()
+	apps.max = n
+}
+
+// App returns the app label of appName
+func App(appName string) string {
+	if appName == "" {
+		return NoApp
+	}
+	apps.Lock()
+	defer apps.Unlock()
+	if _, ok := apps.seen[appName]; ok {
+		return appName
+	}
+	if len(apps.seen) >= apps.max {
+		return OtherApp
+	}
+	apps.seen[appName] = struct{}{}
+	return appName
+}
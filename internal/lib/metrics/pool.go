@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type PoolStater interface {
+	Stat() *pgxpool.Stat
+}
+
+// PoolCollector exposes the statistics of the pgx pool on every scrape
+type PoolCollector struct {
+	pool PoolStater
+
+	acquired     *prometheus.Desc
+	idle         *prometheus.Desc
+	total        *prometheus.Desc
+	max          *prometheus.Desc
+	acquires     *prometheus.Desc
+	emptyAcquire *prometheus.Desc
+	waitSeconds  *prometheus.Desc
+}
+
+func NewPoolCollector(pool PoolStater) *PoolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &PoolCollector{
+		pool:         pool,
+		acquired:     desc("acquired_conns", "Connections currently in use."),
+		idle:         desc("idle_conns", "Idle connections."),
+		total:        desc("total_conns", "Open connections."),
+		max:          desc("max_conns", "Maximum size of the pool."),
+		acquires:     desc("acquires_total", "Connections acquired from the pool."),
+		emptyAcquire: desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		waitSeconds:  desc("acquire_wait_seconds_total", "Time spent waiting for a connection."),
+	}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.total
+	ch <- c.max
+	ch <- c.acquires
+	ch <- c.emptyAcquire
+	ch <- c.waitSeconds
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquire, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.waitSeconds, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
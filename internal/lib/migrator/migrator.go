@@ -3,16 +3,29 @@ package migrator
 import (
 	"fmt"
 	"github.com/neepooha/sso/internal/config"
+	"github.com/neepooha/sso/internal/storage"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/jackc/pgx/v5/pgconn"
 )
 
 func Migrate(cfg *config.Config) error {
-	m, err := migrate.New("file://"+cfg.Storage.Migrations_path,
-		fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", cfg.Storage.User, cfg.Storage.Password, cfg.Storage.Host, cfg.Storage.Port, cfg.Storage.Dbname))
+	var database string
+	switch cfg.Storage.Driver {
+	case storage.DriverPostgres:
+		database = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", cfg.Storage.User, cfg.Storage.Password, cfg.Storage.Host, cfg.Storage.Port, cfg.Storage.Dbname)
+	case storage.DriverSQLite:
+		database = "sqlite://" + cfg.Storage.Path
+	default:
+		return fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+	m, err := migrate.New("file://"+cfg.Storage.Migrations_path, database)
 	if err != nil {
 		return err
 	}
@@ -21,3 +34,25 @@ func Migrate(cfg *config.Config) error {
 	}
 	return nil
 }
+
+// LatestVersion returns the highest version of the up migrations in the directory
+func LatestVersion(path string) (uint, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return 0, err
+	}
+	var latest uint
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s: %w", name, err)
+		}
+		latest = max(latest, uint(version))
+	}
+	return latest, nil
+}
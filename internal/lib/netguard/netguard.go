@@ -0,0 +1,116 @@
+// Package netguard keeps calls to URLs set by apps, like hooks and webhooks,
+// away from the internal network of the server
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddr = errors.New("address is not public")
+
+// reserved are non-public networks netip has no predicate for: "this network",
+// which Linux routes to the host itself, and the shared address space of carrier-grade
+// NAT, which cloud providers use for internal services
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// Guard refuses loopback, link-local, private, unspecified, multicast and reserved
+// addresses unless they are in one of the allowed networks
+type Guard struct {
+	allowed []netip.Prefix
+}
+
+// New returns a guard allowing the given networks, each a CIDR or a single address
+func New(allowed []string) (*Guard, error) {
+	g := &Guard{}
+	for _, s := range allowed {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(s)
+			if addrErr != nil {
+				return nil, fmt.Errorf("netguard: invalid network %q: %w", s, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		g.allowed = append(g.allowed, prefix.Masked())
+	}
+	return g, nil
+}
+
+// Check returns ErrForbiddenAddr if addr isn't public and isn't allowed
+func (g *Guard) Check(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, prefix := range g.allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddr, addr)
+	}
+	for _, prefix := range reserved {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s", ErrForbiddenAddr, addr)
+		}
+	}
+	return nil
+}
+
+// CheckHost resolves the host and checks all of its addresses. It catches bad
+// targets early, the dialer still checks the address actually dialed
+func (g *Guard) CheckHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return g.Check(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err := g.Check(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Dialer returns a dialer that refuses forbidden addresses. The check runs
+// on the resolved address, so names resolving to internal ones are refused too
+func (g *Guard) Dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return g.Check(addrPort.Addr())
+		},
+	}
+}
+
+// HTTPClient returns a client whose connections, redirects included, pass the
+// guard. Proxies from the environment aren't used, they would hide the target
+func (g *Guard) HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         g.Dialer().DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,50 @@
+package netguard_test
+
+import (
+	"errors"
+	"github.com/neepooha/sso/internal/lib/netguard"
+	"net/netip"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		addr    string
+		allowed []string
+		wantErr error
+	}{
+		{name: "public v4", addr: "93.184.216.34"},
+		{name: "public v6", addr: "2606:2800:220:1:248:1893:25c8:1946"},
+		{name: "loopback", addr: "127.0.0.1", wantErr: netguard.ErrForbiddenAddr},
+		{name: "loopback v6", addr: "::1", wantErr: netguard.ErrForbiddenAddr},
+		{name: "private", addr: "10.1.2.3", wantErr: netguard.ErrForbiddenAddr},
+		{name: "private v6", addr: "fd00::1", wantErr: netguard.ErrForbiddenAddr},
+		{name: "link-local", addr: "169.254.169.254", wantErr: netguard.ErrForbiddenAddr},
+		{name: "unspecified", addr: "0.0.0.0", wantErr: netguard.ErrForbiddenAddr},
+		{name: "this network", addr: "0.1.2.3", wantErr: netguard.ErrForbiddenAddr},
+		{name: "carrier-grade nat", addr: "100.64.0.1", wantErr: netguard.ErrForbiddenAddr},
+		{name: "carrier-grade nat end", addr: "100.127.255.254", wantErr: netguard.ErrForbiddenAddr},
+		{name: "below carrier-grade nat", addr: "100.63.255.255"},
+		{name: "above carrier-grade nat", addr: "100.128.0.1"},
+		{name: "multicast", addr: "224.0.0.1", wantErr: netguard.ErrForbiddenAddr},
+		{name: "v4-mapped private", addr: "::ffff:10.0.0.1", wantErr: netguard.ErrForbiddenAddr},
+		{name: "v4-mapped carrier-grade nat", addr: "::ffff:100.64.0.1", wantErr: netguard.ErrForbiddenAddr},
+		{name: "allowed network", addr: "10.1.2.3", allowed: []string{"10.0.0.0/8"}},
+		{name: "allowed carrier-grade nat", addr: "100.64.0.1", allowed: []string{"100.64.0.0/10"}},
+		{name: "allowed address", addr: "127.0.0.1", allowed: []string{"127.0.0.1"}},
+		{name: "outside allowed network", addr: "10.1.2.3", allowed: []string{"192.168.0.0/16"}, wantErr: netguard.ErrForbiddenAddr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := netguard.New(tt.allowed)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			err = g.Check(netip.MustParseAddr(tt.addr))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Check(%s) = %v, want %v", tt.addr, err, tt.wantErr)
+			}
+		})
+	}
+}
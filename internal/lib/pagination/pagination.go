@@ -0,0 +1,94 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"github.com/neepooha/sso/internal/domain/models"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+var (
+	ErrInvalidPageToken = errors.New("invalid page token")
+	ErrInvalidRange     = errors.New("created_after must be before created_before")
+)
+
+// PageSize clamps the requested page size to [1, MaxPageSize]
+func PageSize(size int32) int {
+	if size <= 0 {
+		return DefaultPageSize
+	}
+	if size > MaxPageSize {
+		return MaxPageSize
+	}
+	return int(size)
+}
+
+// EncodeToken returns an opaque cursor pointing after the given id
+func EncodeToken(id uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(id, 10)))
+}
+
+// DecodeToken returns the id encoded in the cursor, empty token means first page
+func DecodeToken(token string) (uint64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidPageToken
+	}
+	id, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidPageToken
+	}
+	return id, nil
+}
+
+// NextToken trims the extra row fetched by the storage (limit+1) and returns
+// the cursor for the next page or an empty string on the last page
+func NextToken[T any](items []T, limit int, id func(T) uint64) ([]T, string) {
+	if len(items) <= limit {
+		return items, ""
+	}
+	items = items[:limit]
+	return items, EncodeToken(id(items[len(items)-1]))
+}
+
+// Filter builds a storage filter from the common fields of list requests
+func Filter(prefix string, createdAfter, createdBefore *timestamppb.Timestamp, size int32, token string) (models.ListFilter, error) {
+	afterID, err := DecodeToken(token)
+	if err != nil {
+		return models.ListFilter{}, err
+	}
+	filter := models.ListFilter{
+		Prefix:  prefix,
+		AfterID: afterID,
+		Limit:   PageSize(size),
+	}
+	if createdAfter != nil {
+		filter.CreatedAfter = createdAfter.AsTime()
+	}
+	if createdBefore != nil {
+		filter.CreatedBefore = createdBefore.AsTime()
+	}
+	if !filter.CreatedAfter.IsZero() && !filter.CreatedBefore.IsZero() && !filter.CreatedAfter.Before(filter.CreatedBefore) {
+		return models.ListFilter{}, ErrInvalidRange
+	}
+	return filter, nil
+}
+
+// Timestamp converts a storage time into its protobuf representation
+func Timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
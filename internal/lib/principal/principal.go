@@ -0,0 +1,39 @@
+package principal
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/domain/models"
+)
+
+// Principal is the verified caller of a request, put into the context by the authz interceptor
+type Principal struct {
+	UserID uint64
+	// Kind is UserService for service accounts
+	Kind models.UserKind
+	// ActorID is the operator impersonating UserID, 0 for the user's own tokens
+	ActorID uint64
+	// App is the app the token was issued for and verified against
+	App    models.App
+	Claims map[string]any
+	// ClientCert is the identity of the verified client certificate, empty without mTLS
+	ClientCert string
+}
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the principal of the request, false for public methods
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+// UserID returns the user id of the caller, 0 for public methods
+func UserID(ctx context.Context) uint64 {
+	p, _ := FromContext(ctx)
+	return p.UserID
+}
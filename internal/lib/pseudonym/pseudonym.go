@@ -0,0 +1,18 @@
+// Package pseudonym replaces personal data written to append-only stores with keyed
+// hashes. It is pseudonymization, not erasure: whoever holds the key and the email
+// can still find the entries of a user, also after the user is purged
+package pseudonym
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Email returns the keyed hash written instead of the email. Without the key
+// the hash can't be matched against a list of known emails, with it anyone can
+func Email(key []byte, email string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(email))
+	return "email:" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
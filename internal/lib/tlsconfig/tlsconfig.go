@@ -0,0 +1,167 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Client certificate modes
+const (
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+)
+
+var ErrNoClientCA = errors.New("client_ca_file is required to verify client certificates")
+
+// Reloader keeps the server certificate and the client CAs read from files and
+// reloads them when the files change, so certificates can be rotated without a restart
+type Reloader struct {
+	log        *slog.Logger
+	certFile   string
+	keyFile    string
+	caFile     string
+	clientAuth tls.ClientAuthType
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime map[string]time.Time
+}
+
+// New reads the files once, it fails if they can't be loaded. caFile is needed
+// unless clientAuth is ClientAuthNone
+func New(log *slog.Logger, certFile string, keyFile string, caFile string, clientAuth string) (*Reloader, error) {
+	const op = "tlsconfig.New"
+
+	r := &Reloader{log: log, certFile: certFile, keyFile: keyFile, caFile: caFile}
+	switch clientAuth {
+	case ClientAuthNone, "":
+		r.clientAuth = tls.NoClientCert
+	case ClientAuthOptional:
+		r.clientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		r.clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("%s: unknown client_auth %q", op, clientAuth)
+	}
+	if r.clientAuth != tls.NoClientCert && caFile == "" {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoClientCA)
+	}
+	if err := r.load(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return r, nil
+}
+
+// Config returns the server TLS config of gRPC. Every handshake takes the
+// certificate and the client CAs loaded last
+func (r *Reloader) Config() *tls.Config {
+	return r.config("h2")
+}
+
+// HTTPConfig is Config for HTTP servers, which speak HTTP/1.1 as well
+func (r *Reloader) HTTPConfig() *tls.Config {
+	return r.config("h2", "http/1.1")
+}
+
+func (r *Reloader) config(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   r.clientAuth,
+				ClientCAs:    r.pool,
+				NextProtos:   nextProtos,
+			}, nil
+		},
+	}
+}
+
+// Reload loads the files again if any of them changed. A broken new file keeps the
+// old certificate in use. It runs as a background job
+func (r *Reloader) Reload(ctx context.Context) error {
+	const op = "tlsconfig.Reload"
+	log := r.log.With(slog.String("op", op))
+
+	changed, err := r.changed()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !changed {
+		return nil
+	}
+	if err := r.load(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("tls certificates reloaded")
+	return nil
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.caFile != "" {
+		files = append(files, r.caFile)
+	}
+	return files
+}
+
+func (r *Reloader) changed() (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		if !info.ModTime().Equal(r.modTime[file]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *Reloader) load() error {
+	modTime := map[string]time.Time{}
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTime[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.pool = pool
+	r.modTime = modTime
+	return nil
+}
@@ -0,0 +1,129 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/neepooha/sso"
+
+// Exporters of spans
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+type Options struct {
+	// Exporter is one of none, otlp, stdout or file
+	Exporter string
+	// Endpoint is host:port of the OTLP gRPC collector
+	Endpoint string
+	Insecure bool
+	// File is where the file exporter writes spans as JSON lines
+	File string
+	// SampleRatio is the share of new traces that are sampled, traces started
+	// by the caller follow the caller's decision
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C propagators, so spans of
+// incoming calls continue the trace of the caller. The returned func flushes and stops
+// the exporter. With ExporterNone spans are still created for log correlation but not exported
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	const op = "tracing.Setup"
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName("sso")))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	}
+
+	var closer io.Closer
+	switch opts.Exporter {
+	case ExporterNone, "":
+	case ExporterOTLP:
+		clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err := otlptracegrpc.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	case ExporterFile:
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		closer = f
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	default:
+		return nil, fmt.Errorf("%s: unknown exporter %q", op, opts.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOpts...)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}
+
+// Start starts a span named after the op of a service method or a query
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// End records err, if any, on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// LogAttr returns the trace and span ids of ctx to add to log records,
+// an empty attr that slog drops when ctx has no span
+func LogAttr(ctx context.Context) slog.Attr {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return slog.Attr{}
+	}
+	return slog.Group("trace", slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+}
@@ -0,0 +1,239 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/metrics"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const purgeBatch = 100
+
+type Accounts struct {
+	log             *slog.Logger
+	accountProvider AccountProvider
+	retention       time.Duration
+	anonymize       bool
+	// operators may change accounts of other users
+	operators []uint64
+	// emailKey keys the hashes the audit log keeps instead of emails
+	emailKey []byte
+}
+
+type AccountProvider interface {
+	GetUserByID(ctx context.Context, userID uint64) (models.User, error)
+	ListMemberships(ctx context.Context, userID uint64) ([]models.AppMembership, error)
+	// ListUserAudit returns entries with the user as actor or with the target
+	ListUserAudit(ctx context.Context, userID uint64, target string) ([]models.AuditEntry, error)
+	ListUserSessions(ctx context.Context, userID uint64) ([]models.Session, error)
+	SetUserStatus(ctx context.Context, userID uint64, status models.UserStatus) error
+	ListExpiredDeletions(ctx context.Context, before time.Time, afterID uint64, limit int) ([]uint64, error)
+	PurgeUser(ctx context.Context, userID uint64, anonymize bool) error
+	UpdEmail(ctx context.Context, userID uint64, email string) error
+}
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNotOperator        = errors.New("user isn't operator")
+	ErrInvalidStatus      = errors.New("invalid status")
+	ErrUserExists         = errors.New("user already exists")
+)
+
+// New returns a new instanse of the Accounts service.
+// purgeMode "delete" removes purged users, any other value anonymizes them
+func New(log *slog.Logger, accountProvider AccountProvider, retention time.Duration, purgeMode string, operators []uint64, emailKey []byte) *Accounts {
+	return &Accounts{
+		log:             log,
+		accountProvider: accountProvider,
+		retention:       retention,
+		anonymize:       purgeMode != "delete",
+		operators:       operators,
+		emailKey:        emailKey,
+	}
+}
+
+// SetUserStatus suspends or reactivates the user. Caller must be operator.
+// Reactivating a user pending deletion cancels the deletion
+func (a *Accounts) SetUserStatus(ctx context.Context, appName string, userID uint64, status models.UserStatus) (models.UserStatus, error) {
+	const op = "accounts.SetUserStatus"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	if status != models.UserActive && status != models.UserSuspended {
+		log.Warn("invalid status", slog.String("status", string(status)))
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidStatus)
+	}
+	if err := a.requireOperator(ctx); err != nil {
+		log.Warn("user not operator", sl.Err(err))
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("attempting to set user status", slog.String("status", string(status)))
+	err := a.accountProvider.SetUserStatus(ctx, userID, status)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to set user status", sl.Err(err))
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("user status changed")
+	return status, nil
+}
+
+// DeleteUser marks the user for deletion. Users can delete themselves,
+// operators can delete anyone. Returns the time after which the user is purged
+func (a *Accounts) DeleteUser(ctx context.Context, appName string, userID uint64) (time.Time, error) {
+	const op = "accounts.DeleteUser"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	callerID := principal.UserID(ctx)
+	if userID == 0 {
+		userID = callerID
+	}
+	if userID != callerID {
+		if err := a.requireOperator(ctx); err != nil {
+			log.Warn("user not operator", sl.Err(err))
+			return time.Time{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	log.Info("attempting to mark user for deletion")
+	err := a.accountProvider.SetUserStatus(ctx, userID, models.UserPendingDeletion)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+			return time.Time{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to mark user for deletion", sl.Err(err))
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("user marked for deletion")
+	return time.Now().Add(a.retention), nil
+}
+
+// ChangeEmail changes the email of the caller. The current password is
+// required, so a stolen token alone can't take over the account
+func (a *Accounts) ChangeEmail(ctx context.Context, appName string, email string, password string) (string, error) {
+	const op = "accounts.ChangeEmail"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	userID := principal.UserID(ctx)
+	user, err := a.accountProvider.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	_, bcryptSpan := tracing.Start(ctx, "bcrypt.compare")
+	start := time.Now()
+	err = bcrypt.CompareHashAndPassword(user.PassHash, []byte(password))
+	metrics.Since(metrics.Bcrypt.WithLabelValues("compare"), start)
+	bcryptSpan.End()
+	if err != nil {
+		log.Info("invalid credentials", sl.Err(err))
+		return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	log.Info("attempting to change email")
+	err = a.accountProvider.UpdEmail(ctx, userID, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Warn("user already exists", sl.Err(err))
+			return "", fmt.Errorf("%s: %w", op, ErrUserExists)
+		}
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+			return "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to change email", sl.Err(err))
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("email changed")
+	return email, nil
+}
+
+// Purge anonymizes or deletes all users whose retention window has passed.
+// A user that fails is left for the next run and doesn't stop the others
+func (a *Accounts) Purge(ctx context.Context) error {
+	const op = "accounts.Purge"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	purged, failed := 0, 0
+	var afterID uint64
+	for {
+		ids, err := a.accountProvider.ListExpiredDeletions(ctx, time.Now().Add(-a.retention), afterID, purgeBatch)
+		if err != nil {
+			log.Error("failed to list expired deletions", sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, id := range ids {
+			err := a.accountProvider.PurgeUser(ctx, id, a.anonymize)
+			observePurge(err)
+			if err != nil {
+				if errors.Is(err, storage.ErrLastCreator) {
+					log.Warn("user is the last creator of an app", slog.Uint64("uid", id))
+				} else {
+					log.Error("failed to purge user", slog.Uint64("uid", id), sl.Err(err))
+				}
+				failed++
+				continue
+			}
+			purged++
+		}
+		if len(ids) < purgeBatch {
+			break
+		}
+		afterID = ids[len(ids)-1]
+	}
+	if purged > 0 {
+		log.Info("users purged", slog.Int("count", purged), slog.Bool("anonymize", a.anonymize))
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s: %d users not purged", op, failed)
+	}
+	return nil
+}
+
+func observePurge(err error) {
+	reason := ""
+	switch {
+	case errors.Is(err, storage.ErrLastCreator):
+		reason = "last_creator"
+	case err != nil:
+		reason = "internal"
+	}
+	result, reason := metrics.Result(reason)
+	metrics.Purges.WithLabelValues(result, reason).Inc()
+}
+
+// requireOperator checks that the caller is an operator calling with their own token.
+// Operators come from the config only, no RPC can make a user one
+func (a *Accounts) requireOperator(ctx context.Context) error {
+	p, _ := principal.FromContext(ctx)
+	if p.Kind != models.UserHuman || p.ActorID != 0 || !slices.Contains(a.operators, p.UserID) {
+		return ErrNotOperator
+	}
+	return nil
+}
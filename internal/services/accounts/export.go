@@ -0,0 +1,188 @@
+package accounts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/pseudonym"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+	"time"
+)
+
+// Export is the archive returned for a data access request.
+// It never contains password hashes, secrets or tokens
+type Export struct {
+	ExportedAt time.Time       `json:"exported_at"`
+	User       ExportUser      `json:"user"`
+	Apps       []ExportApp     `json:"apps"`
+	Sessions   []ExportSession `json:"sessions"`
+	Audit      []ExportAudit   `json:"audit"`
+}
+
+type ExportUser struct {
+	ID          uint64    `json:"id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Locale      string    `json:"locale"`
+	AvatarURL   string    `json:"avatar_url"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ExportApp struct {
+	AppID        int             `json:"app_id"`
+	AppName      string          `json:"app_name"`
+	Roles        []string        `json:"roles"`
+	AppMetadata  json.RawMessage `json:"app_metadata"`
+	UserMetadata json.RawMessage `json:"user_metadata"`
+}
+
+type ExportSession struct {
+	ID         uint64    `json:"id"`
+	AppID      int       `json:"app_id"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Revoked    bool      `json:"revoked"`
+}
+
+type ExportAudit struct {
+	ID        uint64    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Action    string    `json:"action"`
+	AppName   string    `json:"app_name"`
+	IP        string    `json:"ip"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason"`
+}
+
+// ExportUserData returns a JSON archive of everything stored about the user.
+// Users can export themselves, operators can export anyone
+func (a *Accounts) ExportUserData(ctx context.Context, appName string, userID uint64) ([]byte, error) {
+	const op = "accounts.ExportUserData"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	callerID := principal.UserID(ctx)
+	if userID == 0 {
+		userID = callerID
+	}
+	if userID != callerID {
+		if err := a.requireOperator(ctx); err != nil {
+			log.Warn("user not operator", sl.Err(err))
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	data, err := a.Export(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return data, nil
+}
+
+// Export builds the data archive of the user without any access checks.
+// It is used by the admin CLI
+func (a *Accounts) Export(ctx context.Context, userID uint64) ([]byte, error) {
+	const op = "accounts.Export"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx), slog.Uint64("uid", userID))
+
+	log.Info("exporting user data")
+	user, err := a.accountProvider.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to get user", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	memberships, err := a.accountProvider.ListMemberships(ctx, userID)
+	if err != nil {
+		log.Error("failed to list memberships", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	sessions, err := a.accountProvider.ListUserSessions(ctx, userID)
+	if err != nil {
+		log.Error("failed to list sessions", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	auditEntries, err := a.accountProvider.ListUserAudit(ctx, userID, pseudonym.Email(a.emailKey, user.Email))
+	if err != nil {
+		log.Error("failed to list audit entries", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	export := Export{
+		ExportedAt: time.Now().UTC(),
+		User: ExportUser{
+			ID:          user.ID,
+			Email:       user.Email,
+			DisplayName: user.DisplayName,
+			Locale:      user.Locale,
+			AvatarURL:   user.AvatarURL,
+			Status:      string(user.Status),
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+		},
+		Apps:     []ExportApp{},
+		Sessions: []ExportSession{},
+		Audit:    []ExportAudit{},
+	}
+	for _, m := range memberships {
+		app := ExportApp{
+			AppID:        m.AppID,
+			AppName:      m.AppName,
+			Roles:        []string{},
+			AppMetadata:  m.AppMetadata,
+			UserMetadata: m.UserMetadata,
+		}
+		if m.IsCreator {
+			app.Roles = append(app.Roles, "creator")
+		}
+		if m.IsAdmin {
+			app.Roles = append(app.Roles, "admin")
+		}
+		export.Apps = append(export.Apps, app)
+	}
+
+	for _, s := range sessions {
+		export.Sessions = append(export.Sessions, ExportSession{
+			ID:         s.ID,
+			AppID:      s.AppID,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			Revoked:    s.Revoked,
+		})
+	}
+
+	for _, e := range auditEntries {
+		export.Audit = append(export.Audit, ExportAudit{
+			ID:        e.ID,
+			CreatedAt: e.CreatedAt,
+			Action:    e.Action,
+			AppName:   e.AppName,
+			IP:        e.IP,
+			Outcome:   e.Outcome,
+			Reason:    e.Reason,
+		})
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		log.Error("failed to encode export", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("user data exported")
+	return data, nil
+}
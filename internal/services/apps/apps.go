@@ -5,10 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/jsonschema"
 	"github.com/neepooha/sso/internal/lib/logger/sl"
-	"github.com/neepooha/sso/internal/lib/logging"
+	"github.com/neepooha/sso/internal/lib/netguard"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/tracing"
 	"github.com/neepooha/sso/internal/storage"
 	"log/slog"
+	"slices"
 )
 
 type Apps struct {
@@ -17,6 +22,11 @@ type Apps struct {
 	userProvider      UserProvider
 	creatorProvider   CreatorProvider
 	adminProvider     AdminProvider
+	hookStorage       HookStorage
+	guard             *netguard.Guard
+	auditor           Auditor
+	// operators may list apps of any creator
+	operators []uint64
 }
 
 type AppsSetterDeleter interface {
@@ -25,10 +35,15 @@ type AppsSetterDeleter interface {
 	SetApp(ctx context.Context, appName string, appSecret string) (int, error)
 	UpdApp(ctx context.Context, appNameOlnd string, appName string, appSecret string) error
 	DelApp(ctx context.Context, appName string) error
+	ListApps(ctx context.Context, filter models.ListFilter, creatorEmail string) ([]models.App, error)
+	SetMetadataSchemas(ctx context.Context, appName string, schemas models.MetadataSchemas) error
+	SetClaimMapping(ctx context.Context, appName string, mapping map[string]string) error
+	SetAppSettings(ctx context.Context, appName string, settings models.AppSettings) error
 }
 
 type UserProvider interface {
 	GetUser(ctx context.Context, email string) (models.User, error)
+	GetUserByID(ctx context.Context, userID uint64) (models.User, error)
 }
 
 type CreatorProvider interface {
@@ -37,7 +52,11 @@ type CreatorProvider interface {
 }
 
 type AdminProvider interface {
-	SetAdmin(ctx context.Context, email string, appName string) error
+	GrantRole(ctx context.Context, email string, appName string, role string) error
+}
+
+type Auditor interface {
+	Record(ctx context.Context, entry models.AuditEntry)
 }
 
 var (
@@ -46,22 +65,30 @@ var (
 	ErrAppExists          = errors.New("app exists")
 	ErrAppNotFound        = errors.New("app not found")
 	ErrUserNotCreator     = errors.New("user not creator")
+	ErrInvalidSchema      = errors.New("invalid metadata schema")
+	ErrNotOperator        = errors.New("user isn't operator")
 )
 
 // New returns a new instanse of the Permissions service
-func New(log *slog.Logger, appsSetterDeleter AppsSetterDeleter, userProvider UserProvider, creatorProvider CreatorProvider, adminProvider AdminProvider) *Apps {
+func New(log *slog.Logger, appsSetterDeleter AppsSetterDeleter, userProvider UserProvider, creatorProvider CreatorProvider, adminProvider AdminProvider, hookStorage HookStorage, guard *netguard.Guard, auditor Auditor, operators []uint64) *Apps {
 	return &Apps{
 		log:               log,
 		appsSetterDeleter: appsSetterDeleter,
 		userProvider:      userProvider,
 		creatorProvider:   creatorProvider,
 		adminProvider:     adminProvider,
+		hookStorage:       hookStorage,
+		guard:             guard,
+		auditor:           auditor,
+		operators:         operators,
 	}
 }
 
 func (a *Apps) GetAppID(ctx context.Context, appName string) (int, string, error) {
 	const op = "apps.GetAppID"
-	log := a.log.With(slog.String("op", op))
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
 
 	log.Info("attempting to get appID")
 	app, err := a.appsSetterDeleter.GetAppID(ctx, appName)
@@ -77,9 +104,14 @@ func (a *Apps) GetAppID(ctx context.Context, appName string) (int, string, error
 	return app.ID, app.Name, nil
 }
 
-func (a *Apps) SetApp(ctx context.Context, email string, appName string, appSecret string) (int, error) {
+func (a *Apps) SetApp(ctx context.Context, email string, appName string, appSecret string) (_ int, err error) {
 	const op = "apps.SetApp"
-	log := a.log.With(slog.String("op", op))
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	entry := models.AuditEntry{Action: models.AuditSetApp, TargetEmail: email, AppName: appName}
+	defer func() { a.auditor.Record(ctx, entry.WithResult(err, ErrInvalidCredentials, ErrAppExists)) }()
 
 	user, err := a.userProvider.GetUser(ctx, email)
 	if err != nil {
@@ -90,6 +122,7 @@ func (a *Apps) SetApp(ctx context.Context, email string, appName string, appSecr
 		log.Error("failed to find user", sl.Err(err))
 		return 0, fmt.Errorf("%s:%w", op, err)
 	}
+	entry.ActorID = user.ID
 
 	log.Info("attempting to set app")
 	appID, err := a.appsSetterDeleter.SetApp(ctx, appName, appSecret)
@@ -106,9 +139,9 @@ func (a *Apps) SetApp(ctx context.Context, email string, appName string, appSecr
 		log.Error("failed to set creator", sl.Err(err))
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	err = a.adminProvider.SetAdmin(ctx, email, appName)
+	err = a.adminProvider.GrantRole(ctx, email, appName, models.RoleAdmin)
 	if err != nil {
-		log.Error("failed to set creator", sl.Err(err))
+		log.Error("failed to set admin", sl.Err(err))
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -116,33 +149,23 @@ func (a *Apps) SetApp(ctx context.Context, email string, appName string, appSecr
 	return appID, nil
 }
 
-func (a *Apps) UpdApp(ctx context.Context, appName string, NewAppName string, NewAppSecret string) (bool, error) {
+func (a *Apps) UpdApp(ctx context.Context, appName string, NewAppName string, NewAppSecret string) (_ bool, err error) {
 	const op = "apps.UpdApp"
-	log := a.log.With(slog.String("op", op))
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
 
-	log.Info("attempting to log in")
-	err := logging.Logging(ctx, appName, a.creatorProvider, a.appsSetterDeleter)
-	if err != nil {
-		if errors.Is(err, logging.ErrCreatorNotFound) {
-			log.Warn("user not creator", sl.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrNotCreator)
-		}
-		if errors.Is(err, logging.ErrInvalidCredentials) {
-			log.Warn("cant get info of user", sl.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-		}
-		log.Warn("error logging", sl.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
-	}
+	entry := models.AuditEntry{Action: models.AuditUpdApp, Target: NewAppName, AppName: appName}
+	defer func() {
+		a.auditor.Record(ctx, entry.WithResult(err, ErrInvalidCredentials, ErrNotCreator, ErrAppExists))
+	}()
+
+	entry.ActorID = principal.UserID(ctx)
 
 	log.Info("attempting to update app")
 	err = a.appsSetterDeleter.UpdApp(ctx, appName, NewAppName, NewAppSecret)
 	if err != nil {
 		if errors.Is(err, storage.ErrAppNotFound) {
-			if errors.Is(err, logging.ErrAppNotFound) {
-				log.Warn("app not found", sl.Err(err))
-				return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-			}
 			log.Warn("app not found ", sl.Err(err))
 			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 		}
@@ -157,24 +180,16 @@ func (a *Apps) UpdApp(ctx context.Context, appName string, NewAppName string, Ne
 	return true, nil
 }
 
-func (a *Apps) DelApp(ctx context.Context, appName string) (bool, error) {
+func (a *Apps) DelApp(ctx context.Context, appName string) (_ bool, err error) {
 	const op = "apps.DelApp"
-	log := a.log.With(slog.String("op", op))
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
 
-	log.Info("attempting to log in")
-	err := logging.Logging(ctx, appName, a.creatorProvider, a.appsSetterDeleter)
-	if err != nil {
-		if errors.Is(err, logging.ErrCreatorNotFound) {
-			log.Warn("user not creator", sl.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrNotCreator)
-		}
-		if errors.Is(err, logging.ErrInvalidCredentials) {
-			log.Warn("cant get info of user", sl.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-		}
-		log.Warn("error logging", sl.Err(err))
-		return false, fmt.Errorf("%s: %w", op, err)
-	}
+	entry := models.AuditEntry{Action: models.AuditDelApp, AppName: appName}
+	defer func() { a.auditor.Record(ctx, entry.WithResult(err, ErrInvalidCredentials, ErrNotCreator)) }()
+
+	entry.ActorID = principal.UserID(ctx)
 
 	log.Info("attempting to delete app")
 	err = a.appsSetterDeleter.DelApp(ctx, appName)
@@ -189,3 +204,67 @@ func (a *Apps) DelApp(ctx context.Context, appName string) (bool, error) {
 	log.Info("app deleted")
 	return true, nil
 }
+
+// ListApps returns one page of apps created by creatorEmail. Users list only their own apps,
+// empty creatorEmail is the caller's email; operators list apps of anyone, or all apps
+func (a *Apps) ListApps(ctx context.Context, filter models.ListFilter, creatorEmail string) ([]models.App, string, error) {
+	const op = "apps.ListApps"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	log.Info("listing apps")
+	p, _ := principal.FromContext(ctx)
+	if p.Kind != models.UserHuman || p.ActorID != 0 || !slices.Contains(a.operators, p.UserID) {
+		user, err := a.userProvider.GetUserByID(ctx, p.UserID)
+		if err != nil {
+			log.Error("failed to get user", sl.Err(err))
+			return nil, "", fmt.Errorf("%s: %w", op, err)
+		}
+		if creatorEmail != "" && creatorEmail != user.Email {
+			log.Warn("user not operator", slog.Uint64("uid", p.UserID))
+			return nil, "", fmt.Errorf("%s: %w", op, ErrNotOperator)
+		}
+		creatorEmail = user.Email
+	}
+	limit := filter.Limit
+	filter.Limit++
+	apps, err := a.appsSetterDeleter.ListApps(ctx, filter, creatorEmail)
+	if err != nil {
+		log.Error("failed to list apps", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	apps, next := pagination.NextToken(apps, limit, func(app models.App) uint64 { return uint64(app.ID) })
+	return apps, next, nil
+}
+
+// SetMetadataSchema sets JSON Schemas for app_metadata and user_metadata of the app
+func (a *Apps) SetMetadataSchema(ctx context.Context, appName string, schemas models.MetadataSchemas) (bool, error) {
+	const op = "apps.SetMetadataSchema"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	for _, schema := range [][]byte{schemas.AppMetadata, schemas.UserMetadata} {
+		if len(schema) == 0 {
+			continue
+		}
+		if _, err := jsonschema.Compile(schema); err != nil {
+			log.Warn("invalid schema", sl.Err(err))
+			return false, fmt.Errorf("%s: %w: %s", op, ErrInvalidSchema, err)
+		}
+	}
+
+	log.Info("attempting to set metadata schema")
+	err := a.appsSetterDeleter.SetMetadataSchemas(ctx, appName, schemas)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to set metadata schema", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("metadata schema set")
+	return true, nil
+}
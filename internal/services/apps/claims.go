@@ -0,0 +1,60 @@
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/lib/claimmap"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+)
+
+var ErrInvalidMapping = errors.New("invalid claim mapping")
+
+// SetClaimMapping validates and saves the claim mapping of the app, empty mapping
+// removes it. Caller must be creator of the app
+func (a *Apps) SetClaimMapping(ctx context.Context, appName string, mapping map[string]string) (bool, error) {
+	const op = "apps.SetClaimMapping"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	if err := claimmap.Validate(mapping); err != nil {
+		log.Warn("invalid claim mapping", sl.Err(err))
+		return false, fmt.Errorf("%s: %w: %w", op, ErrInvalidMapping, err)
+	}
+
+	log.Info("attempting to set claim mapping")
+	err := a.appsSetterDeleter.SetClaimMapping(ctx, appName, mapping)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to set claim mapping", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("claim mapping set", slog.Int("claims", len(mapping)))
+	return true, nil
+}
+
+// GetClaimMapping returns the claim mapping of the app. Caller must be creator of the app
+func (a *Apps) GetClaimMapping(ctx context.Context, appName string) (map[string]string, error) {
+	const op = "apps.GetClaimMapping"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	app, err := a.appsSetterDeleter.GetApp(ctx, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to get app", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return app.ClaimMapping, nil
+}
@@ -0,0 +1,101 @@
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxHookTimeout keeps a slow hook service from holding logins for long
+const maxHookTimeout = 30 * time.Second
+
+var ErrInvalidHook = errors.New("invalid hook")
+
+type HookStorage interface {
+	SetHooks(ctx context.Context, appName string, hooks []models.Hook) error
+	ListHooks(ctx context.Context, appName string, stage string) ([]models.Hook, error)
+}
+
+// SetHooks replaces all hooks of the app. Caller must be creator of the app
+func (a *Apps) SetHooks(ctx context.Context, appName string, hooks []models.Hook) (bool, error) {
+	const op = "apps.SetHooks"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	for _, hook := range hooks {
+		if err := a.validateHook(ctx, hook); err != nil {
+			log.Warn("invalid hook", sl.Err(err))
+			return false, fmt.Errorf("%s: %w: %s", op, ErrInvalidHook, err)
+		}
+	}
+
+	log.Info("attempting to set hooks")
+	err := a.hookStorage.SetHooks(ctx, appName, hooks)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to set hooks", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("hooks set", slog.Int("count", len(hooks)))
+	return true, nil
+}
+
+// ListHooks returns all hooks of the app in run order. Caller must be creator of the app
+func (a *Apps) ListHooks(ctx context.Context, appName string) ([]models.Hook, error) {
+	const op = "apps.ListHooks"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	hooks, err := a.hookStorage.ListHooks(ctx, appName, "")
+	if err != nil {
+		log.Error("failed to list hooks", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return hooks, nil
+}
+
+// validateHook checks the hook and that its target host is allowed by the guard
+func (a *Apps) validateHook(ctx context.Context, hook models.Hook) error {
+	if !slices.Contains(models.HookStages, hook.Stage) {
+		return fmt.Errorf("unknown stage %q", hook.Stage)
+	}
+	if hook.Timeout < 0 || hook.Timeout > maxHookTimeout {
+		return fmt.Errorf("timeout must be between 0 and %s", maxHookTimeout)
+	}
+	var host string
+	switch hook.Kind {
+	case models.HookHTTP:
+		u, err := url.Parse(hook.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("target of http hook must be an http(s) URL")
+		}
+		host = u.Hostname()
+	case models.HookGRPC:
+		h, _, err := net.SplitHostPort(strings.TrimPrefix(hook.Target, "dns:///"))
+		if err != nil || h == "" {
+			return fmt.Errorf("target of grpc hook must be host:port")
+		}
+		host = h
+	default:
+		return fmt.Errorf("unknown kind %q", hook.Kind)
+	}
+	if err := a.guard.CheckHost(ctx, host); err != nil {
+		return fmt.Errorf("target of hook isn't allowed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+	"time"
+)
+
+var ErrInvalidSettings = errors.New("invalid app settings")
+
+// maxAccessTTL keeps access tokens short lived, they can't be revoked before they expire
+const maxAccessTTL = 24 * time.Hour
+
+// SetAppSettings saves token lifetimes and session policies of the app, zero values
+// mean the service defaults. Caller must be creator of the app
+func (a *Apps) SetAppSettings(ctx context.Context, appName string, settings models.AppSettings) (bool, error) {
+	const op = "apps.SetAppSettings"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	if err := validateSettings(settings); err != nil {
+		log.Warn("invalid settings", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("attempting to set app settings")
+	err := a.appsSetterDeleter.SetAppSettings(ctx, appName, settings)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to set app settings", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("app settings set")
+	return true, nil
+}
+
+// GetAppSettings returns the settings saved for the app. Caller must be creator of the app
+func (a *Apps) GetAppSettings(ctx context.Context, appName string) (models.AppSettings, error) {
+	const op = "apps.GetAppSettings"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	app, err := a.appsSetterDeleter.GetApp(ctx, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return models.AppSettings{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to get app", sl.Err(err))
+		return models.AppSettings{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return app.Settings, nil
+}
+
+func validateSettings(s models.AppSettings) error {
+	durations := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"access token ttl", s.AccessTTL},
+		{"refresh token ttl", s.RefreshTTL},
+		{"idle timeout", s.IdleTimeout},
+		{"absolute lifetime", s.AbsoluteLifetime},
+	}
+	for _, d := range durations {
+		if d.d < 0 {
+			return fmt.Errorf("%w: %s is negative", ErrInvalidSettings, d.name)
+		}
+		if d.d%time.Second != 0 {
+			return fmt.Errorf("%w: %s is not whole seconds", ErrInvalidSettings, d.name)
+		}
+	}
+	if s.AccessTTL > maxAccessTTL {
+		return fmt.Errorf("%w: access token ttl is over %s", ErrInvalidSettings, maxAccessTTL)
+	}
+	if s.MaxSessions < 0 {
+		return fmt.Errorf("%w: max sessions is negative", ErrInvalidSettings)
+	}
+	if s.AbsoluteLifetime > 0 && s.IdleTimeout > s.AbsoluteLifetime {
+		return fmt.Errorf("%w: idle timeout is over absolute lifetime", ErrInvalidSettings)
+	}
+	return nil
+}
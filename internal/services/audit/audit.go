@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/clientip"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/pseudonym"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"log/slog"
+	"time"
+)
+
+// writeTimeout bounds writing an entry, which outlives the deadline of the audited request
+const writeTimeout = 5 * time.Second
+
+type Audit struct {
+	log          *slog.Logger
+	auditStorage AuditStorage
+	// emailKey keys the hashes stored instead of target emails
+	emailKey []byte
+}
+
+type AuditStorage interface {
+	AppendAudit(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error)
+	QueryAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+}
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNotCreator         = errors.New("user isn't creator")
+)
+
+// New returns a new instanse of the Audit service
+func New(log *slog.Logger, auditStorage AuditStorage, emailKey []byte) *Audit {
+	return &Audit{
+		log:          log,
+		auditStorage: auditStorage,
+		emailKey:     emailKey,
+	}
+}
+
+// Record appends the entry to the audit log. Time, client IP and the operator
+// of an impersonation token are taken from the request when not set,
+// TargetEmail is stored as its keyed hash.
+// Failures are logged and never returned, so auditing can't break the audited operation
+func (a *Audit) Record(ctx context.Context, entry models.AuditEntry) {
+	const op = "audit.Record"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	if entry.IP == "" {
+		entry.IP = clientip.FromContext(ctx)
+	}
+	// the log is append-only, an email written to it would outlive the purge of the user
+	if entry.TargetEmail != "" {
+		entry.Target = pseudonym.Email(a.emailKey, entry.TargetEmail)
+		entry.TargetEmail = ""
+	}
+	if p, ok := principal.FromContext(ctx); ok {
+		if entry.ActorKind == "" && entry.ActorID == p.UserID {
+			entry.ActorKind = p.Kind
+		}
+		// whatever is done with an impersonation token is done by the operator
+		if entry.OperatorID == 0 && p.ActorID != 0 && p.ActorID != entry.ActorID {
+			entry.OperatorID = p.ActorID
+		}
+	}
+	// the audited request may be already cancelled, but the entry must be written
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+	defer cancel()
+
+	if _, err := a.auditStorage.AppendAudit(ctx, entry); err != nil {
+		log.Error("failed to append audit entry", slog.String("action", entry.Action), sl.Err(err))
+	}
+}
+
+// QueryAudit returns one page of audit entries of the app, newest first.
+// Caller must be creator of the app
+func (a *Audit) QueryAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, string, error) {
+	const op = "audit.QueryAudit"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	log.Info("querying audit log")
+	limit := filter.Limit
+	filter.Limit++
+	entries, err := a.auditStorage.QueryAudit(ctx, filter)
+	if err != nil {
+		log.Error("failed to query audit log", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	entries, next := pagination.NextToken(entries, limit, func(e models.AuditEntry) uint64 { return e.ID })
+	return entries, next, nil
+}
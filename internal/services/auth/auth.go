@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"github.com/neepooha/sso/internal/domain/models"
-	"github.com/neepooha/sso/internal/lib/jwt"
+	"github.com/neepooha/sso/internal/lib/clientip"
 	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/metrics"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/lib/tracing"
 	"github.com/neepooha/sso/internal/storage"
 	"log/slog"
 	"time"
@@ -15,95 +18,246 @@ import (
 )
 
 type Auth struct {
-	log          *slog.Logger
-	userSaver    UserSaver
-	userProvider UserProvider
-	appProvider  AppProvider
-	tokenTTL     time.Duration
+	log             *slog.Logger
+	userSaver       UserSaver
+	userProvider    UserProvider
+	appProvider     AppProvider
+	creatorProvider CreatorProvider
+	roleProvider    RoleProvider
+	claimSource     ClaimSource
+	auditor         Auditor
+	sessions        SessionStorage
+	hooks           Hooks
+	// defaults are used for settings the app left unset
+	defaults models.AppSettings
+	// impersonationTTL is the longest lifetime of impersonation tokens
+	impersonationTTL time.Duration
 }
 
 type UserSaver interface {
-	SaveUser(ctx context.Context, email string, passHash []byte) (uid uint64, err error)
+	SaveUser(ctx context.Context, email string, passHash []byte, appID int) (uid uint64, err error)
+	AddMember(ctx context.Context, userID uint64, appID int) error
 }
 
 type UserProvider interface {
 	GetUser(ctx context.Context, email string) (models.User, error)
+	GetUserByID(ctx context.Context, userID uint64) (models.User, error)
+	ListUsers(ctx context.Context, appName string, filter models.ListFilter) ([]models.User, error)
+	IsMember(ctx context.Context, userID uint64, appName string) error
 }
 
 type AppProvider interface {
 	GetApp(ctx context.Context, appName string) (models.App, error)
 }
 
+type CreatorProvider interface {
+	IsCreator(ctx context.Context, userID uint64, appName string) error
+}
+
+type Auditor interface {
+	Record(ctx context.Context, entry models.AuditEntry)
+}
+
+type Hooks interface {
+	Run(ctx context.Context, in models.HookInput) (models.HookResult, error)
+	Configured(ctx context.Context, stage string) (bool, error)
+}
+
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserExists         = errors.New("user already exists")
 	ErrUserNotFound       = errors.New("user not found")
+	ErrNotCreator         = errors.New("user isn't creator")
+	ErrUserSuspended      = errors.New("user is suspended")
+	ErrUserDeleted        = errors.New("user is pending deletion")
+	ErrHookDenied         = errors.New("denied by hook")
+	ErrHookFailed         = errors.New("hook failed")
+	// ErrAppRequired is returned to registrations without an app while apps
+	// have pre_register hooks, which such registrations would skip
+	ErrAppRequired = errors.New("app is required")
 )
 
+// HookDeniedError carries the reason given by the hook service, it matches ErrHookDenied
+type HookDeniedError struct {
+	Reason string
+}
+
+func (e *HookDeniedError) Error() string {
+	if e.Reason == "" {
+		return ErrHookDenied.Error()
+	}
+	return ErrHookDenied.Error() + ": " + e.Reason
+}
+
+func (e *HookDeniedError) Unwrap() error {
+	return ErrHookDenied
+}
+
 // New returns a new instanse of the Auth service
-func New(log *slog.Logger, userSaver UserSaver, userProvider UserProvider, appProvider AppProvider, tokenTTL time.Duration) *Auth {
+func New(log *slog.Logger, userSaver UserSaver, userProvider UserProvider, appProvider AppProvider, creatorProvider CreatorProvider, roleProvider RoleProvider, claimSource ClaimSource, sessions SessionStorage, auditor Auditor, hooks Hooks, defaults models.AppSettings, impersonationTTL time.Duration) *Auth {
 	return &Auth{
-		log:          log,
-		userSaver:    userSaver,
-		userProvider: userProvider,
-		appProvider:  appProvider,
-		tokenTTL:     tokenTTL,
+		log:             log,
+		userSaver:       userSaver,
+		userProvider:    userProvider,
+		appProvider:     appProvider,
+		creatorProvider: creatorProvider,
+		roleProvider:    roleProvider,
+		claimSource:     claimSource,
+		auditor:         auditor,
+		sessions:        sessions,
+		hooks:           hooks,
+		defaults:        defaults,
+
+		impersonationTTL: impersonationTTL,
 	}
 }
 
-// Login checks if user with given credentials exists in the system
-func (a *Auth) Login(ctx context.Context, email string, password string, appName string) (string, error) {
+// Login checks if user with given credentials exists in the system and starts
+// a session, it returns an access token and a refresh token of the session.
+// With join the user becomes a member of the app, logging in alone doesn't make one
+func (a *Auth) Login(ctx context.Context, email string, password string, appName string, join bool) (_ models.Tokens, err error) {
 	const op = "auth.Login"
-	log := a.log.With(slog.String("op", op))
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	entry := models.AuditEntry{Action: models.AuditLogin, TargetEmail: email, AppName: appName}
+	defer func() {
+		a.auditor.Record(ctx, entry.WithResult(err, ErrInvalidCredentials, ErrUserSuspended, ErrUserDeleted, ErrHookDenied, ErrHookFailed, ErrClaimMapping))
+		observeLogin(appName, err)
+	}()
 
 	log.Info("attempting to login user")
 	user, err := a.userProvider.GetUser(ctx, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Warn("user not found", sl.Err(err))
-			return "", fmt.Errorf("%s:%w", op, ErrInvalidCredentials)
+			return models.Tokens{}, fmt.Errorf("%s:%w", op, ErrInvalidCredentials)
 		}
 		log.Error("failed to find user", sl.Err(err))
-		return "", fmt.Errorf("%s:%w", op, err)
+		return models.Tokens{}, fmt.Errorf("%s:%w", op, err)
 	}
+	entry.ActorID = user.ID
 
-	if err := bcrypt.CompareHashAndPassword(user.PassHash, []byte(password)); err != nil {
+	if user.Kind == models.UserService {
+		log.Warn("service account can't log in with password")
+		return models.Tokens{}, fmt.Errorf("%s:%w", op, ErrInvalidCredentials)
+	}
+	_, bcryptSpan := tracing.Start(ctx, "bcrypt.compare")
+	start := time.Now()
+	err = bcrypt.CompareHashAndPassword(user.PassHash, []byte(password))
+	metrics.Since(metrics.Bcrypt.WithLabelValues("compare"), start)
+	bcryptSpan.End()
+	if err != nil {
 		log.Error("failed to compare passwords", sl.Err(err))
-		return "", fmt.Errorf("%s:%w", op, ErrInvalidCredentials)
+		return models.Tokens{}, fmt.Errorf("%s:%w", op, ErrInvalidCredentials)
+	}
+
+	switch user.Status {
+	case models.UserSuspended:
+		log.Warn("user is suspended")
+		return models.Tokens{}, fmt.Errorf("%s:%w", op, ErrUserSuspended)
+	case models.UserPendingDeletion, models.UserDeleted:
+		log.Warn("user is deleted")
+		return models.Tokens{}, fmt.Errorf("%s:%w", op, ErrUserDeleted)
 	}
 
 	app, err := a.appProvider.GetApp(ctx, appName)
 	if err != nil {
 		if errors.Is(err, storage.ErrAppNotFound) {
 			log.Warn("app not found", sl.Err(err))
-			return "", fmt.Errorf("%s:%w", op, ErrInvalidCredentials)
+			return models.Tokens{}, fmt.Errorf("%s:%w", op, ErrInvalidCredentials)
 		}
 		log.Error("failed to find app", sl.Err(err))
-		return "", fmt.Errorf("%s:%w", op, err)
+		return models.Tokens{}, fmt.Errorf("%s:%w", op, err)
 	}
 
-	log.Info("user logged in successfully")
-	token, err := jwt.NewToken(user, app, a.tokenTTL)
+	in := models.HookInput{AppName: appName, UserID: user.ID, Email: user.Email, IP: clientip.FromContext(ctx)}
+	if _, err := a.runHook(ctx, models.HookPreLogin, in); err != nil {
+		log.Warn("login stopped by hook", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s:%w", op, err)
+	}
+
+	in.Claims, err = a.claims(ctx, user, app)
+	if err != nil {
+		log.Error("failed to map claims", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s:%w", op, err)
+	}
+	minted, err := a.runHook(ctx, models.HookTokenMint, in)
+	if err != nil {
+		log.Warn("token minting stopped by hook", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s:%w", op, err)
+	}
+
+	if join {
+		if err := a.userSaver.AddMember(ctx, user.ID, app.ID); err != nil {
+			log.Error("failed to add user to app", sl.Err(err))
+			return models.Tokens{}, fmt.Errorf("%s:%w", op, err)
+		}
+	}
+	tokens, err := a.startSession(ctx, user, app, in.Claims, minted.Claims)
 	if err != nil {
-		log.Error("failed to generate token", sl.Err(err))
-		return "", fmt.Errorf("%s:%w", op, err)
+		log.Error("failed to start session", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s:%w", op, err)
 	}
-	return token, nil
+	log.Info("user logged in successfully")
+	return tokens, nil
 }
 
-// RegisterNewUser registers new user in the system and returns userID
-func (a *Auth) RegisterNewUser(ctx context.Context, email string, password string) (uint64, error) {
+// RegisterNewUser registers new user in the system and returns userID.
+// When appName is set the user joins the app and hooks of the app are run. It
+// may be empty only while no app has pre_register hooks
+func (a *Auth) RegisterNewUser(ctx context.Context, email string, password string, appName string) (_ uint64, err error) {
 	const op = "auth.RegisterNewUser"
-	log := a.log.With(slog.String("op", op))
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	entry := models.AuditEntry{Action: models.AuditRegister, TargetEmail: email, AppName: appName}
+	defer func() {
+		a.auditor.Record(ctx, entry.WithResult(err, ErrUserExists, ErrInvalidCredentials, ErrHookDenied, ErrHookFailed, ErrAppRequired))
+		observeRegistration(appName, err)
+	}()
+
+	in := models.HookInput{AppName: appName, Email: email, IP: clientip.FromContext(ctx)}
+	var app models.App
+	if appName != "" {
+		if app, err = a.appProvider.GetApp(ctx, appName); err != nil {
+			if errors.Is(err, storage.ErrAppNotFound) {
+				log.Warn("app not found", sl.Err(err))
+				return 0, fmt.Errorf("%s:%w", op, ErrInvalidCredentials)
+			}
+			log.Error("failed to find app", sl.Err(err))
+			return 0, fmt.Errorf("%s:%w", op, err)
+		}
+		if _, err := a.runHook(ctx, models.HookPreRegister, in); err != nil {
+			log.Warn("registration stopped by hook", sl.Err(err))
+			return 0, fmt.Errorf("%s:%w", op, err)
+		}
+	} else {
+		hooked, err := a.hooks.Configured(ctx, models.HookPreRegister)
+		if err != nil {
+			log.Error("failed to check hooks", sl.Err(err))
+			return 0, fmt.Errorf("%s:%w", op, err)
+		}
+		if hooked {
+			log.Warn("registration without app while pre_register hooks are configured")
+			return 0, fmt.Errorf("%s:%w", op, ErrAppRequired)
+		}
+	}
 
 	log.Info("registering user")
+	_, bcryptSpan := tracing.Start(ctx, "bcrypt.hash")
+	start := time.Now()
 	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	metrics.Since(metrics.Bcrypt.WithLabelValues("hash"), start)
+	bcryptSpan.End()
 	if err != nil {
 		log.Error("failed to generate password Hash", sl.Err(err))
 		return 0, fmt.Errorf("%s:%w", op, err)
 	}
 
-	id, err := a.userSaver.SaveUser(ctx, email, passHash)
+	id, err := a.userSaver.SaveUser(ctx, email, passHash, app.ID)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) {
 			log.Warn("user already exists", sl.Err(err))
@@ -112,14 +266,25 @@ func (a *Auth) RegisterNewUser(ctx context.Context, email string, password strin
 		log.Error("failed to save user", sl.Err(err))
 		return 0, fmt.Errorf("%s:%w", op, err)
 	}
+	entry.ActorID = id
 	log.Info("user registered")
 
+	if appName != "" {
+		// the user is already saved, so failures here are only logged
+		in.UserID = id
+		if _, err := a.runHook(ctx, models.HookPostRegister, in); err != nil {
+			log.Error("post register hook failed", sl.Err(err))
+		}
+	}
+
 	return id, nil
 }
 
 func (a *Auth) GetUserID(ctx context.Context, email string) (uint64, error) {
 	const op = "auth.GetUserID"
-	log := a.log.With(slog.String("op", op))
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
 
 	log.Info("attemting to get userID")
 	user, err := a.userProvider.GetUser(ctx, email)
@@ -135,3 +300,35 @@ func (a *Auth) GetUserID(ctx context.Context, email string) (uint64, error) {
 
 	return user.ID, nil
 }
+
+// ListUsers returns one page of users of the app. Caller must be creator of the app
+func (a *Auth) ListUsers(ctx context.Context, appName string, filter models.ListFilter) ([]models.User, string, error) {
+	const op = "auth.ListUsers"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	log.Info("listing users")
+	limit := filter.Limit
+	filter.Limit++
+	users, err := a.userProvider.ListUsers(ctx, appName, filter)
+	if err != nil {
+		log.Error("failed to list users", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	users, next := pagination.NextToken(users, limit, func(u models.User) uint64 { return u.ID })
+	return users, next, nil
+}
+
+// runHook runs hooks of the stage and turns a deny into HookDeniedError
+func (a *Auth) runHook(ctx context.Context, stage string, in models.HookInput) (models.HookResult, error) {
+	in.Stage = stage
+	res, err := a.hooks.Run(ctx, in)
+	if err != nil {
+		return models.HookResult{}, fmt.Errorf("%w: %w", ErrHookFailed, err)
+	}
+	if res.Deny && stage != models.HookPostRegister {
+		return models.HookResult{}, &HookDeniedError{Reason: res.Reason}
+	}
+	return res, nil
+}
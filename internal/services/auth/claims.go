@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/claimmap"
+	"github.com/neepooha/sso/internal/lib/jwt"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+)
+
+// ClaimSource provides the data available to claim mappings
+type ClaimSource interface {
+	GetProfile(ctx context.Context, userID uint64, appName string) (models.Profile, error)
+	IsAdmin(ctx context.Context, userID uint64, appName string) error
+}
+
+var (
+	ErrInvalidMapping = errors.New("invalid claim mapping")
+	ErrClaimMapping   = errors.New("claim mapping failed")
+)
+
+// DryRunToken returns the claims a token of the user would get, without hooks
+// and without signing. A non-empty mapping is previewed instead of the saved one.
+// Zero userID means the caller, other users must be members of the app.
+// Caller must be creator of the app
+func (a *Auth) DryRunToken(ctx context.Context, appName string, userID uint64, mapping map[string]string) (map[string]any, error) {
+	const op = "auth.DryRunToken"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	if err := claimmap.Validate(mapping); err != nil {
+		log.Warn("invalid claim mapping", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w: %w", op, ErrInvalidMapping, err)
+	}
+
+	callerID := principal.UserID(ctx)
+	if userID == 0 {
+		userID = callerID
+	}
+
+	app, err := a.appProvider.GetApp(ctx, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return nil, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to find app", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// users of other apps look the same as missing ones
+	if err := a.userProvider.IsMember(ctx, userID, appName); err != nil {
+		if errors.Is(err, storage.ErrMemberNotFound) || errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user isn't member of app", sl.Err(err))
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to check membership", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(mapping) > 0 {
+		app.ClaimMapping = mapping
+	}
+
+	profile, err := a.claimSource.GetProfile(ctx, userID, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) || errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("user not found", sl.Err(err))
+			return nil, fmt.Errorf("%s: %w", op, ErrUserNotFound)
+		}
+		log.Error("failed to get profile", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	claims, err := a.claims(ctx, profile.User, app)
+	if err != nil {
+		log.Warn("failed to map claims", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return claims, nil
+}
+
+// claims returns the default claims of the token with the claim mapping of the app applied.
+// Mapped claims replace default ones, except claims reserved by claimmap
+func (a *Auth) claims(ctx context.Context, user models.User, app models.App) (map[string]any, error) {
+	claims := jwt.Claims(user, app, app.Settings.Or(a.defaults).AccessTTL)
+	if len(app.ClaimMapping) == 0 {
+		return claims, nil
+	}
+
+	env, err := a.claimEnv(ctx, user, app, claims)
+	if err != nil {
+		return nil, err
+	}
+	mapped, err := claimmap.Apply(app.ClaimMapping, env)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrClaimMapping, err)
+	}
+	for k, v := range mapped {
+		claims[k] = v
+	}
+	return claims, nil
+}
+
+func (a *Auth) claimEnv(ctx context.Context, user models.User, app models.App, claims map[string]any) (claimmap.Env, error) {
+	profile, err := a.claimSource.GetProfile(ctx, user.ID, app.Name)
+	if err != nil {
+		return claimmap.Env{}, err
+	}
+
+	roles := []string{}
+	err = a.claimSource.IsAdmin(ctx, user.ID, app.Name)
+	if err == nil {
+		roles = append(roles, "admin")
+	} else if !errors.Is(err, storage.ErrAdminNotFound) {
+		return claimmap.Env{}, err
+	}
+	err = a.creatorProvider.IsCreator(ctx, user.ID, app.Name)
+	if err == nil {
+		roles = append(roles, "creator")
+	} else if !errors.Is(err, storage.ErrCreatorNotFound) {
+		return claimmap.Env{}, err
+	}
+
+	appMetadata, err := object(profile.AppMetadata)
+	if err != nil {
+		return claimmap.Env{}, err
+	}
+	userMetadata, err := object(profile.UserMetadata)
+	if err != nil {
+		return claimmap.Env{}, err
+	}
+
+	return claimmap.Env{
+		User: map[string]any{
+			"id":         user.ID,
+			"email":      user.Email,
+			"name":       user.DisplayName,
+			"locale":     user.Locale,
+			"picture":    user.AvatarURL,
+			"status":     string(user.Status),
+			"created_at": user.CreatedAt.Unix(),
+		},
+		App: map[string]any{
+			"id":   app.ID,
+			"name": app.Name,
+		},
+		Roles:    roles,
+		Metadata: map[string]any{"app": appMetadata, "user": userMetadata},
+		Claims:   claims,
+	}, nil
+}
+
+func object(data json.RawMessage) (map[string]any, error) {
+	m := map[string]any{}
+	if len(data) == 0 {
+		return m, nil
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/clientip"
+	"github.com/neepooha/sso/internal/lib/jwt"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type RoleProvider interface {
+	GetRoles(ctx context.Context, userID uint64, appName string) ([]string, error)
+}
+
+var (
+	ErrNotSupport          = errors.New("user isn't support")
+	ErrPrivilegedTarget    = errors.New("user with a role can't be impersonated")
+	ErrNestedImpersonation = errors.New("impersonation token can't impersonate")
+)
+
+// Impersonate issues a short-lived access token of the user with the email for the caller,
+// who must have the support role of the app. The token carries the caller in the act claim,
+// has no session and can't be refreshed. Only members of the app without any role in it
+// can be impersonated
+func (a *Auth) Impersonate(ctx context.Context, appName string, email string) (_ string, _ time.Time, err error) {
+	const op = "auth.Impersonate"
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	entry := models.AuditEntry{Action: models.AuditImpersonate, TargetEmail: email, AppName: appName}
+	defer func() {
+		a.auditor.Record(ctx, entry.WithResult(err, ErrInvalidCredentials, ErrNotSupport, ErrPrivilegedTarget, ErrNestedImpersonation, ErrUserSuspended, ErrUserDeleted, ErrHookDenied, ErrHookFailed, ErrClaimMapping))
+	}()
+
+	caller, _ := principal.FromContext(ctx)
+	entry.ActorID = caller.UserID
+	if caller.ActorID != 0 {
+		log.Warn("impersonation token used to impersonate", slog.Uint64("act", caller.ActorID))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, ErrNestedImpersonation)
+	}
+	roles, err := a.roleProvider.GetRoles(ctx, caller.UserID, appName)
+	if err != nil {
+		log.Error("failed to get roles of caller", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if !slices.Contains(roles, models.RoleSupport) {
+		log.Warn("caller is not support", slog.Uint64("uid", caller.UserID))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, ErrNotSupport)
+	}
+
+	log.Info("attempting to impersonate user")
+	user, err := a.userProvider.GetUser(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			log.Warn("user not found", sl.Err(err))
+			return "", time.Time{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to find user", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// users of other apps look the same as missing ones
+	if err := a.userProvider.IsMember(ctx, user.ID, appName); err != nil {
+		if errors.Is(err, storage.ErrMemberNotFound) {
+			log.Warn("user isn't member of app", sl.Err(err))
+			return "", time.Time{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to check membership", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	switch user.Status {
+	case models.UserSuspended:
+		log.Warn("user is suspended")
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, ErrUserSuspended)
+	case models.UserPendingDeletion, models.UserDeleted:
+		log.Warn("user is deleted")
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, ErrUserDeleted)
+	}
+	targetRoles, err := a.roleProvider.GetRoles(ctx, user.ID, appName)
+	if err != nil {
+		log.Error("failed to get roles of user", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(targetRoles) > 0 {
+		log.Warn("user has a role", slog.Any("roles", targetRoles))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, ErrPrivilegedTarget)
+	}
+
+	app := caller.App
+	in := models.HookInput{AppName: appName, UserID: user.ID, Email: user.Email, IP: clientip.FromContext(ctx)}
+	in.Claims, err = a.claims(ctx, user, app)
+	if err != nil {
+		log.Error("failed to map claims", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	minted, err := a.runHook(ctx, models.HookTokenMint, in)
+	if err != nil {
+		log.Warn("token minting stopped by hook", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	expiresAt := time.Now().Add(min(a.impersonationTTL, app.Settings.Or(a.defaults).AccessTTL))
+	in.Claims["exp"] = expiresAt.Unix()
+	in.Claims["act"] = map[string]any{"sub": strconv.FormatUint(caller.UserID, 10)}
+	token, err := jwt.NewToken(app, in.Claims, minted.Claims)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("user impersonated", slog.Uint64("act", caller.UserID), slog.Uint64("uid", user.ID))
+	return token, expiresAt, nil
+}
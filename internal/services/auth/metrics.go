@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/neepooha/sso/internal/lib/metrics"
+)
+
+// failureReason returns the reason label of a failed login or registration,
+// the set is fixed so the label can't grow with error messages
+func failureReason(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrInvalidCredentials):
+		return "invalid_credentials"
+	case errors.Is(err, ErrUserExists):
+		return "user_exists"
+	case errors.Is(err, ErrUserSuspended):
+		return "user_suspended"
+	case errors.Is(err, ErrUserDeleted):
+		return "user_deleted"
+	case errors.Is(err, ErrHookDenied):
+		return "hook_denied"
+	case errors.Is(err, ErrHookFailed):
+		return "hook_failed"
+	case errors.Is(err, ErrClaimMapping):
+		return "claim_mapping"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "internal"
+}
+
+func observeLogin(appName string, err error) {
+	result, reason := metrics.Result(failureReason(err))
+	metrics.Logins.WithLabelValues(metrics.App(appName), result, reason).Inc()
+}
+
+func observeRegistration(appName string, err error) {
+	result, reason := metrics.Result(failureReason(err))
+	metrics.Registrations.WithLabelValues(metrics.App(appName), result, reason).Inc()
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/clientip"
+	"github.com/neepooha/sso/internal/lib/jwt"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+type SessionStorage interface {
+	CreateSession(ctx context.Context, session models.Session, maxSessions int) (uint64, error)
+	GetSession(ctx context.Context, refreshHash string) (models.Session, error)
+	RotateSession(ctx context.Context, id uint64, oldHash, newHash string, refreshExpiresAt time.Time) error
+	RevokeSession(ctx context.Context, id uint64) error
+	DeleteEndedSessions(ctx context.Context, before time.Time) (int64, error)
+}
+
+var (
+	ErrInvalidSession = errors.New("invalid refresh token")
+	ErrSessionExpired = errors.New("session expired")
+)
+
+// sessionRetention is how long ended sessions are kept before PurgeSessions removes them
+const sessionRetention = 24 * time.Hour
+
+// Refresh rotates the refresh token of the session and returns new tokens.
+// Presenting an already rotated refresh token revokes the whole session,
+// since either the client or an attacker holds a stolen copy
+func (a *Auth) Refresh(ctx context.Context, appName string, refreshToken string) (_ models.Tokens, err error) {
+	const op = "auth.Refresh"
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	entry := models.AuditEntry{Action: models.AuditRefresh, AppName: appName}
+	defer func() {
+		a.auditor.Record(ctx, entry.WithResult(err, ErrInvalidSession, ErrSessionExpired, ErrUserSuspended, ErrUserDeleted, ErrHookDenied, ErrHookFailed, ErrClaimMapping))
+	}()
+
+	log.Info("attempting to refresh session")
+	app, session, err := a.session(ctx, appName, refreshToken)
+	if err != nil {
+		log.Warn("cant find session", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+	entry.ActorID = session.UserID
+
+	if session.Reused {
+		log.Warn("rotated refresh token reused, revoking session", slog.Uint64("sid", session.ID))
+		if err := a.sessions.RevokeSession(ctx, session.ID); err != nil {
+			log.Error("failed to revoke session", sl.Err(err))
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+		}
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidSession)
+	}
+	settings := app.Settings.Or(a.defaults)
+	if err := checkSession(session, settings, time.Now()); err != nil {
+		log.Warn("session ended", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	user, err := a.userProvider.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		log.Error("failed to find user", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+	switch user.Status {
+	case models.UserSuspended:
+		log.Warn("user is suspended")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrUserSuspended)
+	case models.UserPendingDeletion, models.UserDeleted:
+		log.Warn("user is deleted")
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrUserDeleted)
+	}
+
+	in := models.HookInput{AppName: appName, UserID: user.ID, Email: user.Email, IP: clientip.FromContext(ctx)}
+	in.Claims, err = a.claims(ctx, user, app)
+	if err != nil {
+		log.Error("failed to map claims", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+	minted, err := a.runHook(ctx, models.HookTokenMint, in)
+	if err != nil {
+		log.Warn("token minting stopped by hook", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	refresh, hash := newRefreshToken()
+	err = a.sessions.RotateSession(ctx, session.ID, session.RefreshHash, hash, refreshExpiry(session, settings, time.Now()))
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			log.Warn("session rotated concurrently", sl.Err(err))
+			return models.Tokens{}, fmt.Errorf("%s: %w", op, ErrInvalidSession)
+		}
+		log.Error("failed to rotate session", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	access, err := sign(app, session, in.Claims, minted.Claims)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+		return models.Tokens{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("session refreshed")
+	return models.Tokens{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+// Logout revokes the session of the refresh token. Access tokens already
+// issued for it stay valid until they expire
+func (a *Auth) Logout(ctx context.Context, appName string, refreshToken string) (_ bool, err error) {
+	const op = "auth.Logout"
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	entry := models.AuditEntry{Action: models.AuditLogout, AppName: appName}
+	defer func() {
+		a.auditor.Record(ctx, entry.WithResult(err, ErrInvalidSession))
+	}()
+
+	log.Info("attempting to log out")
+	_, session, err := a.session(ctx, appName, refreshToken)
+	if err != nil {
+		log.Warn("cant find session", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	entry.ActorID = session.UserID
+
+	if err := a.sessions.RevokeSession(ctx, session.ID); err != nil {
+		log.Error("failed to revoke session", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("session revoked", slog.Uint64("sid", session.ID))
+	return true, nil
+}
+
+// PurgeSessions deletes sessions that ended more than a day ago, it runs as a background job
+func (a *Auth) PurgeSessions(ctx context.Context) error {
+	const op = "auth.PurgeSessions"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, a.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	n, err := a.sessions.DeleteEndedSessions(ctx, time.Now().Add(-sessionRetention))
+	if err != nil {
+		log.Error("failed to delete sessions", sl.Err(err))
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n > 0 {
+		log.Info("ended sessions deleted", slog.Int64("count", n))
+	}
+	return nil
+}
+
+// startSession saves a new session of the user and signs its first access token
+func (a *Auth) startSession(ctx context.Context, user models.User, app models.App, claims, extra map[string]any) (models.Tokens, error) {
+	settings := app.Settings.Or(a.defaults)
+	now := time.Now()
+
+	refresh, hash := newRefreshToken()
+	session := models.Session{
+		UserID:      user.ID,
+		AppID:       app.ID,
+		RefreshHash: hash,
+		IP:          clientip.FromContext(ctx),
+		CreatedAt:   now,
+	}
+	if settings.AbsoluteLifetime > 0 {
+		session.ExpiresAt = now.Add(settings.AbsoluteLifetime)
+	}
+	session.RefreshExpiresAt = refreshExpiry(session, settings, now)
+
+	id, err := a.sessions.CreateSession(ctx, session, settings.MaxSessions)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	session.ID = id
+
+	access, err := sign(app, session, claims, extra)
+	if err != nil {
+		return models.Tokens{}, err
+	}
+	return models.Tokens{AccessToken: access, RefreshToken: refresh}, nil
+}
+
+// session returns the app and the session of the refresh token, the session must belong to the app
+func (a *Auth) session(ctx context.Context, appName string, refreshToken string) (models.App, models.Session, error) {
+	app, err := a.appProvider.GetApp(ctx, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			return models.App{}, models.Session{}, ErrInvalidCredentials
+		}
+		return models.App{}, models.Session{}, err
+	}
+	session, err := a.sessions.GetSession(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			return models.App{}, models.Session{}, ErrInvalidSession
+		}
+		return models.App{}, models.Session{}, err
+	}
+	if session.AppID != app.ID {
+		return models.App{}, models.Session{}, ErrInvalidSession
+	}
+	return app, session, nil
+}
+
+// checkSession returns ErrInvalidSession for revoked sessions and
+// ErrSessionExpired when the refresh token, idle timeout or absolute lifetime ran out
+func checkSession(session models.Session, settings models.AppSettings, now time.Time) error {
+	if session.Revoked {
+		return ErrInvalidSession
+	}
+	if now.After(session.RefreshExpiresAt) {
+		return ErrSessionExpired
+	}
+	if !session.ExpiresAt.IsZero() && now.After(session.ExpiresAt) {
+		return ErrSessionExpired
+	}
+	if settings.IdleTimeout > 0 && now.After(session.LastUsedAt.Add(settings.IdleTimeout)) {
+		return ErrSessionExpired
+	}
+	return nil
+}
+
+// refreshExpiry is when a refresh token issued now expires, never after the session itself
+func refreshExpiry(session models.Session, settings models.AppSettings, now time.Time) time.Time {
+	expiry := now.Add(settings.RefreshTTL)
+	if !session.ExpiresAt.IsZero() && session.ExpiresAt.Before(expiry) {
+		expiry = session.ExpiresAt
+	}
+	return expiry
+}
+
+// sign adds the session id to the claims and signs them. The access token
+// never outlives the absolute lifetime of its session
+func sign(app models.App, session models.Session, claims, extra map[string]any) (string, error) {
+	claims["sid"] = strconv.FormatUint(session.ID, 10)
+	if exp, ok := claims["exp"].(int64); ok && !session.ExpiresAt.IsZero() && session.ExpiresAt.Unix() < exp {
+		claims["exp"] = session.ExpiresAt.Unix()
+	}
+	return jwt.NewToken(app, claims, extra)
+}
+
+// newRefreshToken returns an opaque refresh token and the hash stored instead of it
+func newRefreshToken() (string, string) {
+	b := make([]byte, 32)
+	// crypto/rand never fails on supported platforms
+	_, _ = rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+const batchSize = 100
+
+type Events struct {
+	log          *slog.Logger
+	eventStorage EventStorage
+	pollInterval time.Duration
+
+	mu      sync.Mutex
+	waiters map[chan struct{}]struct{}
+}
+
+type EventStorage interface {
+	ListEvents(ctx context.Context, appID int, afterID uint64, types []string, limit int) ([]models.Event, error)
+	LastEventID(ctx context.Context) (uint64, error)
+	ListenEvents(ctx context.Context, notify func()) error
+}
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNotAdmin           = errors.New("user isn't admin")
+	ErrInvalidEventType   = errors.New("invalid event type")
+	ErrAppDeleted         = errors.New("app deleted")
+)
+
+// New returns a new instanse of the Events service. Watchers are woken up by
+// Listen on every committed event and poll the storage every pollInterval in case
+// a notification was lost
+func New(log *slog.Logger, eventStorage EventStorage, pollInterval time.Duration) *Events {
+	return &Events{
+		log:          log,
+		eventStorage: eventStorage,
+		pollInterval: pollInterval,
+		waiters:      make(map[chan struct{}]struct{}),
+	}
+}
+
+// Watch sends events of the app and user events to send, starting after afterID.
+// Zero afterID means only new events. Caller must be admin of the app.
+// Watch returns when ctx is done, send fails, the app is deleted or the caller
+// loses admin rights
+func (e *Events) Watch(ctx context.Context, appName string, types []string, afterID uint64, send func(models.Event) error) error {
+	const op = "events.Watch"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, e.log).With(slog.String("op", op), tracing.LogAttr(ctx), slog.String("app", appName))
+
+	for _, t := range types {
+		if !slices.Contains(models.EventTypes, t) {
+			log.Warn("invalid event type", slog.String("type", t))
+			return fmt.Errorf("%s: %w", op, ErrInvalidEventType)
+		}
+	}
+
+	caller, _ := principal.FromContext(ctx)
+	callerID, app := caller.UserID, caller.App
+
+	if afterID == 0 {
+		var err error
+		afterID, err = e.eventStorage.LastEventID(ctx)
+		if err != nil {
+			log.Error("failed to get last event", sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	wake := e.subscribe()
+	defer e.unsubscribe(wake)
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+
+	log.Info("watching events", slog.Uint64("after_id", afterID))
+	for {
+		events, err := e.eventStorage.ListEvents(ctx, app.ID, afterID, types, batchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Error("failed to list events", sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, event := range events {
+			if err := send(event); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			afterID = event.ID
+			if err := closes(event, app.ID, callerID); err != nil {
+				log.Info("stopping watch", sl.Err(err))
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if len(events) == batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// closes reports whether the event ends the watch of the caller
+func closes(event models.Event, appID int, callerID uint64) error {
+	switch event.Type {
+	case models.EventAppDeleted:
+		if event.AppID == appID {
+			return ErrAppDeleted
+		}
+	case models.EventAdminRevoked:
+		var p struct {
+			UserID uint64 `json:"user_id"`
+		}
+		if err := json.Unmarshal(event.Payload, &p); err == nil && event.AppID == appID && p.UserID == callerID {
+			return ErrNotAdmin
+		}
+	}
+	return nil
+}
+
+// Listen wakes up watchers on every committed event. It is run as a background
+// job, so it is restarted after a failure
+func (e *Events) Listen(ctx context.Context) error {
+	const op = "events.Listen"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	if err := e.eventStorage.ListenEvents(ctx, e.notify); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (e *Events) notify() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for w := range e.waiters {
+		select {
+		case w <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *Events) subscribe() chan struct{} {
+	w := make(chan struct{}, 1)
+	e.mu.Lock()
+	e.waiters[w] = struct{}{}
+	e.mu.Unlock()
+	return w
+}
+
+func (e *Events) unsubscribe(w chan struct{}) {
+	e.mu.Lock()
+	delete(e.waiters, w)
+	e.mu.Unlock()
+}
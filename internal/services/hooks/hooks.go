@@ -0,0 +1,288 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/jsonstruct"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/netguard"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	ssov2 "github.com/neepooha/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// maxResponse limits the body read from HTTP hooks
+const maxResponse = 1 << 20
+
+type Hooks struct {
+	log            *slog.Logger
+	hookProvider   HookProvider
+	client         *http.Client
+	dialer         *net.Dialer
+	defaultTimeout time.Duration
+	// grpcInsecure dials gRPC hooks without TLS
+	grpcInsecure bool
+	// connIdleTimeout is how long a connection to a gRPC hook service stays open unused
+	connIdleTimeout time.Duration
+
+	mu    sync.Mutex
+	conns map[string]*hookConn
+}
+
+// hookConn is a shared connection to a gRPC hook service
+type hookConn struct {
+	conn *grpc.ClientConn
+	// active counts calls using the connection
+	active   int
+	lastUsed time.Time
+}
+
+type HookProvider interface {
+	ListHooks(ctx context.Context, appName string, stage string) ([]models.Hook, error)
+	HasHooks(ctx context.Context, stage string) (bool, error)
+}
+
+var ErrHookFailed = errors.New("hook failed")
+
+// New returns a new instanse of the Hooks service. defaultTimeout is used for
+// hooks configured without a timeout. Hook services are reached only through
+// the guard, gRPC ones over TLS unless grpcInsecure is set. Connections to them
+// unused for connIdleTimeout are closed by CloseIdle
+func New(log *slog.Logger, hookProvider HookProvider, defaultTimeout time.Duration, guard *netguard.Guard, grpcInsecure bool, connIdleTimeout time.Duration) *Hooks {
+	return &Hooks{
+		log:             log,
+		hookProvider:    hookProvider,
+		client:          guard.HTTPClient(0),
+		dialer:          guard.Dialer(),
+		defaultTimeout:  defaultTimeout,
+		grpcInsecure:    grpcInsecure,
+		connIdleTimeout: connIdleTimeout,
+		conns:           make(map[string]*hookConn),
+	}
+}
+
+// Run calls hooks of the app for the stage of the input in order. It stops at the
+// first hook that denies and returns its result. Claims of all hooks are merged,
+// later hooks win. A failed hook is skipped if it fails open, otherwise Run
+// returns ErrHookFailed
+func (h *Hooks) Run(ctx context.Context, in models.HookInput) (models.HookResult, error) {
+	const op = "hooks.Run"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, h.log).With(slog.String("op", op), tracing.LogAttr(ctx), slog.String("app", in.AppName), slog.String("stage", in.Stage))
+
+	hooks, err := h.hookProvider.ListHooks(ctx, in.AppName, in.Stage)
+	if err != nil {
+		log.Error("failed to list hooks", sl.Err(err))
+		return models.HookResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var result models.HookResult
+	for _, hook := range hooks {
+		res, err := h.call(ctx, hook, in)
+		if err != nil {
+			if hook.FailOpen {
+				log.Warn("hook failed, skipping", slog.Int("hook_id", hook.ID), sl.Err(err))
+				continue
+			}
+			log.Error("hook failed", slog.Int("hook_id", hook.ID), sl.Err(err))
+			return models.HookResult{}, fmt.Errorf("%s: %w", op, ErrHookFailed)
+		}
+		if res.Deny {
+			log.Info("denied by hook", slog.Int("hook_id", hook.ID), slog.String("reason", res.Reason))
+			return res, nil
+		}
+		for k, v := range res.Claims {
+			if result.Claims == nil {
+				result.Claims = make(map[string]any)
+			}
+			result.Claims[k] = v
+		}
+	}
+	return result, nil
+}
+
+// Configured reports whether any app has hooks of the stage
+func (h *Hooks) Configured(ctx context.Context, stage string) (bool, error) {
+	const op = "hooks.Configured"
+
+	has, err := h.hookProvider.HasHooks(ctx, stage)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return has, nil
+}
+
+// Close closes connections to gRPC hook services
+func (h *Hooks) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for target, c := range h.conns {
+		c.conn.Close()
+		delete(h.conns, target)
+	}
+}
+
+// CloseIdle closes connections to gRPC hook services that no call used for the
+// idle timeout. Hooks don't tell this instance when they are removed or their
+// target changes, so connections to old targets are dropped here. It is run as a background job
+func (h *Hooks) CloseIdle(ctx context.Context) error {
+	const op = "hooks.CloseIdle"
+	log := h.log.With(slog.String("op", op))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for target, c := range h.conns {
+		if c.active > 0 || time.Since(c.lastUsed) < h.connIdleTimeout {
+			continue
+		}
+		if err := c.conn.Close(); err != nil {
+			log.Warn("failed to close hook connection", slog.String("target", target), sl.Err(err))
+		}
+		delete(h.conns, target)
+	}
+	return nil
+}
+
+func (h *Hooks) call(ctx context.Context, hook models.Hook, in models.HookInput) (models.HookResult, error) {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = h.defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := hookRequest(in)
+	if err != nil {
+		return models.HookResult{}, err
+	}
+
+	var resp *ssov2.HookResponse
+	switch hook.Kind {
+	case models.HookHTTP:
+		resp, err = h.callHTTP(ctx, hook.Target, req)
+	case models.HookGRPC:
+		resp, err = h.callGRPC(ctx, hook.Target, req)
+	default:
+		err = fmt.Errorf("unknown hook kind %q", hook.Kind)
+	}
+	if err != nil {
+		return models.HookResult{}, err
+	}
+	return models.HookResult{
+		Deny:   resp.GetDeny(),
+		Reason: resp.GetReason(),
+		Claims: resp.GetClaims().AsMap(),
+	}, nil
+}
+
+func (h *Hooks) callHTTP(ctx context.Context, url string, req *ssov2.HookRequest) (*ssov2.HookResponse, error) {
+	body, err := protojson.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := h.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %d", httpResp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponse))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp ssov2.HookResponse
+	if len(data) > 0 {
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, &resp); err != nil {
+			return nil, err
+		}
+	}
+	return &resp, nil
+}
+
+func (h *Hooks) callGRPC(ctx context.Context, target string, req *ssov2.HookRequest) (*ssov2.HookResponse, error) {
+	c, err := h.acquire(target)
+	if err != nil {
+		return nil, err
+	}
+	defer h.release(c)
+	return ssov2.NewHookServiceClient(c.conn).Run(ctx, req)
+}
+
+// acquire returns a shared connection to the gRPC hook service, CloseIdle
+// keeps it open until the call releases it
+func (h *Hooks) acquire(target string) (*hookConn, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if c, ok := h.conns[target]; ok {
+		c.active++
+		return c, nil
+	}
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if h.grpcInsecure {
+		creds = insecure.NewCredentials()
+	}
+	dial := func(ctx context.Context, addr string) (net.Conn, error) {
+		return h.dialer.DialContext(ctx, "tcp", addr)
+	}
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds), grpc.WithContextDialer(dial))
+	if err != nil {
+		return nil, err
+	}
+	c := &hookConn{conn: conn, active: 1}
+	h.conns[target] = c
+	return c, nil
+}
+
+func (h *Hooks) release(c *hookConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c.active--
+	c.lastUsed = time.Now()
+}
+
+func hookRequest(in models.HookInput) (*ssov2.HookRequest, error) {
+	req := &ssov2.HookRequest{
+		Stage:   in.Stage,
+		AppName: in.AppName,
+		UserId:  in.UserID,
+		Email:   in.Email,
+		Ip:      in.IP,
+	}
+	if in.Claims != nil {
+		// mapped claims may hold types structpb doesn't take, like []string
+		data, err := json.Marshal(in.Claims)
+		if err != nil {
+			return nil, err
+		}
+		if req.Claims, err = jsonstruct.FromJSON(data); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
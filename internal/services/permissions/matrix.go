@@ -0,0 +1,46 @@
+package perm
+
+import (
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"slices"
+)
+
+// Matrix states which roles each role may grant and revoke, e.g. "admin": ["admin"]
+type Matrix map[string][]string
+
+// DefaultMatrix lets creators manage every role, and admins manage admins
+var DefaultMatrix = Matrix{
+	models.RoleCreator: {models.RoleCreator, models.RoleAdmin, models.RoleSupport},
+	models.RoleAdmin:   {models.RoleAdmin},
+}
+
+// Validate checks that the matrix uses known roles and never lets a role grant above its own level
+func (m Matrix) Validate() error {
+	for granter, roles := range m {
+		level, ok := models.RoleLevels[granter]
+		if !ok {
+			return fmt.Errorf("unknown role %q", granter)
+		}
+		for _, role := range roles {
+			l, ok := models.RoleLevels[role]
+			if !ok {
+				return fmt.Errorf("unknown role %q", role)
+			}
+			if l > level {
+				return fmt.Errorf("role %q can't manage higher role %q", granter, role)
+			}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether any of the roles may manage the role
+func (m Matrix) Allows(roles []string, role string) bool {
+	for _, r := range roles {
+		if slices.Contains(m[r], role) {
+			return true
+		}
+	}
+	return false
+}
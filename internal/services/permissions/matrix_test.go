@@ -0,0 +1,144 @@
+package perm_test
+
+import (
+	"context"
+	"errors"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/principal"
+	perm "github.com/neepooha/sso/internal/services/permissions"
+	"github.com/neepooha/sso/internal/storage/memory"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		matrix  perm.Matrix
+		wantErr bool
+	}{
+		{name: "default", matrix: perm.DefaultMatrix},
+		{name: "empty", matrix: perm.Matrix{}},
+		{name: "admin manages support", matrix: perm.Matrix{models.RoleAdmin: {models.RoleAdmin, models.RoleSupport}}},
+		{name: "support manages admin of the same level", matrix: perm.Matrix{models.RoleSupport: {models.RoleAdmin}}},
+		{name: "admin grants creator", matrix: perm.Matrix{models.RoleAdmin: {models.RoleCreator}}, wantErr: true},
+		{name: "support grants creator", matrix: perm.Matrix{models.RoleSupport: {models.RoleCreator}}, wantErr: true},
+		{name: "unknown granter", matrix: perm.Matrix{"owner": {models.RoleAdmin}}, wantErr: true},
+		{name: "unknown role", matrix: perm.Matrix{models.RoleCreator: {"owner"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.matrix.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		name  string
+		roles []string
+		role  string
+		want  bool
+	}{
+		{name: "creator grants creator", roles: []string{models.RoleCreator}, role: models.RoleCreator, want: true},
+		{name: "creator grants support", roles: []string{models.RoleCreator}, role: models.RoleSupport, want: true},
+		{name: "admin grants admin", roles: []string{models.RoleAdmin}, role: models.RoleAdmin, want: true},
+		{name: "admin grants creator", roles: []string{models.RoleAdmin}, role: models.RoleCreator},
+		{name: "admin grants support", roles: []string{models.RoleAdmin}, role: models.RoleSupport},
+		{name: "support grants support", roles: []string{models.RoleSupport}, role: models.RoleSupport},
+		{name: "any of the roles", roles: []string{models.RoleSupport, models.RoleAdmin}, role: models.RoleAdmin, want: true},
+		{name: "no roles", roles: nil, role: models.RoleAdmin},
+		{name: "unknown role", roles: []string{models.RoleCreator}, role: "owner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := perm.DefaultMatrix.Allows(tt.roles, tt.role); got != tt.want {
+				t.Errorf("Allows(%v, %q) = %v, want %v", tt.roles, tt.role, got, tt.want)
+			}
+		})
+	}
+}
+
+const appName = "app"
+
+// newApp returns a storage with an app where each user by email holds the role of its name
+func newApp(t *testing.T) (*memory.Storage, map[string]uint64) {
+	t.Helper()
+	ctx := context.Background()
+	s := memory.New()
+	appID, err := s.SetApp(ctx, appName, "secret")
+	if err != nil {
+		t.Fatalf("SetApp: %v", err)
+	}
+	users := map[string]uint64{}
+	for _, email := range []string{"creator@example.com", "admin@example.com", "support@example.com", "user@example.com"} {
+		id, err := s.SaveUser(ctx, email, []byte("hash"), appID)
+		if err != nil {
+			t.Fatalf("SaveUser: %v", err)
+		}
+		users[email] = id
+	}
+	if err := s.SetCreator(ctx, users["creator@example.com"], appID); err != nil {
+		t.Fatalf("SetCreator: %v", err)
+	}
+	for email, role := range map[string]string{"admin@example.com": models.RoleAdmin, "support@example.com": models.RoleSupport} {
+		if err := s.GrantRole(ctx, email, appName, role); err != nil {
+			t.Fatalf("GrantRole: %v", err)
+		}
+	}
+	return s, users
+}
+
+type discardAuditor struct{}
+
+func (discardAuditor) Record(ctx context.Context, entry models.AuditEntry) {}
+
+// TestEscalation checks that nobody gains or takes a role above their own, even
+// with a matrix that skipped Validate
+func TestEscalation(t *testing.T) {
+	// unsafe lets admins manage every role, Validate would refuse it
+	unsafe := perm.Matrix{models.RoleAdmin: {models.RoleCreator, models.RoleAdmin, models.RoleSupport}}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name    string
+		matrix  perm.Matrix
+		caller  string
+		revoke  bool
+		target  string
+		role    string
+		wantErr error
+	}{
+		{name: "creator grants admin", matrix: perm.DefaultMatrix, caller: "creator@example.com", target: "user@example.com", role: models.RoleAdmin},
+		{name: "admin grants admin", matrix: perm.DefaultMatrix, caller: "admin@example.com", target: "user@example.com", role: models.RoleAdmin},
+		{name: "admin grants creator", matrix: perm.DefaultMatrix, caller: "admin@example.com", target: "user@example.com", role: models.RoleCreator, wantErr: perm.ErrNotAllowed},
+		{name: "admin grants support", matrix: perm.DefaultMatrix, caller: "admin@example.com", target: "user@example.com", role: models.RoleSupport, wantErr: perm.ErrNotAllowed},
+		{name: "support grants support", matrix: perm.DefaultMatrix, caller: "support@example.com", target: "user@example.com", role: models.RoleSupport, wantErr: perm.ErrNotAllowed},
+		{name: "user without roles grants admin", matrix: perm.DefaultMatrix, caller: "user@example.com", target: "user@example.com", role: models.RoleAdmin, wantErr: perm.ErrNotAllowed},
+		{name: "unknown role", matrix: perm.DefaultMatrix, caller: "creator@example.com", target: "user@example.com", role: "owner", wantErr: perm.ErrUnknownRole},
+		{name: "unsafe matrix: admin grants creator", matrix: unsafe, caller: "admin@example.com", target: "admin@example.com", role: models.RoleCreator, wantErr: perm.ErrEscalation},
+		{name: "admin revokes admin of a creator", matrix: perm.DefaultMatrix, caller: "admin@example.com", revoke: true, target: "creator@example.com", role: models.RoleAdmin, wantErr: perm.ErrEscalation},
+		{name: "unsafe matrix: admin revokes creator", matrix: unsafe, caller: "admin@example.com", revoke: true, target: "creator@example.com", role: models.RoleCreator, wantErr: perm.ErrEscalation},
+		{name: "creator revokes support", matrix: perm.DefaultMatrix, caller: "creator@example.com", revoke: true, target: "support@example.com", role: models.RoleSupport},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, users := newApp(t)
+			p := perm.New(log, s, s, s, discardAuditor{}, tt.matrix)
+			ctx := principal.NewContext(context.Background(), principal.Principal{UserID: users[tt.caller]})
+
+			var err error
+			if tt.revoke {
+				_, err = p.RevokeRole(ctx, appName, tt.target, tt.role)
+			} else {
+				_, err = p.GrantRole(ctx, appName, tt.target, tt.role)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
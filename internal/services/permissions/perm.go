@@ -6,134 +6,213 @@ import (
 	"fmt"
 	"github.com/neepooha/sso/internal/domain/models"
 	"github.com/neepooha/sso/internal/lib/logger/sl"
-	"github.com/neepooha/sso/internal/lib/logging"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/tracing"
 	"github.com/neepooha/sso/internal/storage"
 	"log/slog"
 )
 
 type Permissions struct {
-	log                *slog.Logger
-	adminSetterDeleter AdminSetterDeleter
-	appProvider        AppProvider
+	log          *slog.Logger
+	roleStorage  RoleStorage
+	userProvider UserProvider
+	appProvider  AppProvider
+	auditor      Auditor
+	matrix       Matrix
 }
 
-type AdminSetterDeleter interface {
-	SetAdmin(ctx context.Context, email string, appName string) error
-	DelAdmin(ctx context.Context, email string, appName string) error
+type RoleStorage interface {
+	GrantRole(ctx context.Context, email string, appName string, role string) error
+	RevokeRole(ctx context.Context, email string, appName string, role string) error
+	GetRoles(ctx context.Context, userID uint64, appName string) ([]string, error)
 	IsAdmin(ctx context.Context, userID uint64, appName string) error
 	IsCreator(ctx context.Context, userID uint64, appName string) error
+	ListAdmins(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, error)
+	ListCreators(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, error)
+}
+
+type UserProvider interface {
+	GetUser(ctx context.Context, email string) (models.User, error)
 }
 
 type AppProvider interface {
 	GetApp(ctx context.Context, appName string) (models.App, error)
 }
 
+type Auditor interface {
+	Record(ctx context.Context, entry models.AuditEntry)
+}
+
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserNotFound       = errors.New("user not found")
-	ErrNotCreator         = errors.New("ErrNotCreator")
+	ErrNotCreator         = errors.New("user isn't creator")
 	ErrAdminExists        = errors.New("user already admin")
 	ErrAdminNotFound      = errors.New("admin not found")
+	ErrUnknownRole        = errors.New("unknown role")
+	ErrNotAllowed         = errors.New("role can't be managed by the caller")
+	ErrEscalation         = errors.New("role is above the caller's own")
+	ErrLastOwner          = errors.New("app must keep at least one creator")
 )
 
-// New returns a new instanse of the Permissions service
-func New(log *slog.Logger, adminSetterDeleter AdminSetterDeleter, appProvider AppProvider) *Permissions {
+// New returns a new instanse of the Permissions service, an empty matrix means DefaultMatrix
+func New(log *slog.Logger, roleStorage RoleStorage, userProvider UserProvider, appProvider AppProvider, auditor Auditor, matrix Matrix) *Permissions {
+	if len(matrix) == 0 {
+		matrix = DefaultMatrix
+	}
 	return &Permissions{
-		log:                log,
-		adminSetterDeleter: adminSetterDeleter,
-		appProvider:        appProvider,
+		log:          log,
+		roleStorage:  roleStorage,
+		userProvider: userProvider,
+		appProvider:  appProvider,
+		auditor:      auditor,
+		matrix:       matrix,
 	}
 }
 
+// SetAdmin makes the user with the email admin of the app, see GrantRole
 func (p *Permissions) SetAdmin(ctx context.Context, email string, appName string) (bool, error) {
-	const op = "perm.SetAdmin"
-	log := p.log.With(slog.String("op", op))
+	ctx, span := tracing.Start(ctx, "perm.SetAdmin")
+	defer span.End()
 
-	log.Info("attempting to log in")
-	err := logging.Logging(ctx, appName, p.adminSetterDeleter, p.appProvider)
-	if err != nil {
-		if errors.Is(err, logging.ErrCreatorNotFound) {
-			log.Warn("user not creator", sl.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrNotCreator)
-		}
-		if errors.Is(err, logging.ErrInvalidCredentials) {
-			log.Warn("cant get info of user", sl.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-		}
-		log.Warn("error logging", sl.Err(err))
+	return p.GrantRole(ctx, appName, email, models.RoleAdmin)
+}
+
+// DelAdmin takes the admin role of the app from the user with the email, see RevokeRole
+func (p *Permissions) DelAdmin(ctx context.Context, email string, appName string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "perm.DelAdmin")
+	defer span.End()
+
+	return p.RevokeRole(ctx, appName, email, models.RoleAdmin)
+}
+
+// GrantRole gives the role in the app to the user with the email. The matrix must allow
+// one of the caller's roles to grant it, and nobody can grant a role above their own
+func (p *Permissions) GrantRole(ctx context.Context, appName string, email string, role string) (_ bool, err error) {
+	const op = "perm.GrantRole"
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, p.log).With(slog.String("op", op), tracing.LogAttr(ctx), slog.String("role", role))
+
+	entry := models.AuditEntry{Action: "set_" + role, TargetEmail: email, AppName: appName}
+	defer func() {
+		p.auditor.Record(ctx, entry.WithResult(err, ErrInvalidCredentials, ErrUnknownRole, ErrNotAllowed, ErrEscalation))
+	}()
+
+	entry.ActorID = principal.UserID(ctx)
+	if err := p.canManage(ctx, appName, entry.ActorID, role, ""); err != nil {
+		log.Warn("caller can't grant role", sl.Err(err))
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Info("attempting to set admin")
-	err = p.adminSetterDeleter.SetAdmin(ctx, email, appName)
+	log.Info("attempting to grant role")
+	err = p.roleStorage.GrantRole(ctx, email, appName, role)
 	if err != nil {
-		if errors.Is(err, storage.ErrAdminExists) {
-			log.Warn("user already admin", sl.Err(err))
+		if errors.Is(err, storage.ErrRoleExists) {
+			log.Warn("user already has the role", sl.Err(err))
 			return true, nil
 		}
-		if errors.Is(err, storage.ErrUserNotFound) {
-			log.Warn("user not found", sl.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-		}
-		if errors.Is(err, storage.ErrAppNotFound) {
-			log.Warn("app not found", sl.Err(err))
+		if errors.Is(err, storage.ErrUserNotFound) || errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("user or app not found", sl.Err(err))
 			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 		}
-		log.Error("failed to set admin", sl.Err(err))
+		log.Error("failed to grant role", sl.Err(err))
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
-	log.Info("user is admin now")
+	log.Info("role granted")
 	return true, nil
 }
 
-func (p *Permissions) DelAdmin(ctx context.Context, email string, appName string) (bool, error) {
-	const op = "perm.DelAdmin"
-	log := p.log.With(slog.String("op", op))
+// RevokeRole takes the role in the app from the user with the email. Besides the
+// matrix, the target must not rank above the caller, and the last creator stays
+func (p *Permissions) RevokeRole(ctx context.Context, appName string, email string, role string) (_ bool, err error) {
+	const op = "perm.RevokeRole"
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, p.log).With(slog.String("op", op), tracing.LogAttr(ctx), slog.String("role", role))
 
-	log.Info("attempting to log in")
-	err := logging.Logging(ctx, appName, p.adminSetterDeleter, p.appProvider)
-	if err != nil {
-		if errors.Is(err, logging.ErrCreatorNotFound) {
-			log.Warn("user not creator", sl.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrNotCreator)
-		}
-		if errors.Is(err, logging.ErrInvalidCredentials) {
-			log.Warn("cant get info of user", sl.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
-		}
-		log.Warn("error logging", sl.Err(err))
+	entry := models.AuditEntry{Action: "del_" + role, TargetEmail: email, AppName: appName}
+	defer func() {
+		p.auditor.Record(ctx, entry.WithResult(err, ErrInvalidCredentials, ErrUnknownRole, ErrNotAllowed, ErrEscalation, ErrLastOwner))
+	}()
+
+	entry.ActorID = principal.UserID(ctx)
+	if err := p.canManage(ctx, appName, entry.ActorID, role, email); err != nil {
+		log.Warn("caller can't revoke role", sl.Err(err))
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	log.Info("attempting to delete admin")
-	err = p.adminSetterDeleter.DelAdmin(ctx, email, appName)
+	log.Info("attempting to revoke role")
+	err = p.roleStorage.RevokeRole(ctx, email, appName, role)
 	if err != nil {
-		if errors.Is(err, storage.ErrAdminNotFound) {
-			log.Warn("user already not admin", sl.Err(err))
+		if errors.Is(err, storage.ErrRoleNotFound) {
+			log.Warn("user already doesn't have the role", sl.Err(err))
 			return true, nil
 		}
-		if errors.Is(err, storage.ErrUserNotFound) {
-			log.Warn("user not found", sl.Err(err))
-			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		if errors.Is(err, storage.ErrLastCreator) {
+			log.Warn("last creator can't be removed", sl.Err(err))
+			return false, fmt.Errorf("%s: %w", op, ErrLastOwner)
 		}
-		if errors.Is(err, storage.ErrAppNotFound) {
-			log.Warn("app not found", sl.Err(err))
+		if errors.Is(err, storage.ErrUserNotFound) || errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("user or app not found", sl.Err(err))
 			return false, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
 		}
-		log.Error("failed to delete admin", sl.Err(err))
+		log.Error("failed to revoke role", sl.Err(err))
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
-	log.Info("user is not admin now")
+	log.Info("role revoked")
 	return true, nil
 }
 
+// canManage checks that the caller may grant or revoke the role. When target is
+// set, the roles of that user must not rank above the caller's
+func (p *Permissions) canManage(ctx context.Context, appName string, callerID uint64, role string, target string) error {
+	level, ok := models.RoleLevels[role]
+	if !ok {
+		return ErrUnknownRole
+	}
+	roles, err := p.roleStorage.GetRoles(ctx, callerID, appName)
+	if err != nil {
+		return err
+	}
+	if !p.matrix.Allows(roles, role) {
+		return ErrNotAllowed
+	}
+	callerLevel := models.MaxRoleLevel(roles)
+	if level > callerLevel {
+		return ErrEscalation
+	}
+	if target == "" {
+		return nil
+	}
+
+	user, err := p.userProvider.GetUser(ctx, target)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+	targetRoles, err := p.roleStorage.GetRoles(ctx, user.ID, appName)
+	if err != nil {
+		return err
+	}
+	if models.MaxRoleLevel(targetRoles) > callerLevel {
+		return ErrEscalation
+	}
+	return nil
+}
+
 // IsAdmin checks if user is admin
 func (p *Permissions) IsAdmin(ctx context.Context, userID uint64, appName string) (bool, error) {
 	const op = "perm.IsAdmin"
-	log := p.log.With(slog.String("op", op))
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, p.log).With(slog.String("op", op), tracing.LogAttr(ctx))
 
 	log.Info("checking if user is admin")
-	err := p.adminSetterDeleter.IsAdmin(ctx, userID, appName)
+	err := p.roleStorage.IsAdmin(ctx, userID, appName)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Warn("user not found", sl.Err(err))
@@ -157,7 +236,9 @@ func (p *Permissions) IsAdmin(ctx context.Context, userID uint64, appName string
 
 func (p *Permissions) IsCreator(ctx context.Context, userID uint64, appName string) (bool, error) {
 	const op = "perm.IsCreator"
-	log := p.log.With(slog.String("op", op))
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, p.log).With(slog.String("op", op), tracing.LogAttr(ctx))
 
 	log.Info("checking if user is creator")
 	_, err := p.appProvider.GetApp(ctx, appName)
@@ -170,7 +251,7 @@ func (p *Permissions) IsCreator(ctx context.Context, userID uint64, appName stri
 		return false, fmt.Errorf("%s:%w", op, err)
 	}
 
-	err = p.adminSetterDeleter.IsCreator(ctx, userID, appName)
+	err = p.roleStorage.IsCreator(ctx, userID, appName)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Warn("user not found", sl.Err(err))
@@ -187,3 +268,42 @@ func (p *Permissions) IsCreator(ctx context.Context, userID uint64, appName stri
 	log.Info("checked if user is creator", slog.Bool("is_creator", true))
 	return true, nil
 }
+
+// ListAdmins returns one page of admins of the app. Caller must be creator of the app
+func (p *Permissions) ListAdmins(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, string, error) {
+	const op = "perm.ListAdmins"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	return p.listMembers(ctx, op, appName, filter, p.roleStorage.ListAdmins)
+}
+
+// ListCreators returns one page of creators of the app. Caller must be creator of the app
+func (p *Permissions) ListCreators(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, string, error) {
+	const op = "perm.ListCreators"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	return p.listMembers(ctx, op, appName, filter, p.roleStorage.ListCreators)
+}
+
+type memberLister func(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, error)
+
+func (p *Permissions) listMembers(ctx context.Context, op string, appName string, filter models.ListFilter, list memberLister) ([]models.Member, string, error) {
+	log := sl.FromContext(ctx, p.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	log.Info("listing members")
+	limit := filter.Limit
+	filter.Limit++
+	members, err := list(ctx, appName, filter)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return nil, "", fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to list members", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	members, next := pagination.NextToken(members, limit, func(m models.Member) uint64 { return m.UserID })
+	return members, next, nil
+}
@@ -0,0 +1,212 @@
+package profiles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/jsonschema"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+)
+
+type Profiles struct {
+	log             *slog.Logger
+	profileProvider ProfileProvider
+	adminProvider   AdminProvider
+	memberProvider  MemberProvider
+}
+
+type ProfileProvider interface {
+	GetProfile(ctx context.Context, userID uint64, appName string) (models.Profile, error)
+	UpdUser(ctx context.Context, userID uint64, upd models.ProfileUpdate) error
+	SetUserMetadata(ctx context.Context, userID uint64, appID int, data []byte) error
+	SetAppMetadata(ctx context.Context, userID uint64, appID int, data []byte) error
+	GetMetadataSchemas(ctx context.Context, appName string) (models.MetadataSchemas, error)
+}
+
+type AdminProvider interface {
+	IsAdmin(ctx context.Context, userID uint64, appName string) error
+}
+
+type MemberProvider interface {
+	IsMember(ctx context.Context, userID uint64, appName string) error
+}
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNotAdmin           = errors.New("user isn't admin")
+	ErrInvalidMetadata    = errors.New("metadata doesn't match schema")
+)
+
+// New returns a new instanse of the Profiles service
+func New(log *slog.Logger, profileProvider ProfileProvider, adminProvider AdminProvider, memberProvider MemberProvider) *Profiles {
+	return &Profiles{
+		log:             log,
+		profileProvider: profileProvider,
+		adminProvider:   adminProvider,
+		memberProvider:  memberProvider,
+	}
+}
+
+// GetProfile returns the profile of the caller or, for app admins, of any user of the app
+func (p *Profiles) GetProfile(ctx context.Context, appName string, userID uint64) (models.Profile, error) {
+	const op = "profiles.GetProfile"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, p.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	callerID := principal.UserID(ctx)
+	if userID == 0 {
+		userID = callerID
+	}
+	if userID != callerID {
+		if err := p.requireAdmin(ctx, callerID, appName); err != nil {
+			log.Warn("user not admin", sl.Err(err))
+			return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := p.requireMember(ctx, userID, appName); err != nil {
+			log.Warn("user not member of app", sl.Err(err))
+			return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	log.Info("attempting to get profile")
+	profile, err := p.getProfile(ctx, userID, appName)
+	if err != nil {
+		log.Warn("failed to get profile", sl.Err(err))
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return profile, nil
+}
+
+// UpdateProfile updates profile fields and user_metadata of the caller
+func (p *Profiles) UpdateProfile(ctx context.Context, appName string, upd models.ProfileUpdate, userMetadata []byte) (models.Profile, error) {
+	const op = "profiles.UpdateProfile"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, p.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	userID := principal.UserID(ctx)
+
+	profile, err := p.getProfile(ctx, userID, appName)
+	if err != nil {
+		log.Warn("failed to get profile", sl.Err(err))
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if userMetadata != nil {
+		schemas, err := p.profileProvider.GetMetadataSchemas(ctx, appName)
+		if err != nil {
+			log.Error("failed to get metadata schema", sl.Err(err))
+			return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if err := jsonschema.Validate(schemas.UserMetadata, userMetadata); err != nil {
+			log.Warn("invalid user metadata", sl.Err(err))
+			return models.Profile{}, fmt.Errorf("%s: %w: %s", op, ErrInvalidMetadata, err)
+		}
+	}
+
+	log.Info("attempting to update profile")
+	if err := p.profileProvider.UpdUser(ctx, userID, upd); err != nil {
+		log.Error("failed to update user", sl.Err(err))
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if userMetadata != nil {
+		if err := p.profileProvider.SetUserMetadata(ctx, userID, profile.AppID, userMetadata); err != nil {
+			log.Error("failed to set user metadata", sl.Err(err))
+			return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	profile, err = p.getProfile(ctx, userID, appName)
+	if err != nil {
+		log.Error("failed to get profile", sl.Err(err))
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("profile updated")
+	return profile, nil
+}
+
+// UpdateAppMetadata replaces app_metadata of a user of the app. Caller must be admin of the app
+func (p *Profiles) UpdateAppMetadata(ctx context.Context, appName string, userID uint64, appMetadata []byte) (models.Profile, error) {
+	const op = "profiles.UpdateAppMetadata"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, p.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	if err := p.requireMember(ctx, userID, appName); err != nil {
+		log.Warn("user not member of app", sl.Err(err))
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	profile, err := p.getProfile(ctx, userID, appName)
+	if err != nil {
+		log.Warn("failed to get profile", sl.Err(err))
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	schemas, err := p.profileProvider.GetMetadataSchemas(ctx, appName)
+	if err != nil {
+		log.Error("failed to get metadata schema", sl.Err(err))
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := jsonschema.Validate(schemas.AppMetadata, appMetadata); err != nil {
+		log.Warn("invalid app metadata", sl.Err(err))
+		return models.Profile{}, fmt.Errorf("%s: %w: %s", op, ErrInvalidMetadata, err)
+	}
+
+	log.Info("attempting to set app metadata")
+	if err := p.profileProvider.SetAppMetadata(ctx, userID, profile.AppID, appMetadata); err != nil {
+		log.Error("failed to set app metadata", sl.Err(err))
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	profile, err = p.getProfile(ctx, userID, appName)
+	if err != nil {
+		log.Error("failed to get profile", sl.Err(err))
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("app metadata updated")
+	return profile, nil
+}
+
+func (p *Profiles) requireAdmin(ctx context.Context, userID uint64, appName string) error {
+	err := p.adminProvider.IsAdmin(ctx, userID, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrAdminNotFound) {
+			return ErrNotAdmin
+		}
+		if errors.Is(err, storage.ErrUserNotFound) || errors.Is(err, storage.ErrAppNotFound) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+	return nil
+}
+
+// requireMember hides users of other apps behind the error of a missing user
+func (p *Profiles) requireMember(ctx context.Context, userID uint64, appName string) error {
+	err := p.memberProvider.IsMember(ctx, userID, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrMemberNotFound) || errors.Is(err, storage.ErrUserNotFound) || errors.Is(err, storage.ErrAppNotFound) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+	return nil
+}
+
+func (p *Profiles) getProfile(ctx context.Context, userID uint64, appName string) (models.Profile, error) {
+	profile, err := p.profileProvider.GetProfile(ctx, userID, appName)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) || errors.Is(err, storage.ErrAppNotFound) {
+			return models.Profile{}, ErrInvalidCredentials
+		}
+		return models.Profile{}, err
+	}
+	return profile, nil
+}
@@ -0,0 +1,313 @@
+package serviceaccounts
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/jwt"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/lib/principal"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+type ServiceAccounts struct {
+	log         *slog.Logger
+	accounts    AccountStorage
+	appProvider AppProvider
+	auditor     Auditor
+	// defaults are used for settings the app left unset
+	defaults models.AppSettings
+}
+
+type AccountStorage interface {
+	CreateServiceAccount(ctx context.Context, appName string, name string, clientID string, createdBy uint64) (models.ServiceAccount, error)
+	GetServiceAccount(ctx context.Context, userID uint64) (models.ServiceAccount, error)
+	ListServiceAccounts(ctx context.Context, appName string, filter models.ListFilter) ([]models.ServiceAccount, error)
+	DeleteServiceAccount(ctx context.Context, userID uint64) error
+	CreateAPIKey(ctx context.Context, key models.APIKey, keyHash string) (uint64, error)
+	GetAPIKey(ctx context.Context, keyHash string) (models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID uint64, keyID uint64) error
+}
+
+type AppProvider interface {
+	GetApp(ctx context.Context, appName string) (models.App, error)
+}
+
+type Auditor interface {
+	Record(ctx context.Context, entry models.AuditEntry)
+}
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidName        = errors.New("invalid service account name")
+	ErrAccountExists      = errors.New("service account already exists")
+	ErrAccountNotFound    = errors.New("service account not found")
+	ErrKeyNotFound        = errors.New("api key not found")
+	ErrInvalidKey         = errors.New("invalid api key")
+	ErrInvalidTTL         = errors.New("invalid api key ttl")
+)
+
+// keyPrefix starts every API key, so leaked keys are easy to find by secret scanners
+const keyPrefix = "sk_"
+
+// shownKeyPrefix is how many first characters of a key are kept to tell keys apart
+const shownKeyPrefix = len(keyPrefix) + 8
+
+var accountName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// New returns a new instanse of the ServiceAccounts service
+func New(log *slog.Logger, accounts AccountStorage, appProvider AppProvider, auditor Auditor, defaults models.AppSettings) *ServiceAccounts {
+	return &ServiceAccounts{
+		log:         log,
+		accounts:    accounts,
+		appProvider: appProvider,
+		auditor:     auditor,
+		defaults:    defaults,
+	}
+}
+
+// ClientID returns the client id of the service account with the name, it is unique like emails of humans
+func ClientID(appName string, name string) string {
+	return name + "@" + appName + ".svc"
+}
+
+// CreateServiceAccount adds a service account to the app. Caller must be creator of the app
+func (s *ServiceAccounts) CreateServiceAccount(ctx context.Context, appName string, name string) (models.ServiceAccount, error) {
+	const op = "serviceaccounts.CreateServiceAccount"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, s.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	if !accountName.MatchString(name) {
+		log.Warn("invalid name", slog.String("name", name))
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, ErrInvalidName)
+	}
+
+	log.Info("attempting to create service account")
+	account, err := s.accounts.CreateServiceAccount(ctx, appName, name, ClientID(appName, name), principal.UserID(ctx))
+	if err != nil {
+		if errors.Is(err, storage.ErrServiceAccountExists) {
+			log.Warn("service account exists", sl.Err(err))
+			return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, ErrAccountExists)
+		}
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to create service account", sl.Err(err))
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("service account created", slog.Uint64("uid", account.UserID))
+	return account, nil
+}
+
+// ListServiceAccounts returns one page of service accounts of the app. Caller must be creator of the app
+func (s *ServiceAccounts) ListServiceAccounts(ctx context.Context, appName string, filter models.ListFilter) ([]models.ServiceAccount, string, error) {
+	const op = "serviceaccounts.ListServiceAccounts"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, s.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	log.Info("listing service accounts")
+	limit := filter.Limit
+	filter.Limit++
+	accounts, err := s.accounts.ListServiceAccounts(ctx, appName, filter)
+	if err != nil {
+		log.Error("failed to list service accounts", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	accounts, next := pagination.NextToken(accounts, limit, func(a models.ServiceAccount) uint64 { return a.UserID })
+	return accounts, next, nil
+}
+
+// DeleteServiceAccount deletes the service account of the app and revokes its keys.
+// Caller must be creator of the app
+func (s *ServiceAccounts) DeleteServiceAccount(ctx context.Context, appName string, userID uint64) (bool, error) {
+	const op = "serviceaccounts.DeleteServiceAccount"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, s.log).With(slog.String("op", op), tracing.LogAttr(ctx), slog.Uint64("uid", userID))
+
+	if _, err := s.account(ctx, appName, userID); err != nil {
+		log.Warn("service account not found", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("attempting to delete service account")
+	err := s.accounts.DeleteServiceAccount(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrServiceAccountNotFound) {
+			log.Warn("service account not found", sl.Err(err))
+			return false, fmt.Errorf("%s: %w", op, ErrAccountNotFound)
+		}
+		log.Error("failed to delete service account", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("service account deleted")
+	return true, nil
+}
+
+// CreateAPIKey returns a new key of the service account, the key is never shown again.
+// Zero ttl means the key doesn't expire. Caller must be creator of the app
+func (s *ServiceAccounts) CreateAPIKey(ctx context.Context, appName string, userID uint64, ttl time.Duration) (string, models.APIKey, error) {
+	const op = "serviceaccounts.CreateAPIKey"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, s.log).With(slog.String("op", op), tracing.LogAttr(ctx), slog.Uint64("uid", userID))
+
+	if ttl < 0 {
+		log.Warn("invalid ttl", slog.Duration("ttl", ttl))
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, ErrInvalidTTL)
+	}
+	if _, err := s.account(ctx, appName, userID); err != nil {
+		log.Warn("service account not found", sl.Err(err))
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("attempting to create api key")
+	secret, hash := newKey()
+	key := models.APIKey{UserID: userID, Prefix: secret[:shownKeyPrefix], CreatedAt: time.Now()}
+	if ttl > 0 {
+		key.ExpiresAt = key.CreatedAt.Add(ttl)
+	}
+	id, err := s.accounts.CreateAPIKey(ctx, key, hash)
+	if err != nil {
+		log.Error("failed to create api key", sl.Err(err))
+		return "", models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	key.ID = id
+	log.Info("api key created", slog.Uint64("key_id", key.ID))
+	return secret, key, nil
+}
+
+// RevokeAPIKey revokes the key of the service account. Caller must be creator of the app
+func (s *ServiceAccounts) RevokeAPIKey(ctx context.Context, appName string, userID uint64, keyID uint64) (bool, error) {
+	const op = "serviceaccounts.RevokeAPIKey"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, s.log).With(slog.String("op", op), tracing.LogAttr(ctx), slog.Uint64("uid", userID), slog.Uint64("key_id", keyID))
+
+	if _, err := s.account(ctx, appName, userID); err != nil {
+		log.Warn("service account not found", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("attempting to revoke api key")
+	err := s.accounts.RevokeAPIKey(ctx, userID, keyID)
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			log.Warn("api key not found", sl.Err(err))
+			return false, fmt.Errorf("%s: %w", op, ErrKeyNotFound)
+		}
+		log.Error("failed to revoke api key", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("api key revoked")
+	return true, nil
+}
+
+// IssueToken exchanges client credentials for an access token of the service account.
+// The client secret is an API key of the account
+func (s *ServiceAccounts) IssueToken(ctx context.Context, appName string, clientID string, clientSecret string) (_ string, _ time.Time, err error) {
+	const op = "serviceaccounts.IssueToken"
+	ctx, span := tracing.Start(ctx, op)
+	defer func() { tracing.End(span, err) }()
+	log := sl.FromContext(ctx, s.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	entry := models.AuditEntry{Action: models.AuditServiceToken, ActorKind: models.UserService, Target: clientID, AppName: appName}
+	defer func() { s.auditor.Record(ctx, entry.WithResult(err, ErrInvalidCredentials, ErrInvalidKey)) }()
+
+	log.Info("attempting to issue service token")
+	account, err := s.Authenticate(ctx, appName, clientSecret)
+	if err != nil {
+		log.Warn("failed to authenticate", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	entry.ActorID = account.UserID
+	if account.ClientID != clientID {
+		log.Warn("api key of another service account")
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, ErrInvalidKey)
+	}
+
+	app, err := s.appProvider.GetApp(ctx, appName)
+	if err != nil {
+		log.Error("failed to find app", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	user := models.User{ID: account.UserID, Kind: models.UserService, Email: account.ClientID, Status: account.Status}
+	ttl := app.Settings.Or(s.defaults).AccessTTL
+	token, err := jwt.NewToken(app, jwt.Claims(user, app, ttl), nil)
+	if err != nil {
+		log.Error("failed to generate token", sl.Err(err))
+		return "", time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("service token issued", slog.Uint64("uid", account.UserID))
+	return token, time.Now().Add(ttl), nil
+}
+
+// Authenticate returns the active service account of the API key, the account must belong to the app
+func (s *ServiceAccounts) Authenticate(ctx context.Context, appName string, apiKey string) (models.ServiceAccount, error) {
+	ctx, span := tracing.Start(ctx, "serviceaccounts.Authenticate")
+	defer span.End()
+
+	key, err := s.accounts.GetAPIKey(ctx, hashKey(apiKey))
+	if err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			return models.ServiceAccount{}, ErrInvalidKey
+		}
+		return models.ServiceAccount{}, err
+	}
+	if key.Revoked || (!key.ExpiresAt.IsZero() && time.Now().After(key.ExpiresAt)) {
+		return models.ServiceAccount{}, ErrInvalidKey
+	}
+	account, err := s.account(ctx, appName, key.UserID)
+	if err != nil {
+		if errors.Is(err, ErrAccountNotFound) {
+			return models.ServiceAccount{}, ErrInvalidKey
+		}
+		return models.ServiceAccount{}, err
+	}
+	if account.Status != models.UserActive {
+		return models.ServiceAccount{}, ErrInvalidKey
+	}
+	return account, nil
+}
+
+// account returns the service account, it must belong to the app
+func (s *ServiceAccounts) account(ctx context.Context, appName string, userID uint64) (models.ServiceAccount, error) {
+	account, err := s.accounts.GetServiceAccount(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrServiceAccountNotFound) {
+			return models.ServiceAccount{}, ErrAccountNotFound
+		}
+		return models.ServiceAccount{}, err
+	}
+	if account.AppName != appName || account.Status == models.UserDeleted {
+		return models.ServiceAccount{}, ErrAccountNotFound
+	}
+	return account, nil
+}
+
+// newKey returns an API key and the hash stored instead of it
+func newKey() (string, string) {
+	b := make([]byte, 32)
+	// crypto/rand never fails on supported platforms
+	_, _ = rand.Read(b)
+	key := keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, hashKey(key)
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,133 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// lease hides claimed deliveries from other instances while they are sent
+	lease       = time.Minute
+	baseBackoff = 10 * time.Second
+	maxBackoff  = 6 * time.Hour
+)
+
+type payload struct {
+	ID        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatch sends due deliveries. Failed deliveries are retried with exponential
+// backoff and moved to the dead-letter table after maxAttempts
+func (w *Webhooks) Dispatch(ctx context.Context) error {
+	const op = "webhooks.Dispatch"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, w.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	for {
+		deliveries, err := w.webhookStorage.ClaimDeliveries(ctx, w.batchSize, lease)
+		if err != nil {
+			log.Error("failed to claim deliveries", sl.Err(err))
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, d := range deliveries {
+			if err := w.deliver(ctx, d); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
+		if len(deliveries) < w.batchSize {
+			return nil
+		}
+	}
+}
+
+func (w *Webhooks) deliver(ctx context.Context, d models.Delivery) error {
+	log := sl.FromContext(ctx, w.log).With(
+		slog.String("op", "webhooks.deliver"),
+		slog.Uint64("delivery_id", d.ID),
+		slog.Int("webhook_id", d.Webhook.ID),
+		slog.String("event", d.Event.Type),
+	)
+
+	sendErr := w.send(ctx, d)
+	if sendErr == nil {
+		log.Debug("webhook delivered")
+		return w.webhookStorage.DeliverySucceeded(ctx, d.ID)
+	}
+
+	attempts := d.Attempts + 1
+	if attempts >= w.maxAttempts {
+		log.Warn("webhook delivery dead-lettered", slog.Int("attempts", attempts), sl.Err(sendErr))
+		return w.webhookStorage.DeadLetter(ctx, d.ID, sendErr.Error())
+	}
+	log.Info("webhook delivery failed", slog.Int("attempts", attempts), sl.Err(sendErr))
+	return w.webhookStorage.DeliveryFailed(ctx, d.ID, sendErr.Error(), time.Now().Add(backoff(attempts)))
+}
+
+func (w *Webhooks) send(ctx context.Context, d models.Delivery) error {
+	body, err := json.Marshal(payload{
+		ID:        d.Event.ID,
+		Type:      d.Event.Type,
+		CreatedAt: d.Event.CreatedAt,
+		Data:      d.Event.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-SSO-Event", d.Event.Type)
+	req.Header.Set("X-SSO-Delivery", strconv.FormatUint(d.ID, 10))
+	req.Header.Set("X-SSO-Signature", Sign(d.Webhook.Secret, time.Now(), body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the X-SSO-Signature header value: "t=<unix>,v1=<hex>", where
+// v1 is HMAC-SHA256 of "<unix>.<body>" keyed with the webhook secret
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay before the next attempt: 10s, 20s, 40s... capped at maxBackoff
+func backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
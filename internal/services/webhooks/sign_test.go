@@ -0,0 +1,83 @@
+package webhooks_test
+
+import (
+	"github.com/neepooha/sso/internal/services/webhooks"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"user.created"}`)
+	at := time.Unix(1700000000, 0)
+
+	// expected values are computed with: printf '%s' '<unix>.<body>' | openssl dgst -sha256 -hmac '<secret>'
+	tests := []struct {
+		name   string
+		secret string
+		t      time.Time
+		body   []byte
+		want   string
+	}{
+		{
+			name:   "event",
+			secret: "whsec",
+			t:      at,
+			body:   body,
+			want:   "t=1700000000,v1=5201e1d8ec15a0b64539fe871758d8034bf85d818e868917795d781f8d50b969",
+		},
+		{
+			name:   "sub-second time is truncated",
+			secret: "whsec",
+			t:      at.Add(999 * time.Millisecond),
+			body:   body,
+			want:   "t=1700000000,v1=5201e1d8ec15a0b64539fe871758d8034bf85d818e868917795d781f8d50b969",
+		},
+		{
+			name:   "empty body",
+			secret: "whsec",
+			t:      at,
+			body:   nil,
+			want:   "t=1700000000,v1=ab5fdf6f7cdf5f7abf2f4d61c6b0376dc6bf75beafc17135e5fd06513ee7afd8",
+		},
+		{
+			name:   "other secret and time",
+			secret: "other",
+			t:      time.Unix(0, 0),
+			body:   body,
+			want:   "t=0,v1=9eb8a29217f3f7579bda2396782b6a5644943f903b0e514db4b098ff28c6aa1d",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webhooks.Sign(tt.secret, tt.t, tt.body); got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignDiffers(t *testing.T) {
+	body := []byte(`{"type":"user.created"}`)
+	at := time.Unix(1700000000, 0)
+	base := webhooks.Sign("whsec", at, body)
+
+	tests := []struct {
+		name   string
+		secret string
+		t      time.Time
+		body   []byte
+	}{
+		{name: "secret", secret: "whsec2", t: at, body: body},
+		{name: "time", secret: "whsec", t: at.Add(time.Second), body: body},
+		{name: "body", secret: "whsec", t: at, body: []byte(`{"type":"user.deleted"}`)},
+		// the dot separates the time from the body, so digits can't move between them
+		{name: "time digit moved into body", secret: "whsec", t: time.Unix(170000000, 0), body: append([]byte("0"), body...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := webhooks.Sign(tt.secret, tt.t, tt.body); got == base {
+				t.Errorf("Sign() with other %s = %s, the same signature", tt.name, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/logger/sl"
+	"github.com/neepooha/sso/internal/lib/netguard"
+	"github.com/neepooha/sso/internal/lib/pagination"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"github.com/neepooha/sso/internal/storage"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+)
+
+type Webhooks struct {
+	log            *slog.Logger
+	webhookStorage WebhookStorage
+	client         *http.Client
+	guard          *netguard.Guard
+	maxAttempts    int
+	batchSize      int
+}
+
+type WebhookStorage interface {
+	SetWebhook(ctx context.Context, appName string, url string, secret string, eventTypes []string) (models.Webhook, error)
+	ListWebhooks(ctx context.Context, appName string) ([]models.Webhook, error)
+	DelWebhook(ctx context.Context, appName string, webhookID int) error
+	ListDeadLetters(ctx context.Context, appName string, filter models.ListFilter) ([]models.DeadLetter, error)
+	ReplayDeadLetters(ctx context.Context, appName string, ids []uint64) (int, error)
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.Delivery, error)
+	DeliverySucceeded(ctx context.Context, deliveryID uint64) error
+	DeliveryFailed(ctx context.Context, deliveryID uint64, lastError string, nextAttempt time.Time) error
+	DeadLetter(ctx context.Context, deliveryID uint64, lastError string) error
+}
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNotCreator         = errors.New("user isn't creator")
+	ErrWebhookNotFound    = errors.New("webhook not found")
+	ErrInvalidURL         = errors.New("invalid webhook url")
+	ErrInvalidEventType   = errors.New("invalid event type")
+)
+
+// New returns a new instanse of the Webhooks service. Deliveries are sent only
+// to addresses the guard allows and dead-lettered after maxAttempts failed attempts
+func New(log *slog.Logger, webhookStorage WebhookStorage, guard *netguard.Guard, timeout time.Duration, maxAttempts int, batchSize int) *Webhooks {
+	return &Webhooks{
+		log:            log,
+		webhookStorage: webhookStorage,
+		client:         guard.HTTPClient(timeout),
+		guard:          guard,
+		maxAttempts:    maxAttempts,
+		batchSize:      batchSize,
+	}
+}
+
+// CreateWebhook subscribes the url to events of the app. Empty eventTypes means all events.
+// Returns the secret used to sign deliveries. Caller must be creator of the app
+func (w *Webhooks) CreateWebhook(ctx context.Context, appName string, rawURL string, eventTypes []string) (models.Webhook, error) {
+	const op = "webhooks.CreateWebhook"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, w.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Warn("invalid url", slog.String("url", rawURL))
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, ErrInvalidURL)
+	}
+	if err := w.guard.CheckHost(ctx, u.Hostname()); err != nil {
+		log.Warn("forbidden url", slog.String("url", rawURL), sl.Err(err))
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, ErrInvalidURL)
+	}
+	for _, t := range eventTypes {
+		if !slices.Contains(models.EventTypes, t) {
+			log.Warn("invalid event type", slog.String("type", t))
+			return models.Webhook{}, fmt.Errorf("%s: %w", op, ErrInvalidEventType)
+		}
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		log.Error("failed to generate secret", sl.Err(err))
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("attempting to create webhook")
+	webhook, err := w.webhookStorage.SetWebhook(ctx, appName, rawURL, secret, eventTypes)
+	if err != nil {
+		if errors.Is(err, storage.ErrAppNotFound) {
+			log.Warn("app not found", sl.Err(err))
+			return models.Webhook{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+		log.Error("failed to create webhook", sl.Err(err))
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("webhook created", slog.Int("webhook_id", webhook.ID))
+	return webhook, nil
+}
+
+// ListWebhooks returns all webhooks of the app. Caller must be creator of the app
+func (w *Webhooks) ListWebhooks(ctx context.Context, appName string) ([]models.Webhook, error) {
+	const op = "webhooks.ListWebhooks"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, w.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	webhooks, err := w.webhookStorage.ListWebhooks(ctx, appName)
+	if err != nil {
+		log.Error("failed to list webhooks", sl.Err(err))
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return webhooks, nil
+}
+
+// DelWebhook removes the webhook and its pending deliveries. Caller must be creator of the app
+func (w *Webhooks) DelWebhook(ctx context.Context, appName string, webhookID int) (bool, error) {
+	const op = "webhooks.DelWebhook"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, w.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	log.Info("attempting to delete webhook")
+	err := w.webhookStorage.DelWebhook(ctx, appName, webhookID)
+	if err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			log.Warn("webhook not found", sl.Err(err))
+			return false, fmt.Errorf("%s: %w", op, ErrWebhookNotFound)
+		}
+		log.Error("failed to delete webhook", sl.Err(err))
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("webhook deleted")
+	return true, nil
+}
+
+// ListDeadLetters returns one page of deliveries of the app that ran out of attempts.
+// Caller must be creator of the app
+func (w *Webhooks) ListDeadLetters(ctx context.Context, appName string, filter models.ListFilter) ([]models.DeadLetter, string, error) {
+	const op = "webhooks.ListDeadLetters"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, w.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	limit := filter.Limit
+	filter.Limit++
+	letters, err := w.webhookStorage.ListDeadLetters(ctx, appName, filter)
+	if err != nil {
+		log.Error("failed to list dead letters", sl.Err(err))
+		return nil, "", fmt.Errorf("%s: %w", op, err)
+	}
+	letters, next := pagination.NextToken(letters, limit, func(l models.DeadLetter) uint64 { return l.ID })
+	return letters, next, nil
+}
+
+// ReplayDeadLetters schedules dead letters of the app for delivery again.
+// Empty ids replays all of them. Caller must be creator of the app
+func (w *Webhooks) ReplayDeadLetters(ctx context.Context, appName string, ids []uint64) (int, error) {
+	const op = "webhooks.ReplayDeadLetters"
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+	log := sl.FromContext(ctx, w.log).With(slog.String("op", op), tracing.LogAttr(ctx))
+
+	log.Info("attempting to replay dead letters")
+	replayed, err := w.webhookStorage.ReplayDeadLetters(ctx, appName, ids)
+	if err != nil {
+		log.Error("failed to replay dead letters", sl.Err(err))
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("dead letters replayed", slog.Int("count", replayed))
+	return replayed, nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"maps"
+	"time"
+)
+
+func (s *Storage) GetAppID(ctx context.Context, appName string) (models.App, error) {
+	const op = "storage.memory.GetAppID"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	return models.App{ID: app.ID, Name: app.Name}, nil
+}
+
+func (s *Storage) GetApp(ctx context.Context, appName string) (models.App, error) {
+	const op = "storage.memory.GetApp"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	return models.App{
+		ID:           app.ID,
+		Name:         app.Name,
+		Secret:       app.Secret,
+		ClaimMapping: maps.Clone(app.ClaimMapping),
+		Settings:     app.Settings,
+	}, nil
+}
+
+func (s *Storage) SetApp(ctx context.Context, appName string, appSecret string) (int, error) {
+	const op = "storage.memory.SetApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.appNames[appName]; ok {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+	}
+	s.lastAppID++
+	s.apps[s.lastAppID] = &models.App{ID: s.lastAppID, Name: appName, Secret: appSecret, CreatedAt: time.Now()}
+	s.appNames[appName] = s.lastAppID
+	return s.lastAppID, nil
+}
+
+// SetClaimMapping replaces the claim mapping of the app, empty mapping removes it
+func (s *Storage) SetClaimMapping(ctx context.Context, appName string, mapping map[string]string) error {
+	const op = "storage.memory.SetClaimMapping"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	app.ClaimMapping = nil
+	if len(mapping) > 0 {
+		app.ClaimMapping = maps.Clone(mapping)
+	}
+	return nil
+}
+
+// SetAppSettings replaces the token and session settings of the app. Durations are
+// cut to whole seconds, as postgres stores them
+func (s *Storage) SetAppSettings(ctx context.Context, appName string, settings models.AppSettings) error {
+	const op = "storage.memory.SetAppSettings"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	settings.AccessTTL = settings.AccessTTL.Truncate(time.Second)
+	settings.RefreshTTL = settings.RefreshTTL.Truncate(time.Second)
+	settings.IdleTimeout = settings.IdleTimeout.Truncate(time.Second)
+	settings.AbsoluteLifetime = settings.AbsoluteLifetime.Truncate(time.Second)
+	app.Settings = settings
+	return nil
+}
+
+// UpdApp renames the app and changes its secret
+func (s *Storage) UpdApp(ctx context.Context, appName, newAppName, newAppSecret string) error {
+	const op = "storage.memory.UpdApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	if id, ok := s.appNames[newAppName]; ok && id != app.ID {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+	}
+	delete(s.appNames, app.Name)
+	app.Name = newAppName
+	app.Secret = newAppSecret
+	s.appNames[newAppName] = app.ID
+	return nil
+}
+
+// DelApp removes the app with its roles, sessions, hooks and schemas
+func (s *Storage) DelApp(ctx context.Context, appName string) error {
+	const op = "storage.memory.DelApp"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	ofApp := func(m member, _ time.Time) bool { return m.appID == app.ID }
+	for _, members := range s.roles {
+		maps.DeleteFunc(members, ofApp)
+	}
+	maps.DeleteFunc(s.joined, ofApp)
+	maps.DeleteFunc(s.sessions, func(_ uint64, sess *session) bool { return sess.AppID == app.ID })
+	delete(s.hooks, app.ID)
+	delete(s.schemas, app.ID)
+	delete(s.appNames, app.Name)
+	delete(s.apps, app.ID)
+	return nil
+}
+
+// ListApps returns the same fields as postgres: id, name and created_at
+func (s *Storage) ListApps(ctx context.Context, filter models.ListFilter, creatorEmail string) ([]models.App, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var creator *models.User
+	if creatorEmail != "" {
+		user, ok := s.userByEmail(creatorEmail)
+		if !ok {
+			return nil, nil
+		}
+		creator = user
+	}
+
+	var apps []models.App
+	for _, app := range s.apps {
+		if !matches(filter, uint64(app.ID), app.Name, app.CreatedAt) {
+			continue
+		}
+		if creator != nil {
+			if _, ok := s.roles[models.RoleCreator][member{userID: creator.ID, appID: app.ID}]; !ok {
+				continue
+			}
+		}
+		apps = append(apps, models.App{ID: app.ID, Name: app.Name, CreatedAt: app.CreatedAt})
+	}
+	return page(apps, filter.Limit, func(a models.App) uint64 { return uint64(a.ID) }), nil
+}
+
+// appByName must be called with the lock held
+func (s *Storage) appByName(appName string) (*models.App, bool) {
+	id, ok := s.appNames[appName]
+	if !ok {
+		return nil, false
+	}
+	return s.apps[id], true
+}
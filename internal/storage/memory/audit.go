@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/hashchain"
+	"time"
+)
+
+func (s *Storage) AppendAudit(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the same precision as the databases, so entries hash the same way
+	entry.CreatedAt = entry.CreatedAt.UTC().Truncate(time.Microsecond)
+	if entry.ActorKind == "" {
+		entry.ActorKind = models.UserHuman
+	}
+	entry.PrevHash = []byte{}
+	if n := len(s.audit); n > 0 {
+		entry.PrevHash = s.audit[n-1].Hash
+	}
+	entry.Hash = hashchain.Sum(entry.PrevHash, entry)
+	entry.ID = uint64(len(s.audit)) + 1
+	s.audit = append(s.audit, entry)
+	return entry, nil
+}
+
+// QueryAudit returns entries matching the filter, newest first
+func (s *Storage) QueryAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []models.AuditEntry
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		e := s.audit[i]
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+		if auditMatches(filter, e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func auditMatches(f models.AuditFilter, e models.AuditEntry) bool {
+	return (f.AppName == "" || e.AppName == f.AppName) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.ActorID == 0 || e.ActorID == f.ActorID) &&
+		(f.ActorKind == "" || e.ActorKind == f.ActorKind) &&
+		(f.OperatorID == 0 || e.OperatorID == f.OperatorID) &&
+		(f.Outcome == "" || e.Outcome == f.Outcome) &&
+		(f.CreatedAfter.IsZero() || !e.CreatedAt.Before(f.CreatedAfter)) &&
+		(f.CreatedBefore.IsZero() || e.CreatedAt.Before(f.CreatedBefore)) &&
+		(f.BeforeID == 0 || e.ID < f.BeforeID)
+}
@@ -0,0 +1,16 @@
+package memory
+
+import "context"
+
+// Ping always succeeds, there is no database to reach
+func (s *Storage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// MigrationVersion is always 0: the storage has no schema to migrate
+func (s *Storage) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	return 0, false, nil
+}
+
+// Close drops nothing, the data lives as long as the process
+func (s *Storage) Close() {}
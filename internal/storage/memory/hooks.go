@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+)
+
+// SetHooks replaces all hooks of the app
+func (s *Storage) SetHooks(ctx context.Context, appName string, hooks []models.Hook) error {
+	const op = "storage.memory.SetHooks"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	stored := make([]models.Hook, 0, len(hooks))
+	for _, h := range hooks {
+		s.lastHookID++
+		h.ID = s.lastHookID
+		h.AppID = app.ID
+		stored = append(stored, h)
+	}
+	if len(stored) == 0 {
+		delete(s.hooks, app.ID)
+		return nil
+	}
+	s.hooks[app.ID] = stored
+	return nil
+}
+
+// ListHooks returns hooks of the app in run order. Empty stage means all stages
+func (s *Storage) ListHooks(ctx context.Context, appName string, stage string) ([]models.Hook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return nil, nil
+	}
+	var hooks []models.Hook
+	for _, h := range s.hooks[app.ID] {
+		if stage == "" || h.Stage == stage {
+			hooks = append(hooks, h)
+		}
+	}
+	return hooks, nil
+}
+
+// HasHooks reports whether any app has hooks of the stage
+func (s *Storage) HasHooks(ctx context.Context, stage string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, hooks := range s.hooks {
+		for _, h := range hooks {
+			if h.Stage == stage {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
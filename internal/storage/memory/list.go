@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"cmp"
+	"github.com/neepooha/sso/internal/domain/models"
+	"slices"
+	"strings"
+	"time"
+)
+
+// matches applies the filter like the keyset of postgres does: prefix of the name
+// or email, created_at in [CreatedAfter, CreatedBefore) and ids after AfterID
+func matches(f models.ListFilter, id uint64, name string, createdAt time.Time) bool {
+	if f.Prefix != "" && !strings.HasPrefix(name, f.Prefix) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && createdAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !createdAt.Before(f.CreatedBefore) {
+		return false
+	}
+	return f.AfterID == 0 || id > f.AfterID
+}
+
+// page orders items by id and keeps the first limit of them
+func page[T any](items []T, limit int, id func(T) uint64) []T {
+	slices.SortFunc(items, func(a, b T) int { return cmp.Compare(id(a), id(b)) })
+	if len(items) > limit {
+		items = items[:max(limit, 0)]
+	}
+	return items
+}
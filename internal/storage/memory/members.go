@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+)
+
+// AddMember makes the user a member of the app, a member stays one
+func (s *Storage) AddMember(ctx context.Context, userID uint64, appID int) error {
+	const op = "storage.memory.AddMember"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apps[appID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	m := member{userID: userID, appID: appID}
+	if _, ok := s.joined[m]; !ok {
+		s.joined[m] = time.Now()
+	}
+	return nil
+}
+
+func (s *Storage) IsMember(ctx context.Context, userID uint64, appName string) error {
+	const op = "storage.memory.IsMember"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if !s.isMember(member{userID: userID, appID: app.ID}) {
+		return fmt.Errorf("%s: %w", op, storage.ErrMemberNotFound)
+	}
+	return nil
+}
+
+// isMember must be called with the lock held
+func (s *Storage) isMember(m member) bool {
+	if _, ok := s.joined[m]; ok {
+		return true
+	}
+	for _, members := range s.roles {
+		if _, ok := members[m]; ok {
+			return true
+		}
+	}
+	return false
+}
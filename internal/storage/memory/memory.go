@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"sync"
+	"time"
+)
+
+var _ storage.Storage = (*Storage)(nil)
+
+// Storage keeps users, apps, roles, sessions, hooks and the audit log in memory,
+// for tests and demos that shouldn't need a database. Unlike postgres it writes
+// no events to the outbox
+type Storage struct {
+	mu sync.RWMutex
+
+	users      map[uint64]*models.User
+	userEmails map[string]uint64
+	lastUserID uint64
+
+	apps      map[int]*models.App
+	appNames  map[string]int
+	lastAppID int
+
+	// roles holds when each member got the role, by role
+	roles map[string]map[member]time.Time
+	// joined holds when users joined apps, holders of roles are members without joining
+	joined map[member]time.Time
+	// schemas are only kept, profiles with metadata need postgres
+	schemas map[int]models.MetadataSchemas
+
+	sessions      map[uint64]*session
+	lastSessionID uint64
+
+	// hooks holds hooks of each app in run order
+	hooks      map[int][]models.Hook
+	lastHookID int
+
+	audit []models.AuditEntry
+}
+
+type member struct {
+	userID uint64
+	appID  int
+}
+
+func New() *Storage {
+	roles := make(map[string]map[member]time.Time, len(models.RoleLevels))
+	for role := range models.RoleLevels {
+		roles[role] = map[member]time.Time{}
+	}
+	return &Storage{
+		users:      map[uint64]*models.User{},
+		userEmails: map[string]uint64{},
+		apps:       map[int]*models.App{},
+		appNames:   map[string]int{},
+		roles:      roles,
+		joined:     map[member]time.Time{},
+		schemas:    map[int]models.MetadataSchemas{},
+		sessions:   map[uint64]*session{},
+		hooks:      map[int][]models.Hook{},
+	}
+}
@@ -0,0 +1,14 @@
+package memory_test
+
+import (
+	"github.com/neepooha/sso/internal/storage"
+	"github.com/neepooha/sso/internal/storage/memory"
+	"github.com/neepooha/sso/internal/storage/storagetest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return memory.New()
+	})
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+)
+
+// GetProfile returns the user in the app for claims of tokens. Metadata is only
+// written by the profiles service, which needs postgres, so it is always empty here
+func (s *Storage) GetProfile(ctx context.Context, userID uint64, appName string) (models.Profile, error) {
+	const op = "storage.memory.GetProfile"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return models.Profile{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	app, ok := s.appByName(appName)
+	if !ok {
+		return models.Profile{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	return models.Profile{
+		User:         copyUser(user),
+		AppID:        app.ID,
+		AppMetadata:  json.RawMessage(`{}`),
+		UserMetadata: json.RawMessage(`{}`),
+	}, nil
+}
+
+// SetMetadataSchemas replaces the metadata schemas of the app
+func (s *Storage) SetMetadataSchemas(ctx context.Context, appName string, schemas models.MetadataSchemas) error {
+	const op = "storage.memory.SetMetadataSchemas"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	s.schemas[app.ID] = schemas
+	return nil
+}
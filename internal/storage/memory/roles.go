@@ -0,0 +1,196 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+)
+
+func (s *Storage) IsAdmin(ctx context.Context, userID uint64, appName string) error {
+	const op = "storage.memory.IsAdmin"
+
+	if err := s.hasRole(userID, appName, models.RoleAdmin, storage.ErrAdminNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) IsCreator(ctx context.Context, userID uint64, appName string) error {
+	const op = "storage.memory.IsCreator"
+
+	if err := s.hasRole(userID, appName, models.RoleCreator, storage.ErrCreatorNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) SetCreator(ctx context.Context, userID uint64, appID int) error {
+	const op = "storage.memory.SetCreator"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apps[appID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	m := member{userID: userID, appID: appID}
+	if _, ok := s.roles[models.RoleCreator][m]; !ok {
+		s.roles[models.RoleCreator][m] = time.Now()
+	}
+	return nil
+}
+
+func (s *Storage) ListAdmins(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, error) {
+	const op = "storage.memory.ListAdmins"
+
+	admins, err := s.listMembers(models.RoleAdmin, appName, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return admins, nil
+}
+
+func (s *Storage) ListCreators(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, error) {
+	const op = "storage.memory.ListCreators"
+
+	creators, err := s.listMembers(models.RoleCreator, appName, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return creators, nil
+}
+
+// GetRoles returns the roles of the user in the app
+func (s *Storage) GetRoles(ctx context.Context, userID uint64, appName string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return nil, nil
+	}
+	var roles []string
+	for _, role := range []string{models.RoleCreator, models.RoleAdmin, models.RoleSupport} {
+		if _, ok := s.roles[role][member{userID: userID, appID: app.ID}]; ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+// GrantRole gives the role in the app to the user with the email
+func (s *Storage) GrantRole(ctx context.Context, email string, appName string, role string) error {
+	const op = "storage.memory.GrantRole"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, ok := s.roles[role]
+	if !ok {
+		return fmt.Errorf("%s: unknown role %q", op, role)
+	}
+	m, err := s.member(email, appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, ok := members[m]; ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleExists)
+	}
+	members[m] = time.Now()
+	return nil
+}
+
+// RevokeRole takes the role in the app from the user with the email.
+// The last creator of an app can't be removed
+func (s *Storage) RevokeRole(ctx context.Context, email string, appName string, role string) error {
+	const op = "storage.memory.RevokeRole"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, ok := s.roles[role]
+	if !ok {
+		return fmt.Errorf("%s: unknown role %q", op, role)
+	}
+	m, err := s.member(email, appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if _, ok := members[m]; !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrRoleNotFound)
+	}
+	if role == models.RoleCreator && s.count(models.RoleCreator, m.appID) == 1 {
+		return fmt.Errorf("%s: %w", op, storage.ErrLastCreator)
+	}
+	delete(members, m)
+	return nil
+}
+
+// hasRole checks the app, the user and the role in this order, like postgres does
+func (s *Storage) hasRole(userID uint64, appName string, role string, errNoRole error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return storage.ErrAppNotFound
+	}
+	if _, ok := s.users[userID]; !ok {
+		return storage.ErrUserNotFound
+	}
+	if _, ok := s.roles[role][member{userID: userID, appID: app.ID}]; !ok {
+		return errNoRole
+	}
+	return nil
+}
+
+// member must be called with the lock held
+func (s *Storage) member(email string, appName string) (member, error) {
+	user, ok := s.userByEmail(email)
+	if !ok {
+		return member{}, storage.ErrUserNotFound
+	}
+	app, ok := s.appByName(appName)
+	if !ok {
+		return member{}, storage.ErrAppNotFound
+	}
+	return member{userID: user.ID, appID: app.ID}, nil
+}
+
+// count must be called with the lock held
+func (s *Storage) count(role string, appID int) int {
+	n := 0
+	for m := range s.roles[role] {
+		if m.appID == appID {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Storage) listMembers(role string, appName string, filter models.ListFilter) ([]models.Member, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return nil, storage.ErrAppNotFound
+	}
+	var members []models.Member
+	for m, createdAt := range s.roles[role] {
+		if m.appID != app.ID {
+			continue
+		}
+		user := s.users[m.userID]
+		if !matches(filter, user.ID, user.Email, createdAt) {
+			continue
+		}
+		members = append(members, models.Member{UserID: user.ID, Email: user.Email, CreatedAt: createdAt})
+	}
+	return page(members, filter.Limit, func(m models.Member) uint64 { return m.UserID }), nil
+}
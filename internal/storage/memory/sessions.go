@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"slices"
+	"time"
+)
+
+// session is a stored session with the fields models.Session doesn't carry
+type session struct {
+	models.Session
+	prevHash  string
+	revokedAt time.Time
+}
+
+// live reports whether the session can still be refreshed at now
+func (s *session) live(now time.Time) bool {
+	return s.revokedAt.IsZero() && s.RefreshExpiresAt.After(now) && (s.ExpiresAt.IsZero() || s.ExpiresAt.After(now))
+}
+
+// CreateSession saves a new session. When maxSessions is positive, the oldest live
+// sessions of the user in the app are revoked so that at most maxSessions stay
+func (s *Storage) CreateSession(ctx context.Context, sess models.Session, maxSessions int) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.lastSessionID++
+	sess.ID = s.lastSessionID
+	sess.CreatedAt = now
+	sess.LastUsedAt = now
+	sess.Revoked = false
+	sess.Reused = false
+	s.sessions[sess.ID] = &session{Session: sess}
+	if maxSessions <= 0 {
+		return sess.ID, nil
+	}
+
+	var live []*session
+	for _, other := range s.sessions {
+		if other.UserID == sess.UserID && other.AppID == sess.AppID && other.live(now) {
+			live = append(live, other)
+		}
+	}
+	// newest first, the ones past maxSessions are revoked
+	slices.SortFunc(live, func(a, b *session) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	for _, old := range live[min(maxSessions, len(live)):] {
+		old.revokedAt = now
+	}
+	return sess.ID, nil
+}
+
+// GetSession returns the session of the refresh token hash. A session found by
+// its previous refresh token is returned with Reused set
+func (s *Storage) GetSession(ctx context.Context, refreshHash string) (models.Session, error) {
+	const op = "storage.memory.GetSession"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, sess := range s.sessions {
+		if sess.RefreshHash == refreshHash || sess.prevHash == refreshHash {
+			found := sess.Session
+			found.Revoked = !sess.revokedAt.IsZero()
+			found.Reused = sess.RefreshHash != refreshHash
+			return found, nil
+		}
+	}
+	return models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+}
+
+// RotateSession replaces the refresh token of a live session. It fails with
+// ErrSessionNotFound when the token was already rotated by a concurrent refresh
+func (s *Storage) RotateSession(ctx context.Context, id uint64, oldHash, newHash string, refreshExpiresAt time.Time) error {
+	const op = "storage.memory.RotateSession"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok || sess.RefreshHash != oldHash || !sess.revokedAt.IsZero() {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+	sess.prevHash = sess.RefreshHash
+	sess.RefreshHash = newHash
+	sess.RefreshExpiresAt = refreshExpiresAt
+	sess.LastUsedAt = time.Now()
+	return nil
+}
+
+// RevokeSession ends the session, revoking an already revoked session is not an error
+func (s *Storage) RevokeSession(ctx context.Context, id uint64) error {
+	const op = "storage.memory.RevokeSession"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+	if sess.revokedAt.IsZero() {
+		sess.revokedAt = time.Now()
+	}
+	return nil
+}
+
+// DeleteEndedSessions removes sessions that were revoked or expired before the given time
+func (s *Storage) DeleteEndedSessions(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for id, sess := range s.sessions {
+		revoked := !sess.revokedAt.IsZero() && sess.revokedAt.Before(before)
+		expired := sess.RefreshExpiresAt.Before(before) || (!sess.ExpiresAt.IsZero() && sess.ExpiresAt.Before(before))
+		if revoked || expired {
+			delete(s.sessions, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+)
+
+func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte, appID int) (uint64, error) {
+	const op = "storage.memory.SaveUser"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.userEmails[email]; ok {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+	}
+	if _, ok := s.apps[appID]; appID != 0 && !ok {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	s.lastUserID++
+	now := time.Now()
+	s.users[s.lastUserID] = &models.User{
+		ID:        s.lastUserID,
+		Kind:      models.UserHuman,
+		Email:     email,
+		PassHash:  append([]byte(nil), passHash...),
+		Status:    models.UserActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.userEmails[email] = s.lastUserID
+	if appID != 0 {
+		s.joined[member{userID: s.lastUserID, appID: appID}] = now
+	}
+	return s.lastUserID, nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, email string) (models.User, error) {
+	const op = "storage.memory.GetUser"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.userByEmail(email)
+	if !ok {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return copyUser(user), nil
+}
+
+func (s *Storage) GetUserByID(ctx context.Context, userID uint64) (models.User, error) {
+	const op = "storage.memory.GetUserByID"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return copyUser(user), nil
+}
+
+// ListUsers returns the same fields as postgres: id, email, status and created_at
+func (s *Storage) ListUsers(ctx context.Context, appName string, filter models.ListFilter) ([]models.User, error) {
+	const op = "storage.memory.ListUsers"
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	app, ok := s.appByName(appName)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	var users []models.User
+	for _, user := range s.users {
+		if user.Kind != models.UserHuman || !matches(filter, user.ID, user.Email, user.CreatedAt) {
+			continue
+		}
+		if !s.isMember(member{userID: user.ID, appID: app.ID}) {
+			continue
+		}
+		users = append(users, models.User{ID: user.ID, Email: user.Email, Status: user.Status, CreatedAt: user.CreatedAt})
+	}
+	return page(users, filter.Limit, func(u models.User) uint64 { return u.ID }), nil
+}
+
+func (s *Storage) UpdEmail(ctx context.Context, userID uint64, email string) error {
+	const op = "storage.memory.UpdEmail"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	if id, ok := s.userEmails[email]; ok && id != userID {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+	}
+	delete(s.userEmails, user.Email)
+	user.Email = email
+	user.UpdatedAt = time.Now()
+	s.userEmails[email] = userID
+	return nil
+}
+
+// userByEmail must be called with the lock held
+func (s *Storage) userByEmail(email string) (*models.User, bool) {
+	id, ok := s.userEmails[email]
+	if !ok {
+		return nil, false
+	}
+	return s.users[id], true
+}
+
+// copyUser keeps callers from changing the stored user through its slices
+func copyUser(user *models.User) models.User {
+	u := *user
+	u.PassHash = append([]byte(nil), user.PassHash...)
+	return u
+}
@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SetUserStatus changes the status of the user and emits user.status_changed
+func (s *Storage) SetUserStatus(ctx context.Context, userID uint64, status models.UserStatus) error {
+	const op = "storage.postgres.SetUserStatus"
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		stmt := `UPDATE users SET status = $1,
+			deletion_requested_at = CASE WHEN $1 = 'pending_deletion' THEN now() ELSE NULL END,
+			updated_at = now()
+			WHERE id = $2 AND status <> 'deleted'`
+		tag, err := tx.Exec(ctx, stmt, status, userID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return storage.ErrUserNotFound
+		}
+		return enqueueUser(ctx, tx, models.EventUserStatusChanged, userID, statusPayload{UserID: userID, Status: status})
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ListExpiredDeletions returns users after afterID whose deletion was requested before the given time
+func (s *Storage) ListExpiredDeletions(ctx context.Context, before time.Time, afterID uint64, limit int) ([]uint64, error) {
+	const op = "storage.postgres.ListExpiredDeletions"
+
+	stmt := `SELECT id FROM users WHERE status = 'pending_deletion' AND deletion_requested_at < $1 AND id > $2
+		ORDER BY id LIMIT $3`
+	rows, err := s.db.Query(ctx, stmt, before, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uint64])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return ids, nil
+}
+
+// PurgeUser removes everything linked to the user and then anonymizes or deletes the user row.
+// It fails with storage.ErrLastCreator while the user is the only creator of an app
+func (s *Storage) PurgeUser(ctx context.Context, userID uint64, anonymize bool) error {
+	const op = "storage.postgres.PurgeUser"
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `SELECT FROM users WHERE id = $1 FOR UPDATE`, userID).Scan()
+		if err != nil {
+			if IsNotFoundError(err) {
+				return storage.ErrUserNotFound
+			}
+			return err
+		}
+		// apps of the user are locked like in RevokeRole, so no other creator
+		// can leave them while the user is purged
+		rows, err := tx.Query(ctx, `SELECT a.id FROM apps a JOIN creators c ON c.app_id = a.id
+			WHERE c.uid = $1 ORDER BY a.id FOR UPDATE OF a`, userID)
+		if err != nil {
+			return err
+		}
+		if _, err := pgx.CollectRows(rows, pgx.RowTo[int]); err != nil {
+			return err
+		}
+		var lastCreator bool
+		stmt := `SELECT EXISTS (SELECT FROM creators c WHERE c.uid = $1
+			AND NOT EXISTS (SELECT FROM creators o WHERE o.app_id = c.app_id AND o.uid <> c.uid))`
+		if err := tx.QueryRow(ctx, stmt, userID).Scan(&lastCreator); err != nil {
+			return err
+		}
+		if lastCreator {
+			return storage.ErrLastCreator
+		}
+
+		// the event goes to the apps of the user, so it is written before the
+		// memberships are gone. The email is erased, so it is left out
+		if err := enqueueUser(ctx, tx, models.EventUserDeleted, userID, userPayload{UserID: userID}); err != nil {
+			return err
+		}
+
+		for _, stmt := range purgeStmts {
+			if _, err := tx.Exec(ctx, stmt, userID); err != nil {
+				return err
+			}
+		}
+
+		stmt = `DELETE FROM users WHERE id = $1`
+		if anonymize {
+			stmt = `UPDATE users SET email = 'deleted-' || id || '@deleted.invalid', pass_hash = '',
+				display_name = '', locale = '', avatar_url = '', status = 'deleted',
+				deletion_requested_at = NULL, updated_at = now()
+				WHERE id = $1`
+		}
+		_, err = tx.Exec(ctx, stmt, userID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// purgeStmts delete rows referencing the user and emails in payloads of
+// earlier events about the user, $1 is the user id
+var purgeStmts = []string{
+	`UPDATE events SET payload = payload - 'email' - 'old_email' WHERE uid = $1`,
+	`DELETE FROM admins WHERE uid = $1`,
+	`DELETE FROM creators WHERE uid = $1`,
+	`DELETE FROM supports WHERE uid = $1`,
+	`DELETE FROM api_keys WHERE uid = $1`,
+	`DELETE FROM service_accounts WHERE uid = $1`,
+	`DELETE FROM user_metadata WHERE uid = $1`,
+	`DELETE FROM sessions WHERE uid = $1`,
+	`DELETE FROM app_users WHERE uid = $1`,
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"github.com/neepooha/sso/internal/domain/models"
 	"github.com/neepooha/sso/internal/storage"
+	"time"
 
 	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
@@ -28,15 +30,23 @@ func (s *Storage) GetAppID(ctx context.Context, appName string) (models.App, err
 
 func (s *Storage) GetApp(ctx context.Context, appName string) (models.App, error) {
 	const op = "storage.postgres.GetApp"
-	stmt := "SELECT id, name, secret FROM apps WHERE name = $1"
+	stmt := `SELECT id, name, secret, claim_mapping,
+		access_ttl, refresh_ttl, idle_timeout, absolute_lifetime, max_sessions
+		FROM apps WHERE name = $1`
 	var app models.App
-	err := s.db.QueryRow(ctx, stmt, appName).Scan(&app.ID, &app.Name, &app.Secret)
+	var accessTTL, refreshTTL, idleTimeout, absoluteLifetime int64
+	err := s.db.QueryRow(ctx, stmt, appName).Scan(&app.ID, &app.Name, &app.Secret, &app.ClaimMapping,
+		&accessTTL, &refreshTTL, &idleTimeout, &absoluteLifetime, &app.Settings.MaxSessions)
 	if err != nil {
 		if IsNotFoundError(err) {
 			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
 		}
 		return models.App{}, fmt.Errorf("%s: %w", op, err)
 	}
+	app.Settings.AccessTTL = time.Duration(accessTTL) * time.Second
+	app.Settings.RefreshTTL = time.Duration(refreshTTL) * time.Second
+	app.Settings.IdleTimeout = time.Duration(idleTimeout) * time.Second
+	app.Settings.AbsoluteLifetime = time.Duration(absoluteLifetime) * time.Second
 	return app, nil
 }
 
@@ -56,21 +66,61 @@ func (s *Storage) SetApp(ctx context.Context, appName string, appSecret string)
 	return appID, nil
 }
 
-func (s *Storage) UpdApp(ctx context.Context, appName, newAppName, newAppSecret string) error {
-	const op = "storage.postgres.UdpApp"
+// SetClaimMapping replaces the claim mapping of the app, empty mapping removes it
+func (s *Storage) SetClaimMapping(ctx context.Context, appName string, mapping map[string]string) error {
+	const op = "storage.postgres.SetClaimMapping"
 
-	stmt := `SELECT id FROM apps WHERE name = $1`
-	var appID int
-	err := s.db.QueryRow(ctx, stmt, appName).Scan(&appID)
+	var value any
+	if len(mapping) > 0 {
+		value = mapping
+	}
+	tag, err := s.db.Exec(ctx, `UPDATE apps SET claim_mapping = $1 WHERE name = $2`, value, appName)
 	if err != nil {
-		if IsNotFoundError(err) {
-			return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
-		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	return nil
+}
+
+// SetAppSettings replaces the token and session settings of the app, durations are stored in seconds
+func (s *Storage) SetAppSettings(ctx context.Context, appName string, settings models.AppSettings) error {
+	const op = "storage.postgres.SetAppSettings"
+
+	stmt := `UPDATE apps SET access_ttl = $1, refresh_ttl = $2, idle_timeout = $3,
+		absolute_lifetime = $4, max_sessions = $5 WHERE name = $6`
+	tag, err := s.db.Exec(ctx, stmt, int64(settings.AccessTTL.Seconds()), int64(settings.RefreshTTL.Seconds()),
+		int64(settings.IdleTimeout.Seconds()), int64(settings.AbsoluteLifetime.Seconds()), settings.MaxSessions, appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	return nil
+}
+
+// UpdApp renames the app and changes its secret, emits app.updated
+func (s *Storage) UpdApp(ctx context.Context, appName, newAppName, newAppSecret string) error {
+	const op = "storage.postgres.UdpApp"
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		stmt := `SELECT id FROM apps WHERE name = $1 FOR UPDATE`
+		var appID int
+		if err := tx.QueryRow(ctx, stmt, appName).Scan(&appID); err != nil {
+			if IsNotFoundError(err) {
+				return storage.ErrAppNotFound
+			}
+			return err
+		}
 
-	stmt = `UPDATE apps SET name = $1, secret = $2 WHERE id = $3`
-	_, err = s.db.Exec(ctx, stmt, newAppName, newAppSecret, appID)
+		stmt = `UPDATE apps SET name = $1, secret = $2 WHERE id = $3`
+		if _, err := tx.Exec(ctx, stmt, newAppName, newAppSecret, appID); err != nil {
+			return err
+		}
+		return enqueue(ctx, tx, models.EventAppUpdated, appID, 0, appPayload{AppID: appID, AppName: newAppName, OldName: appName})
+	})
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
@@ -81,36 +131,85 @@ func (s *Storage) UpdApp(ctx context.Context, appName, newAppName, newAppSecret
 	return nil
 }
 
+// delAppStmts remove everything linked to the app before the app itself.
+// Webhooks with pending deliveries are only marked, they are removed once
+// app.deleted and earlier events are delivered
+var delAppStmts = []string{
+	`DELETE FROM creators WHERE app_id = $1`,
+	`UPDATE webhooks SET deleted_at = now() WHERE app_id = $1`,
+	`DELETE FROM webhooks w WHERE app_id = $1 AND NOT EXISTS (SELECT FROM webhook_deliveries d WHERE d.webhook_id = w.id)`,
+	`DELETE FROM event_apps WHERE app_id = $1`,
+	`DELETE FROM app_hooks WHERE app_id = $1`,
+	`DELETE FROM sessions WHERE app_id = $1`,
+	`DELETE FROM user_metadata WHERE app_id = $1`,
+	`DELETE FROM admins WHERE app_id = $1`,
+	`DELETE FROM supports WHERE app_id = $1`,
+	`DELETE FROM app_users WHERE app_id = $1`,
+	`DELETE FROM api_keys WHERE uid IN (SELECT uid FROM service_accounts WHERE app_id = $1)`,
+	`UPDATE users SET status = 'deleted' WHERE id IN (SELECT uid FROM service_accounts WHERE app_id = $1)`,
+	`DELETE FROM service_accounts WHERE app_id = $1`,
+	`DELETE FROM apps WHERE id = $1`,
+}
+
+// DelApp removes the app with its roles, metadata and webhooks, emits app.deleted
 func (s *Storage) DelApp(ctx context.Context, appName string) error {
 	const op = "storage.postgres.DelApp"
 
-	stmt := `SELECT id FROM apps WHERE name = $1`
-	var appID int
-	err := s.db.QueryRow(ctx, stmt, appName).Scan(&appID)
-	if err != nil {
-		if IsNotFoundError(err) {
-			return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		stmt := `SELECT id FROM apps WHERE name = $1 FOR UPDATE`
+		var appID int
+		if err := tx.QueryRow(ctx, stmt, appName).Scan(&appID); err != nil {
+			if IsNotFoundError(err) {
+				return storage.ErrAppNotFound
+			}
+			return err
+		}
+		// deliveries are scheduled while the webhooks of the app are still there
+		if err := enqueue(ctx, tx, models.EventAppDeleted, appID, 0, appPayload{AppID: appID, AppName: appName}); err != nil {
+			return err
 		}
-		return fmt.Errorf("%s: %w", op, err)
-	}
 
-	stmt = `DELETE FROM creators WHERE app_id = $1`
-	_, err = s.db.Exec(ctx, stmt, appID)
+		for _, stmt := range delAppStmts {
+			if _, err := tx.Exec(ctx, stmt, appID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	return nil
+}
 
-	stmt = `DELETE FROM admins WHERE app_id = $1`
-	_, err = s.db.Exec(ctx, stmt, appID)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+func (s *Storage) ListApps(ctx context.Context, filter models.ListFilter, creatorEmail string) ([]models.App, error) {
+	const op = "storage.postgres.ListApps"
+
+	var k keyset
+	from := ` FROM apps a`
+	if creatorEmail != "" {
+		from += ` JOIN creators c ON c.app_id = a.id JOIN users u ON u.id = c.uid`
+		k.add("u.email = $%d", creatorEmail)
 	}
+	k.filter(filter, "a.id", "a.name", "a.created_at")
+	stmt := `SELECT a.id, a.name, a.created_at` + from + k.tail(filter.Limit, "a.id")
 
-	stmt = `DELETE FROM apps WHERE name = $1`
-	_, err = s.db.Exec(ctx, stmt, appName)
+	rows, err := s.db.Query(ctx, stmt, k.args...)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
+	defer rows.Close()
 
-	return nil
+	var apps []models.App
+	for rows.Next() {
+		var app models.App
+		if err := rows.Scan(&app.ID, &app.Name, &app.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		apps = append(apps, app)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return apps, nil
 }
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/hashchain"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// auditLockID serializes appends so that every entry is chained to the latest one
+const auditLockID = 0x61756469
+
+const auditColumns = `id, created_at, action, actor_id, actor_kind, operator_id, target, app_name, ip, outcome, reason, prev_hash, hash`
+
+func (s *Storage) AppendAudit(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	const op = "storage.postgres.AppendAudit"
+
+	// postgres keeps microseconds, the hash must cover the stored value
+	entry.CreatedAt = entry.CreatedAt.UTC().Truncate(time.Microsecond)
+	if entry.ActorKind == "" {
+		entry.ActorKind = models.UserHuman
+	}
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, auditLockID); err != nil {
+			return err
+		}
+
+		entry.PrevHash = []byte{}
+		stmt := `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`
+		err := tx.QueryRow(ctx, stmt).Scan(&entry.PrevHash)
+		if err != nil && !IsNotFoundError(err) {
+			return err
+		}
+		entry.Hash = hashchain.Sum(entry.PrevHash, entry)
+
+		stmt = `INSERT INTO audit_log (created_at, action, actor_id, actor_kind, operator_id, target, app_name, ip, outcome, reason, prev_hash, hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+		return tx.QueryRow(ctx, stmt, entry.CreatedAt, entry.Action, entry.ActorID, entry.ActorKind, entry.OperatorID, entry.Target, entry.AppName,
+			entry.IP, entry.Outcome, entry.Reason, entry.PrevHash, entry.Hash).Scan(&entry.ID)
+	})
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return entry, nil
+}
+
+// QueryAudit returns entries matching the filter, newest first
+func (s *Storage) QueryAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const op = "storage.postgres.QueryAudit"
+
+	var k keyset
+	if filter.AppName != "" {
+		k.add("app_name = $%d", filter.AppName)
+	}
+	if filter.Action != "" {
+		k.add("action = $%d", filter.Action)
+	}
+	if filter.ActorID != 0 {
+		k.add("actor_id = $%d", filter.ActorID)
+	}
+	if filter.ActorKind != "" {
+		k.add("actor_kind = $%d", filter.ActorKind)
+	}
+	if filter.OperatorID != 0 {
+		k.add("operator_id = $%d", filter.OperatorID)
+	}
+	if filter.Outcome != "" {
+		k.add("outcome = $%d", filter.Outcome)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		k.add("created_at >= $%d", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		k.add("created_at < $%d", filter.CreatedBefore)
+	}
+	if filter.BeforeID != 0 {
+		k.add("id < $%d", filter.BeforeID)
+	}
+	stmt := `SELECT ` + auditColumns + ` FROM audit_log` + k.tail(filter.Limit, "id DESC")
+
+	rows, err := s.db.Query(ctx, stmt, k.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	entries, err := pgx.CollectRows(rows, scanAudit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return entries, nil
+}
+
+// ListUserAudit returns all entries made by the user, also while impersonating
+// someone, or with the target, oldest first
+func (s *Storage) ListUserAudit(ctx context.Context, userID uint64, target string) ([]models.AuditEntry, error) {
+	const op = "storage.postgres.ListUserAudit"
+
+	stmt := `SELECT ` + auditColumns + ` FROM audit_log WHERE actor_id = $1 OR operator_id = $1 OR target = $2 ORDER BY id`
+	rows, err := s.db.Query(ctx, stmt, userID, target)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	entries, err := pgx.CollectRows(rows, scanAudit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return entries, nil
+}
+
+// VerifyAudit walks the whole chain and returns the number of checked entries.
+// storage.ErrAuditTampered is returned with the id of the first broken entry
+func (s *Storage) VerifyAudit(ctx context.Context) (int, error) {
+	const op = "storage.postgres.VerifyAudit"
+
+	rows, err := s.db.Query(ctx, `SELECT `+auditColumns+` FROM audit_log ORDER BY id`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	checked := 0
+	prev := []byte{}
+	for rows.Next() {
+		entry, err := scanAudit(rows)
+		if err != nil {
+			return checked, fmt.Errorf("%s: %w", op, err)
+		}
+		if !hashchain.Verify(prev, entry) {
+			return checked, fmt.Errorf("%s: entry %d: %w", op, entry.ID, storage.ErrAuditTampered)
+		}
+		prev = entry.Hash
+		checked++
+	}
+	if err := rows.Err(); err != nil {
+		return checked, fmt.Errorf("%s: %w", op, err)
+	}
+	return checked, nil
+}
+
+func scanAudit(row pgx.CollectableRow) (models.AuditEntry, error) {
+	var e models.AuditEntry
+	err := row.Scan(&e.ID, &e.CreatedAt, &e.Action, &e.ActorID, &e.ActorKind, &e.OperatorID, &e.Target, &e.AppName, &e.IP,
+		&e.Outcome, &e.Reason, &e.PrevHash, &e.Hash)
+	return e, err
+}
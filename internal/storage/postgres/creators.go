@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
 	"github.com/neepooha/sso/internal/storage"
 )
 
@@ -50,3 +51,13 @@ func (s *Storage) SetCreator(ctx context.Context, userID uint64, appID int) erro
 
 	return nil
 }
+
+func (s *Storage) ListCreators(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, error) {
+	const op = "storage.postgres.ListCreators"
+
+	creators, err := s.listMembers(ctx, "creators", appName, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return creators, nil
+}
@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const eventsChannel = "sso_events"
+
+// visible keeps events of finished transactions only. Every running or later
+// transaction has a txid at or above the xmin of the snapshot, so no event can
+// show up before one a reader has already seen in (txid, id) order
+const visible = `e.txid < pg_snapshot_xmin(pg_current_snapshot())`
+
+// enqueue writes the event of the app to the outbox and schedules its delivery
+// to every matching webhook of the app. It must be called in the transaction of
+// the change itself, so that the event is stored if and only if the change is committed
+func enqueue(ctx context.Context, tx pgx.Tx, eventType string, appID int, userID uint64, payload any) error {
+	eventID, err := insertEvent(ctx, tx, eventType, appID, userID, payload)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO webhook_deliveries (webhook_id, event_id)
+		SELECT id, $1 FROM webhooks
+		WHERE app_id = $2 AND deleted_at IS NULL
+			AND (cardinality(event_types) = 0 OR $3 = ANY(event_types))`
+	if _, err = tx.Exec(ctx, stmt, eventID, appID, eventType); err != nil {
+		return err
+	}
+	return notify(ctx, tx, eventType)
+}
+
+// enqueueUser is enqueue for events of the user, which have no app. They go
+// only to the apps the user is a member of when the event happens
+func enqueueUser(ctx context.Context, tx pgx.Tx, eventType string, userID uint64, payload any) error {
+	eventID, err := insertEvent(ctx, tx, eventType, 0, userID, payload)
+	if err != nil {
+		return err
+	}
+
+	stmt := `INSERT INTO event_apps (event_id, app_id) SELECT $1, app_id FROM (` + fmt.Sprintf(appsOf, 2) + `) m`
+	if _, err = tx.Exec(ctx, stmt, eventID, userID); err != nil {
+		return err
+	}
+	stmt = `INSERT INTO webhook_deliveries (webhook_id, event_id)
+		SELECT w.id, $1 FROM webhooks w JOIN event_apps a ON a.app_id = w.app_id
+		WHERE a.event_id = $1 AND w.deleted_at IS NULL
+			AND (cardinality(w.event_types) = 0 OR $2 = ANY(w.event_types))`
+	if _, err = tx.Exec(ctx, stmt, eventID, eventType); err != nil {
+		return err
+	}
+	return notify(ctx, tx, eventType)
+}
+
+// insertEvent writes the event, userID is the user it is about, 0 for events of apps
+func insertEvent(ctx context.Context, tx pgx.Tx, eventType string, appID int, userID uint64, payload any) (uint64, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	var app, uid any
+	if appID != 0 {
+		app = appID
+	}
+	if userID != 0 {
+		uid = userID
+	}
+	stmt := `INSERT INTO events (type, app_id, uid, payload) VALUES ($1, $2, $3, $4) RETURNING id`
+	var eventID uint64
+	if err := tx.QueryRow(ctx, stmt, eventType, app, uid, data).Scan(&eventID); err != nil {
+		return 0, err
+	}
+	return eventID, nil
+}
+
+// notify wakes up listeners of the events on commit only
+func notify(ctx context.Context, tx pgx.Tx, eventType string) error {
+	_, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, eventsChannel, eventType)
+	return err
+}
+
+// ListEvents returns events visible to the app after the given one: events of
+// the app itself and events of users that were its members when the events
+// happened. Events come in (txid, id) order, not in id order. Empty types means all types
+func (s *Storage) ListEvents(ctx context.Context, appID int, afterID uint64, types []string, limit int) ([]models.Event, error) {
+	const op = "storage.postgres.ListEvents"
+
+	if types == nil {
+		types = []string{}
+	}
+	stmt := `SELECT e.id, e.type, COALESCE(e.app_id, 0), e.payload, e.created_at
+		FROM events e
+		WHERE ($2 = 0 OR (e.txid, e.id) > (SELECT txid, id FROM events WHERE id = $2))
+			AND ` + visible + `
+			AND (e.app_id = $1 OR EXISTS (SELECT FROM event_apps a WHERE a.app_id = $1 AND a.event_id = e.id))
+			AND (cardinality($3::TEXT[]) = 0 OR e.type = ANY($3))
+		ORDER BY e.txid, e.id LIMIT $4`
+	rows, err := s.db.Query(ctx, stmt, appID, afterID, types, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Event, error) {
+		var e models.Event
+		err := row.Scan(&e.ID, &e.Type, &e.AppID, &e.Payload, &e.CreatedAt)
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return events, nil
+}
+
+// LastEventID returns the id of the last visible event in ListEvents order, zero if there are none
+func (s *Storage) LastEventID(ctx context.Context) (uint64, error) {
+	const op = "storage.postgres.LastEventID"
+
+	var id uint64
+	stmt := `SELECT COALESCE((SELECT e.id FROM events e WHERE ` + visible + ` ORDER BY e.txid DESC, e.id DESC LIMIT 1), 0)`
+	err := s.db.QueryRow(ctx, stmt).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// ListenEvents holds a connection listening for committed events and calls
+// notify for each of them. It blocks until ctx is done or the connection fails
+func (s *Storage) ListenEvents(ctx context.Context, notify func()) error {
+	const op = "storage.postgres.ListenEvents"
+
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+eventsChannel); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for {
+		if _, err := conn.Conn().WaitForNotification(ctx); err != nil {
+			if ctx.Err() != nil {
+				// the connection is left in an unknown state after a cancelled wait
+				conn.Hijack().Close(context.Background())
+				return nil
+			}
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		notify()
+	}
+}
+
+type userPayload struct {
+	UserID uint64 `json:"user_id"`
+	Email  string `json:"email,omitempty"`
+}
+
+type emailPayload struct {
+	UserID   uint64 `json:"user_id"`
+	Email    string `json:"email"`
+	OldEmail string `json:"old_email"`
+}
+
+type statusPayload struct {
+	UserID uint64            `json:"user_id"`
+	Status models.UserStatus `json:"status"`
+}
+
+type appPayload struct {
+	AppID   int    `json:"app_id"`
+	AppName string `json:"app_name"`
+	OldName string `json:"old_name,omitempty"`
+}
+
+type adminPayload struct {
+	UserID  uint64 `json:"user_id"`
+	Email   string `json:"email"`
+	AppID   int    `json:"app_id"`
+	AppName string `json:"app_name"`
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+)
+
+// ListMemberships returns every app the user joined or has a role or metadata in
+func (s *Storage) ListMemberships(ctx context.Context, userID uint64) ([]models.AppMembership, error) {
+	const op = "storage.postgres.ListMemberships"
+
+	stmt := `SELECT a.id, a.name,
+			EXISTS (SELECT 1 FROM creators c WHERE c.uid = $1 AND c.app_id = a.id),
+			EXISTS (SELECT 1 FROM admins d WHERE d.uid = $1 AND d.app_id = a.id),
+			COALESCE(m.app_metadata, '{}'), COALESCE(m.user_metadata, '{}')
+		FROM apps a LEFT JOIN user_metadata m ON m.app_id = a.id AND m.uid = $1
+		WHERE m.uid IS NOT NULL
+			OR EXISTS (SELECT 1 FROM app_users j WHERE j.uid = $1 AND j.app_id = a.id)
+			OR EXISTS (SELECT 1 FROM creators c WHERE c.uid = $1 AND c.app_id = a.id)
+			OR EXISTS (SELECT 1 FROM admins d WHERE d.uid = $1 AND d.app_id = a.id)
+		ORDER BY a.id`
+	rows, err := s.db.Query(ctx, stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var memberships []models.AppMembership
+	for rows.Next() {
+		var m models.AppMembership
+		if err := rows.Scan(&m.AppID, &m.AppName, &m.IsCreator, &m.IsAdmin, &m.AppMetadata, &m.UserMetadata); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		memberships = append(memberships, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return memberships, nil
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Ping checks that the database answers
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgres.Ping"
+
+	if err := s.db.Ping(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// MigrationVersion returns the applied schema version and whether the last migration failed halfway
+func (s *Storage) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	const op = "storage.postgres.MigrationVersion"
+
+	var version int64
+	var dirty bool
+	err := s.db.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	return uint(version), dirty, nil
+}
+
+// Stat returns the statistics of the connection pool
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.db.Stat()
+}
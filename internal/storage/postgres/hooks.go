@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SetHooks replaces all hooks of the app
+func (s *Storage) SetHooks(ctx context.Context, appName string, hooks []models.Hook) error {
+	const op = "storage.postgres.SetHooks"
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var appID int
+		err := tx.QueryRow(ctx, `SELECT id FROM apps WHERE name = $1 FOR UPDATE`, appName).Scan(&appID)
+		if err != nil {
+			if IsNotFoundError(err) {
+				return storage.ErrAppNotFound
+			}
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM app_hooks WHERE app_id = $1`, appID); err != nil {
+			return err
+		}
+		stmt := `INSERT INTO app_hooks (app_id, stage, kind, target, timeout_ms, fail_open, position)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		for i, h := range hooks {
+			_, err := tx.Exec(ctx, stmt, appID, h.Stage, h.Kind, h.Target, h.Timeout.Milliseconds(), h.FailOpen, i)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// ListHooks returns hooks of the app in run order. Empty stage means all stages
+func (s *Storage) ListHooks(ctx context.Context, appName string, stage string) ([]models.Hook, error) {
+	const op = "storage.postgres.ListHooks"
+
+	stmt := `SELECT h.id, h.app_id, h.stage, h.kind, h.target, h.timeout_ms, h.fail_open
+		FROM app_hooks h JOIN apps a ON a.id = h.app_id
+		WHERE a.name = $1 AND ($2 = '' OR h.stage = $2)
+		ORDER BY h.position`
+	rows, err := s.db.Query(ctx, stmt, appName, stage)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	hooks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Hook, error) {
+		var h models.Hook
+		var timeoutMs int64
+		err := row.Scan(&h.ID, &h.AppID, &h.Stage, &h.Kind, &h.Target, &timeoutMs, &h.FailOpen)
+		h.Timeout = time.Duration(timeoutMs) * time.Millisecond
+		return h, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return hooks, nil
+}
+
+// HasHooks reports whether any app has hooks of the stage
+func (s *Storage) HasHooks(ctx context.Context, stage string) (bool, error) {
+	const op = "storage.postgres.HasHooks"
+
+	var has bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM app_hooks WHERE stage = $1)`, stage).Scan(&has)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+	return has, nil
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"strings"
+)
+
+// keyset builds the WHERE/ORDER/LIMIT tail shared by all list queries.
+// Rows are always ordered by idCol, which makes AfterID a stable cursor.
+type keyset struct {
+	conds []string
+	args  []any
+}
+
+func (k *keyset) add(cond string, arg any) {
+	k.args = append(k.args, arg)
+	k.conds = append(k.conds, fmt.Sprintf(cond, len(k.args)))
+}
+
+func (k *keyset) filter(f models.ListFilter, idCol, prefixCol, createdCol string) {
+	if f.Prefix != "" {
+		k.add("starts_with("+prefixCol+", $%d)", f.Prefix)
+	}
+	if !f.CreatedAfter.IsZero() {
+		k.add(createdCol+" >= $%d", f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		k.add(createdCol+" < $%d", f.CreatedBefore)
+	}
+	if f.AfterID != 0 {
+		k.add(idCol+" > $%d", f.AfterID)
+	}
+}
+
+func (k *keyset) tail(limit int, orderBy string) string {
+	var b strings.Builder
+	if len(k.conds) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(k.conds, " AND "))
+	}
+	b.WriteString(" ORDER BY " + orderBy)
+	k.args = append(k.args, limit)
+	b.WriteString(fmt.Sprintf(" LIMIT $%d", len(k.args)))
+	return b.String()
+}
+
+// listMembers lists users joined through a role table (admins or creators) of the app
+func (s *Storage) listMembers(ctx context.Context, table string, appName string, filter models.ListFilter) ([]models.Member, error) {
+	stmt := `SELECT id FROM apps WHERE name = $1`
+	var appID int
+	err := s.db.QueryRow(ctx, stmt, appName).Scan(&appID)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return nil, storage.ErrAppNotFound
+		}
+		return nil, err
+	}
+
+	var k keyset
+	k.add("r.app_id = $%d", appID)
+	k.filter(filter, "u.id", "u.email", "r.created_at")
+	stmt = `SELECT u.id, u.email, r.created_at FROM ` + table + ` r JOIN users u ON u.id = r.uid` + k.tail(filter.Limit, "u.id")
+
+	rows, err := s.db.Query(ctx, stmt, k.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.Member
+	for rows.Next() {
+		var member models.Member
+		if err := rows.Scan(&member.UserID, &member.Email, &member.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/storage"
+)
+
+// memberOf matches ids of users that joined the app or hold a role in it,
+// %[1]d is the number of the placeholder of the app id
+const memberOf = `IN (SELECT uid FROM app_users WHERE app_id = $%[1]d
+	UNION SELECT uid FROM creators WHERE app_id = $%[1]d
+	UNION SELECT uid FROM admins WHERE app_id = $%[1]d
+	UNION SELECT uid FROM supports WHERE app_id = $%[1]d)`
+
+// appsOf selects ids of the apps the user joined or holds a role in,
+// %[1]d is the number of the placeholder of the user id
+const appsOf = `SELECT app_id FROM app_users WHERE uid = $%[1]d
+	UNION SELECT app_id FROM creators WHERE uid = $%[1]d
+	UNION SELECT app_id FROM admins WHERE uid = $%[1]d
+	UNION SELECT app_id FROM supports WHERE uid = $%[1]d`
+
+// AddMember makes the user a member of the app, a member stays one
+func (s *Storage) AddMember(ctx context.Context, userID uint64, appID int) error {
+	const op = "storage.postgres.AddMember"
+
+	stmt := `INSERT INTO app_users (uid, app_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	if _, err := s.db.Exec(ctx, stmt, userID, appID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) IsMember(ctx context.Context, userID uint64, appName string) error {
+	const op = "storage.postgres.IsMember"
+
+	stmt := `SELECT id FROM apps WHERE name = $1`
+	var appID int
+	err := s.db.QueryRow(ctx, stmt, appName).Scan(&appID)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt = `SELECT FROM users WHERE id = $1`
+	err = s.db.QueryRow(ctx, stmt, userID).Scan()
+	if err != nil {
+		if IsNotFoundError(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt = `SELECT $1::INTEGER ` + fmt.Sprintf(memberOf, 2)
+	var member bool
+	if err := s.db.QueryRow(ctx, stmt, userID, appID).Scan(&member); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !member {
+		return fmt.Errorf("%s: %w", op, storage.ErrMemberNotFound)
+	}
+	return nil
+}
@@ -5,12 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"github.com/neepooha/sso/internal/config"
+	"github.com/neepooha/sso/internal/storage"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+var _ storage.Storage = (*Storage)(nil)
+
 type Storage struct {
 	db *pgxpool.Pool
 }
@@ -21,7 +24,13 @@ func New(cfg *config.Config) (*Storage, error) {
 	psqlInfo := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		cfg.Storage.Host, cfg.Storage.Port, cfg.Storage.User, cfg.Storage.Password, cfg.Storage.Dbname)
 
-	db, err := pgxpool.New(context.Background(), psqlInfo)
+	poolConfig, err := pgxpool.ParseConfig(psqlInfo)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	poolConfig.ConnConfig.Tracer = queryTracer{dbName: cfg.Storage.Dbname}
+
+	db, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
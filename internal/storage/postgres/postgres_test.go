@@ -0,0 +1,41 @@
+package postgres_test
+
+import (
+	"errors"
+	"github.com/neepooha/sso/internal/config"
+	"github.com/neepooha/sso/internal/lib/migrator"
+	"github.com/neepooha/sso/internal/storage"
+	"github.com/neepooha/sso/internal/storage/postgres"
+	"github.com/neepooha/sso/internal/storage/storagetest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// TestConformance needs a database, set SSO_TEST_CONFIG to a config whose storage
+// may be migrated and written to
+func TestConformance(t *testing.T) {
+	path := os.Getenv("SSO_TEST_CONFIG")
+	if path == "" {
+		t.Skip("SSO_TEST_CONFIG is not set")
+	}
+	cfg := config.MustLoadByPath(path)
+	if !filepath.IsAbs(cfg.Storage.Migrations_path) {
+		// configs are written for the root of the repository
+		cfg.Storage.Migrations_path = filepath.Join("..", "..", "..", cfg.Storage.Migrations_path)
+	}
+	if err := migrator.Migrate(cfg); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatal(err)
+	}
+	s, err := postgres.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return s
+	})
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+)
+
+func (s *Storage) GetProfile(ctx context.Context, userID uint64, appName string) (models.Profile, error) {
+	const op = "storage.postgres.GetProfile"
+
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt := `SELECT a.id, COALESCE(m.app_metadata, '{}'), COALESCE(m.user_metadata, '{}')
+		FROM apps a LEFT JOIN user_metadata m ON m.app_id = a.id AND m.uid = $1
+		WHERE a.name = $2`
+	profile := models.Profile{User: user}
+	err = s.db.QueryRow(ctx, stmt, userID, appName).Scan(&profile.AppID, &profile.AppMetadata, &profile.UserMetadata)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.Profile{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return profile, nil
+}
+
+func (s *Storage) UpdUser(ctx context.Context, userID uint64, upd models.ProfileUpdate) error {
+	const op = "storage.postgres.UpdUser"
+
+	stmt := `UPDATE users SET
+		display_name = COALESCE($1, display_name),
+		locale = COALESCE($2, locale),
+		avatar_url = COALESCE($3, avatar_url),
+		updated_at = now()
+		WHERE id = $4`
+	tag, err := s.db.Exec(ctx, stmt, upd.DisplayName, upd.Locale, upd.AvatarURL, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+func (s *Storage) SetUserMetadata(ctx context.Context, userID uint64, appID int, data []byte) error {
+	const op = "storage.postgres.SetUserMetadata"
+
+	stmt := `INSERT INTO user_metadata (uid, app_id, user_metadata) VALUES ($1, $2, $3)
+		ON CONFLICT (uid, app_id) DO UPDATE SET user_metadata = EXCLUDED.user_metadata, updated_at = now()`
+	_, err := s.db.Exec(ctx, stmt, userID, appID, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) SetAppMetadata(ctx context.Context, userID uint64, appID int, data []byte) error {
+	const op = "storage.postgres.SetAppMetadata"
+
+	stmt := `INSERT INTO user_metadata (uid, app_id, app_metadata) VALUES ($1, $2, $3)
+		ON CONFLICT (uid, app_id) DO UPDATE SET app_metadata = EXCLUDED.app_metadata, updated_at = now()`
+	_, err := s.db.Exec(ctx, stmt, userID, appID, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) GetMetadataSchemas(ctx context.Context, appName string) (models.MetadataSchemas, error) {
+	const op = "storage.postgres.GetMetadataSchemas"
+
+	stmt := `SELECT app_metadata_schema, user_metadata_schema FROM apps WHERE name = $1`
+	var schemas models.MetadataSchemas
+	err := s.db.QueryRow(ctx, stmt, appName).Scan(&schemas.AppMetadata, &schemas.UserMetadata)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.MetadataSchemas{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return models.MetadataSchemas{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return schemas, nil
+}
+
+func (s *Storage) SetMetadataSchemas(ctx context.Context, appName string, schemas models.MetadataSchemas) error {
+	const op = "storage.postgres.SetMetadataSchemas"
+
+	stmt := `UPDATE apps SET app_metadata_schema = $1, user_metadata_schema = $2 WHERE name = $3`
+	tag, err := s.db.Exec(ctx, stmt, nullJSON(schemas.AppMetadata), nullJSON(schemas.UserMetadata), appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	return nil
+}
+
+// nullJSON stores an empty document as SQL NULL
+func nullJSON(data []byte) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// roleTables are the tables holding members of every role
+var roleTables = map[string]string{
+	models.RoleCreator: "creators",
+	models.RoleAdmin:   "admins",
+	models.RoleSupport: "supports",
+}
+
+// GetRoles returns the roles of the user in the app
+func (s *Storage) GetRoles(ctx context.Context, userID uint64, appName string) ([]string, error) {
+	const op = "storage.postgres.GetRoles"
+
+	stmt := `SELECT 'creator' FROM creators c JOIN apps a ON a.id = c.app_id WHERE c.uid = $1 AND a.name = $2
+		UNION SELECT 'admin' FROM admins d JOIN apps a ON a.id = d.app_id WHERE d.uid = $1 AND a.name = $2
+		UNION SELECT 'support' FROM supports s JOIN apps a ON a.id = s.app_id WHERE s.uid = $1 AND a.name = $2`
+	rows, err := s.db.Query(ctx, stmt, userID, appName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	roles, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return roles, nil
+}
+
+// GrantRole gives the role in the app to the user with the email, emits admin.granted for admins
+func (s *Storage) GrantRole(ctx context.Context, email string, appName string, role string) error {
+	const op = "storage.postgres.GrantRole"
+
+	table, ok := roleTables[role]
+	if !ok {
+		return fmt.Errorf("%s: unknown role %q", op, role)
+	}
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		uid, appID, err := userAndApp(ctx, tx, email, appName)
+		if err != nil {
+			return err
+		}
+
+		stmt := fmt.Sprintf(`SELECT FROM %s WHERE uid = $1 AND app_id = $2`, table)
+		err = tx.QueryRow(ctx, stmt, uid, appID).Scan()
+		if err == nil {
+			return storage.ErrRoleExists
+		}
+		if !IsNotFoundError(err) {
+			return err
+		}
+
+		stmt = fmt.Sprintf(`INSERT INTO %s (uid, app_id) VALUES ($1, $2)`, table)
+		if _, err := tx.Exec(ctx, stmt, uid, appID); err != nil {
+			return err
+		}
+		if role == models.RoleAdmin {
+			return enqueue(ctx, tx, models.EventAdminGranted, appID, uid, adminPayload{UserID: uid, Email: email, AppID: appID, AppName: appName})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RevokeRole takes the role in the app from the user with the email, emits admin.revoked for admins.
+// The last creator of an app can't be removed
+func (s *Storage) RevokeRole(ctx context.Context, email string, appName string, role string) error {
+	const op = "storage.postgres.RevokeRole"
+
+	table, ok := roleTables[role]
+	if !ok {
+		return fmt.Errorf("%s: unknown role %q", op, role)
+	}
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		uid, appID, err := userAndApp(ctx, tx, email, appName)
+		if err != nil {
+			return err
+		}
+
+		stmt := fmt.Sprintf(`DELETE FROM %s WHERE uid = $1 AND app_id = $2`, table)
+		tag, err := tx.Exec(ctx, stmt, uid, appID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return storage.ErrRoleNotFound
+		}
+
+		switch role {
+		case models.RoleCreator:
+			var left int
+			if err := tx.QueryRow(ctx, `SELECT count(*) FROM creators WHERE app_id = $1`, appID).Scan(&left); err != nil {
+				return err
+			}
+			if left == 0 {
+				return storage.ErrLastCreator
+			}
+		case models.RoleAdmin:
+			return enqueue(ctx, tx, models.EventAdminRevoked, appID, uid, adminPayload{UserID: uid, Email: email, AppID: appID, AppName: appName})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// userAndApp returns ids of the user and the app. The app row is locked, so role
+// changes of one app are serialized and two creators can't remove each other at once
+func userAndApp(ctx context.Context, tx pgx.Tx, email string, appName string) (uint64, int, error) {
+	var uid uint64
+	err := tx.QueryRow(ctx, `SELECT id FROM users WHERE email = $1`, email).Scan(&uid)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return 0, 0, storage.ErrUserNotFound
+		}
+		return 0, 0, err
+	}
+
+	var appID int
+	err = tx.QueryRow(ctx, `SELECT id FROM apps WHERE name = $1 FOR UPDATE`, appName).Scan(&appID)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return 0, 0, storage.ErrAppNotFound
+		}
+		return 0, 0, err
+	}
+	return uid, appID, nil
+}
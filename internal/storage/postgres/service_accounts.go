@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const serviceAccountColumns = `u.id, u.email, sa.name, sa.app_id, a.name, sa.created_by, u.status, u.created_at`
+
+const serviceAccountFrom = ` FROM service_accounts sa JOIN users u ON u.id = sa.uid JOIN apps a ON a.id = sa.app_id`
+
+// CreateServiceAccount saves a user of kind service without password and links it to the app
+func (s *Storage) CreateServiceAccount(ctx context.Context, appName string, name string, clientID string, createdBy uint64) (models.ServiceAccount, error) {
+	const op = "storage.postgres.CreateServiceAccount"
+
+	account := models.ServiceAccount{ClientID: clientID, Name: name, AppName: appName, CreatedBy: createdBy, Status: models.UserActive}
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `SELECT id FROM apps WHERE name = $1`, appName).Scan(&account.AppID)
+		if err != nil {
+			if IsNotFoundError(err) {
+				return storage.ErrAppNotFound
+			}
+			return err
+		}
+
+		stmt := `INSERT INTO users (email, pass_hash, kind) VALUES ($1, '', $2) RETURNING id, created_at`
+		if err := tx.QueryRow(ctx, stmt, clientID, models.UserService).Scan(&account.UserID, &account.CreatedAt); err != nil {
+			return err
+		}
+		stmt = `INSERT INTO service_accounts (uid, app_id, name, created_by) VALUES ($1, $2, $3, $4)`
+		_, err = tx.Exec(ctx, stmt, account.UserID, account.AppID, name, createdBy)
+		return err
+	})
+	if err != nil {
+		if IsDuplicatedKeyError(err) {
+			return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, storage.ErrServiceAccountExists)
+		}
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return account, nil
+}
+
+// GetServiceAccount returns the service account of the user
+func (s *Storage) GetServiceAccount(ctx context.Context, userID uint64) (models.ServiceAccount, error) {
+	const op = "storage.postgres.GetServiceAccount"
+
+	stmt := `SELECT ` + serviceAccountColumns + serviceAccountFrom + ` WHERE u.id = $1`
+	rows, err := s.db.Query(ctx, stmt, userID)
+	if err != nil {
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+	account, err := pgx.CollectExactlyOneRow(rows, scanServiceAccount)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, storage.ErrServiceAccountNotFound)
+		}
+		return models.ServiceAccount{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return account, nil
+}
+
+// ListServiceAccounts returns service accounts of the app, deleted ones are left out
+func (s *Storage) ListServiceAccounts(ctx context.Context, appName string, filter models.ListFilter) ([]models.ServiceAccount, error) {
+	const op = "storage.postgres.ListServiceAccounts"
+
+	var k keyset
+	k.add("a.name = $%d", appName)
+	k.add("u.status <> $%d", models.UserDeleted)
+	k.filter(filter, "u.id", "sa.name", "u.created_at")
+	stmt := `SELECT ` + serviceAccountColumns + serviceAccountFrom + k.tail(filter.Limit, "u.id")
+
+	rows, err := s.db.Query(ctx, stmt, k.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	accounts, err := pgx.CollectRows(rows, scanServiceAccount)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return accounts, nil
+}
+
+// DeleteServiceAccount marks the service account deleted, revokes its keys and removes its roles
+func (s *Storage) DeleteServiceAccount(ctx context.Context, userID uint64) error {
+	const op = "storage.postgres.DeleteServiceAccount"
+
+	stmts := []string{
+		`UPDATE api_keys SET revoked_at = now() WHERE uid = $1 AND revoked_at IS NULL`,
+		`DELETE FROM admins WHERE uid = $1`,
+		`DELETE FROM creators WHERE uid = $1`,
+		`DELETE FROM supports WHERE uid = $1`,
+	}
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		stmt := `UPDATE users SET status = $1, updated_at = now() WHERE id = $2 AND kind = $3 AND status <> $1`
+		tag, err := tx.Exec(ctx, stmt, models.UserDeleted, userID, models.UserService)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return storage.ErrServiceAccountNotFound
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.Exec(ctx, stmt, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// CreateAPIKey saves the hash of a new key of the service account
+func (s *Storage) CreateAPIKey(ctx context.Context, key models.APIKey, keyHash string) (uint64, error) {
+	const op = "storage.postgres.CreateAPIKey"
+
+	stmt := `INSERT INTO api_keys (uid, key_hash, prefix, created_at, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	var id uint64
+	err := s.db.QueryRow(ctx, stmt, key.UserID, keyHash, key.Prefix, key.CreatedAt, nullTime(key.ExpiresAt)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// GetAPIKey returns the key with the hash and marks it used
+func (s *Storage) GetAPIKey(ctx context.Context, keyHash string) (models.APIKey, error) {
+	const op = "storage.postgres.GetAPIKey"
+
+	stmt := `UPDATE api_keys SET last_used_at = now() WHERE key_hash = $1
+		RETURNING id, uid, prefix, created_at, expires_at, last_used_at, revoked_at IS NOT NULL`
+	var key models.APIKey
+	var expiresAt *time.Time
+	err := s.db.QueryRow(ctx, stmt, keyHash).Scan(&key.ID, &key.UserID, &key.Prefix, &key.CreatedAt,
+		&expiresAt, &key.LastUsedAt, &key.Revoked)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.APIKey{}, fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+		}
+		return models.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if expiresAt != nil {
+		key.ExpiresAt = *expiresAt
+	}
+	return key, nil
+}
+
+// RevokeAPIKey revokes the key of the service account
+func (s *Storage) RevokeAPIKey(ctx context.Context, userID uint64, keyID uint64) error {
+	const op = "storage.postgres.RevokeAPIKey"
+
+	stmt := `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND uid = $2 AND revoked_at IS NULL`
+	tag, err := s.db.Exec(ctx, stmt, keyID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+	return nil
+}
+
+func scanServiceAccount(row pgx.CollectableRow) (models.ServiceAccount, error) {
+	var a models.ServiceAccount
+	err := row.Scan(&a.UserID, &a.ClientID, &a.Name, &a.AppID, &a.AppName, &a.CreatedBy, &a.Status, &a.CreatedAt)
+	return a, err
+}
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// sessionsLock is the first key of the per-user advisory lock taken while
+// sessions of the user are created, so concurrent logins can't both pass the limit
+const sessionsLock = 0x73657373
+
+// CreateSession saves a new session. When maxSessions is positive, the oldest live
+// sessions of the user in the app are revoked so that at most maxSessions stay
+func (s *Storage) CreateSession(ctx context.Context, session models.Session, maxSessions int) (uint64, error) {
+	const op = "storage.postgres.CreateSession"
+
+	var id uint64
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, sessionsLock, int32(session.UserID)); err != nil {
+			return err
+		}
+
+		stmt := `INSERT INTO sessions (uid, app_id, refresh_hash, ip, refresh_expires_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+		err := tx.QueryRow(ctx, stmt, session.UserID, session.AppID, session.RefreshHash, session.IP,
+			session.RefreshExpiresAt, nullTime(session.ExpiresAt)).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if maxSessions <= 0 {
+			return nil
+		}
+
+		stmt = `UPDATE sessions SET revoked_at = now() WHERE id IN (
+			SELECT id FROM sessions
+			WHERE uid = $1 AND app_id = $2 AND revoked_at IS NULL
+				AND refresh_expires_at > now() AND (expires_at IS NULL OR expires_at > now())
+			ORDER BY created_at DESC, id DESC
+			OFFSET $3)`
+		_, err = tx.Exec(ctx, stmt, session.UserID, session.AppID, maxSessions)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// GetSession returns the session of the refresh token hash. A session found by
+// its previous refresh token is returned with Reused set
+func (s *Storage) GetSession(ctx context.Context, refreshHash string) (models.Session, error) {
+	const op = "storage.postgres.GetSession"
+
+	stmt := `SELECT id, uid, app_id, refresh_hash, ip, created_at, last_used_at,
+		refresh_expires_at, expires_at, revoked_at IS NOT NULL, refresh_hash <> $1
+		FROM sessions WHERE refresh_hash = $1 OR prev_refresh_hash = $1
+		LIMIT 1`
+	var session models.Session
+	var expiresAt *time.Time
+	err := s.db.QueryRow(ctx, stmt, refreshHash).Scan(&session.ID, &session.UserID, &session.AppID,
+		&session.RefreshHash, &session.IP, &session.CreatedAt, &session.LastUsedAt,
+		&session.RefreshExpiresAt, &expiresAt, &session.Revoked, &session.Reused)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+		}
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if expiresAt != nil {
+		session.ExpiresAt = *expiresAt
+	}
+	return session, nil
+}
+
+// RotateSession replaces the refresh token of a live session. It fails with
+// ErrSessionNotFound when the token was already rotated by a concurrent refresh
+func (s *Storage) RotateSession(ctx context.Context, id uint64, oldHash, newHash string, refreshExpiresAt time.Time) error {
+	const op = "storage.postgres.RotateSession"
+
+	stmt := `UPDATE sessions SET refresh_hash = $1, prev_refresh_hash = refresh_hash,
+		refresh_expires_at = $2, last_used_at = now()
+		WHERE id = $3 AND refresh_hash = $4 AND revoked_at IS NULL`
+	tag, err := s.db.Exec(ctx, stmt, newHash, refreshExpiresAt, id, oldHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+	return nil
+}
+
+// RevokeSession ends the session, revoking an already revoked session is not an error
+func (s *Storage) RevokeSession(ctx context.Context, id uint64) error {
+	const op = "storage.postgres.RevokeSession"
+
+	stmt := `UPDATE sessions SET revoked_at = coalesce(revoked_at, now()) WHERE id = $1`
+	tag, err := s.db.Exec(ctx, stmt, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+	return nil
+}
+
+// ListUserSessions returns all sessions of the user, newest first
+func (s *Storage) ListUserSessions(ctx context.Context, userID uint64) ([]models.Session, error) {
+	const op = "storage.postgres.ListUserSessions"
+
+	stmt := `SELECT id, uid, app_id, ip, created_at, last_used_at, refresh_expires_at,
+		expires_at, revoked_at IS NOT NULL
+		FROM sessions WHERE uid = $1 ORDER BY created_at DESC, id DESC`
+	rows, err := s.db.Query(ctx, stmt, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Session, error) {
+		var session models.Session
+		var expiresAt *time.Time
+		err := row.Scan(&session.ID, &session.UserID, &session.AppID, &session.IP, &session.CreatedAt,
+			&session.LastUsedAt, &session.RefreshExpiresAt, &expiresAt, &session.Revoked)
+		if expiresAt != nil {
+			session.ExpiresAt = *expiresAt
+		}
+		return session, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return sessions, nil
+}
+
+// DeleteEndedSessions removes sessions that were revoked or expired before the given time
+func (s *Storage) DeleteEndedSessions(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.postgres.DeleteEndedSessions"
+
+	stmt := `DELETE FROM sessions
+		WHERE revoked_at < $1 OR refresh_expires_at < $1 OR expires_at < $1`
+	tag, err := s.db.Exec(ctx, stmt, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/lib/tracing"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer starts a span for every query sent through the pool, including the
+// BEGIN and COMMIT of transactions. Only the statement is recorded, never the arguments
+type queryTracer struct {
+	dbName string
+}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := verb(data.SQL)
+	ctx, _ = tracing.Start(ctx, "postgres "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBName(t.dbName),
+			semconv.DBOperation(operation),
+			semconv.DBStatement(data.SQL),
+		),
+	)
+	return ctx
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err == nil {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	tracing.End(span, data.Err)
+}
+
+// verb returns the first keyword of the statement to name the span, SELECT or UPDATE
+func verb(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "query"
+	}
+	return strings.ToUpper(fields[0])
+}
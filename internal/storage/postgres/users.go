@@ -8,15 +8,28 @@ import (
 	"github.com/neepooha/sso/internal/storage"
 
 	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
-func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (uint64, error) {
+func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte, appID int) (uint64, error) {
 	const op = "storage.postgres.SaveUser"
 
 	stmt := `INSERT INTO users (email, pass_hash) VALUES($1, $2) RETURNING id`
 	var uid uint64
-	err := s.db.QueryRow(ctx, stmt, email, passHash).Scan(&uid)
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, stmt, email, passHash).Scan(&uid); err != nil {
+			return err
+		}
+		// the user joins the app first, so that the app gets user.registered
+		if appID != 0 {
+			stmt := `INSERT INTO app_users (uid, app_id) VALUES ($1, $2)`
+			if _, err := tx.Exec(ctx, stmt, uid, appID); err != nil {
+				return err
+			}
+		}
+		return enqueueUser(ctx, tx, models.EventUserRegistered, uid, userPayload{UserID: uid, Email: email})
+	})
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
@@ -29,10 +42,11 @@ func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte) (
 
 func (s *Storage) GetUser(ctx context.Context, email string) (models.User, error) {
 	const op = "storage.postgres.GetUser"
-	stmt := `SELECT id, email, pass_hash FROM users WHERE email = $1`
+	stmt := `SELECT id, kind, email, pass_hash, display_name, locale, avatar_url, status, created_at, updated_at FROM users WHERE email = $1`
 
 	var user models.User
-	err := s.db.QueryRow(context.Background(), stmt, email).Scan(&user.ID, &user.Email, &user.PassHash)
+	err := s.db.QueryRow(ctx, stmt, email).Scan(&user.ID, &user.Kind, &user.Email, &user.PassHash,
+		&user.DisplayName, &user.Locale, &user.AvatarURL, &user.Status, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		if IsNotFoundError(err) {
 			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
@@ -42,3 +56,87 @@ func (s *Storage) GetUser(ctx context.Context, email string) (models.User, error
 
 	return user, nil
 }
+
+func (s *Storage) GetUserByID(ctx context.Context, userID uint64) (models.User, error) {
+	const op = "storage.postgres.GetUserByID"
+	stmt := `SELECT id, kind, email, pass_hash, display_name, locale, avatar_url, status, created_at, updated_at FROM users WHERE id = $1`
+
+	var user models.User
+	err := s.db.QueryRow(ctx, stmt, userID).Scan(&user.ID, &user.Kind, &user.Email, &user.PassHash,
+		&user.DisplayName, &user.Locale, &user.AvatarURL, &user.Status, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) ListUsers(ctx context.Context, appName string, filter models.ListFilter) ([]models.User, error) {
+	const op = "storage.postgres.ListUsers"
+
+	var appID int
+	err := s.db.QueryRow(ctx, `SELECT id FROM apps WHERE name = $1`, appName).Scan(&appID)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var k keyset
+	k.add("kind = $%d", models.UserHuman)
+	k.add("id "+memberOf, appID)
+	k.filter(filter, "id", "email", "created_at")
+	stmt := `SELECT id, email, status, created_at FROM users` + k.tail(filter.Limit, "id")
+
+	rows, err := s.db.Query(ctx, stmt, k.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Status, &user.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return users, nil
+}
+
+// UpdEmail changes the email of the user and emits user.email_changed
+func (s *Storage) UpdEmail(ctx context.Context, userID uint64, email string) error {
+	const op = "storage.postgres.UpdEmail"
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var oldEmail string
+		stmt := `SELECT email FROM users WHERE id = $1 FOR UPDATE`
+		if err := tx.QueryRow(ctx, stmt, userID).Scan(&oldEmail); err != nil {
+			if IsNotFoundError(err) {
+				return storage.ErrUserNotFound
+			}
+			return err
+		}
+
+		stmt = `UPDATE users SET email = $1, updated_at = now() WHERE id = $2`
+		if _, err := tx.Exec(ctx, stmt, email, userID); err != nil {
+			return err
+		}
+		return enqueueUser(ctx, tx, models.EventUserEmailChanged, userID, emailPayload{UserID: userID, Email: email, OldEmail: oldEmail})
+	})
+	if err != nil {
+		if IsDuplicatedKeyError(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Storage) SetWebhook(ctx context.Context, appName string, url string, secret string, eventTypes []string) (models.Webhook, error) {
+	const op = "storage.postgres.SetWebhook"
+
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	stmt := `INSERT INTO webhooks (app_id, url, secret, event_types)
+		SELECT id, $2, $3, $4 FROM apps WHERE name = $1
+		RETURNING id, app_id, url, secret, event_types, created_at`
+	var w models.Webhook
+	err := s.db.QueryRow(ctx, stmt, appName, url, secret, eventTypes).
+		Scan(&w.ID, &w.AppID, &w.URL, &w.Secret, &w.EventTypes, &w.CreatedAt)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.Webhook{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return models.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return w, nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context, appName string) ([]models.Webhook, error) {
+	const op = "storage.postgres.ListWebhooks"
+
+	stmt := `SELECT w.id, w.app_id, w.url, w.secret, w.event_types, w.created_at
+		FROM webhooks w JOIN apps a ON a.id = w.app_id
+		WHERE a.name = $1 ORDER BY w.id`
+	rows, err := s.db.Query(ctx, stmt, appName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	webhooks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Webhook, error) {
+		var w models.Webhook
+		err := row.Scan(&w.ID, &w.AppID, &w.URL, &w.Secret, &w.EventTypes, &w.CreatedAt)
+		return w, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return webhooks, nil
+}
+
+func (s *Storage) DelWebhook(ctx context.Context, appName string, webhookID int) error {
+	const op = "storage.postgres.DelWebhook"
+
+	stmt := `DELETE FROM webhooks w USING apps a WHERE a.id = w.app_id AND a.name = $1 AND w.id = $2`
+	tag, err := s.db.Exec(ctx, stmt, appName, webhookID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+	}
+	return nil
+}
+
+// ClaimDeliveries returns due deliveries and hides them from other workers for the lease time
+func (s *Storage) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.Delivery, error) {
+	const op = "storage.postgres.ClaimDeliveries"
+
+	stmt := `WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE next_attempt_at <= now()
+			ORDER BY id LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d SET next_attempt_at = now() + $2::INTERVAL
+			FROM due WHERE d.id = due.id
+			RETURNING d.id, d.webhook_id, d.event_id, d.attempts
+		)
+		SELECT c.id, c.attempts, w.id, w.app_id, w.url, w.secret, e.id, e.type, COALESCE(e.app_id, 0), e.payload, e.created_at
+		FROM claimed c
+		JOIN webhooks w ON w.id = c.webhook_id
+		JOIN events e ON e.id = c.event_id
+		ORDER BY c.id`
+	rows, err := s.db.Query(ctx, stmt, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	deliveries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Delivery, error) {
+		var d models.Delivery
+		err := row.Scan(&d.ID, &d.Attempts, &d.Webhook.ID, &d.Webhook.AppID, &d.Webhook.URL, &d.Webhook.Secret,
+			&d.Event.ID, &d.Event.Type, &d.Event.AppID, &d.Event.Payload, &d.Event.CreatedAt)
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return deliveries, nil
+}
+
+func (s *Storage) DeliverySucceeded(ctx context.Context, deliveryID uint64) error {
+	const op = "storage.postgres.DeliverySucceeded"
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		stmt := `DELETE FROM webhook_deliveries WHERE id = $1 RETURNING webhook_id`
+		return dropDeletedWebhook(ctx, tx, tx.QueryRow(ctx, stmt, deliveryID))
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) DeliveryFailed(ctx context.Context, deliveryID uint64, lastError string, nextAttempt time.Time) error {
+	const op = "storage.postgres.DeliveryFailed"
+
+	stmt := `UPDATE webhook_deliveries SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1`
+	_, err := s.db.Exec(ctx, stmt, deliveryID, lastError, nextAttempt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// DeadLetter moves the delivery to the dead-letter table
+func (s *Storage) DeadLetter(ctx context.Context, deliveryID uint64, lastError string) error {
+	const op = "storage.postgres.DeadLetter"
+
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		stmt := `WITH d AS (DELETE FROM webhook_deliveries WHERE id = $1 RETURNING webhook_id, event_id, attempts)
+			INSERT INTO webhook_dead_letters (webhook_id, event_id, attempts, last_error)
+			SELECT webhook_id, event_id, attempts + 1, $2 FROM d
+			RETURNING webhook_id`
+		return dropDeletedWebhook(ctx, tx, tx.QueryRow(ctx, stmt, deliveryID, lastError))
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// dropDeletedWebhook removes the webhook of a deleted app, scanned from row,
+// once it has nothing left to deliver. Its dead letters go with it
+func dropDeletedWebhook(ctx context.Context, tx pgx.Tx, row pgx.Row) error {
+	var webhookID int
+	if err := row.Scan(&webhookID); err != nil {
+		if IsNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+	stmt := `DELETE FROM webhooks w WHERE id = $1 AND deleted_at IS NOT NULL
+		AND NOT EXISTS (SELECT FROM webhook_deliveries d WHERE d.webhook_id = w.id)`
+	_, err := tx.Exec(ctx, stmt, webhookID)
+	return err
+}
+
+func (s *Storage) ListDeadLetters(ctx context.Context, appName string, filter models.ListFilter) ([]models.DeadLetter, error) {
+	const op = "storage.postgres.ListDeadLetters"
+
+	var k keyset
+	k.add("a.name = $%d", appName)
+	if filter.AfterID != 0 {
+		k.add("l.id > $%d", filter.AfterID)
+	}
+	stmt := `SELECT l.id, l.webhook_id, l.event_id, e.type, l.attempts, l.last_error, l.failed_at
+		FROM webhook_dead_letters l
+		JOIN webhooks w ON w.id = l.webhook_id
+		JOIN apps a ON a.id = w.app_id
+		JOIN events e ON e.id = l.event_id` + k.tail(filter.Limit, "l.id")
+	rows, err := s.db.Query(ctx, stmt, k.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	letters, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.DeadLetter, error) {
+		var l models.DeadLetter
+		err := row.Scan(&l.ID, &l.WebhookID, &l.EventID, &l.EventType, &l.Attempts, &l.LastError, &l.FailedAt)
+		return l, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return letters, nil
+}
+
+// ReplayDeadLetters schedules dead letters of the app for delivery again.
+// Empty ids replays all dead letters of the app
+func (s *Storage) ReplayDeadLetters(ctx context.Context, appName string, ids []uint64) (int, error) {
+	const op = "storage.postgres.ReplayDeadLetters"
+
+	stmt := `WITH l AS (
+			DELETE FROM webhook_dead_letters l USING webhooks w, apps a
+			WHERE w.id = l.webhook_id AND a.id = w.app_id AND a.name = $1
+				AND (cardinality($2::BIGINT[]) = 0 OR l.id = ANY($2))
+			RETURNING l.webhook_id, l.event_id
+		)
+		INSERT INTO webhook_deliveries (webhook_id, event_id) SELECT webhook_id, event_id FROM l`
+	if ids == nil {
+		ids = []uint64{}
+	}
+	tag, err := s.db.Exec(ctx, stmt, appName, ids)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,200 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+)
+
+func (s *Storage) GetAppID(ctx context.Context, appName string) (models.App, error) {
+	const op = "storage.sqlite.GetAppID"
+
+	var app models.App
+	err := s.db.QueryRowContext(ctx, `SELECT id, name FROM apps WHERE name = ?`, appName).Scan(&app.ID, &app.Name)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return app, nil
+}
+
+func (s *Storage) GetApp(ctx context.Context, appName string) (models.App, error) {
+	const op = "storage.sqlite.GetApp"
+	stmt := `SELECT id, name, secret, claim_mapping,
+		access_ttl, refresh_ttl, idle_timeout, absolute_lifetime, max_sessions
+		FROM apps WHERE name = ?`
+
+	var app models.App
+	var claimMapping sql.NullString
+	var accessTTL, refreshTTL, idleTimeout, absoluteLifetime int64
+	err := s.db.QueryRowContext(ctx, stmt, appName).Scan(&app.ID, &app.Name, &app.Secret, &claimMapping,
+		&accessTTL, &refreshTTL, &idleTimeout, &absoluteLifetime, &app.Settings.MaxSessions)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.App{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return models.App{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if claimMapping.Valid {
+		if err := json.Unmarshal([]byte(claimMapping.String), &app.ClaimMapping); err != nil {
+			return models.App{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	app.Settings.AccessTTL = time.Duration(accessTTL) * time.Second
+	app.Settings.RefreshTTL = time.Duration(refreshTTL) * time.Second
+	app.Settings.IdleTimeout = time.Duration(idleTimeout) * time.Second
+	app.Settings.AbsoluteLifetime = time.Duration(absoluteLifetime) * time.Second
+	return app, nil
+}
+
+func (s *Storage) SetApp(ctx context.Context, appName string, appSecret string) (int, error) {
+	const op = "storage.sqlite.SetApp"
+
+	stmt := `INSERT INTO apps (name, secret, created_at) VALUES (?, ?, ?)`
+	res, err := s.db.ExecContext(ctx, stmt, appName, appSecret, unixTime(time.Now()))
+	if err != nil {
+		if IsDuplicatedKeyError(err) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	appID, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return int(appID), nil
+}
+
+// SetClaimMapping replaces the claim mapping of the app, empty mapping removes it
+func (s *Storage) SetClaimMapping(ctx context.Context, appName string, mapping map[string]string) error {
+	const op = "storage.sqlite.SetClaimMapping"
+
+	var value sql.NullString
+	if len(mapping) > 0 {
+		data, err := json.Marshal(mapping)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		value = sql.NullString{String: string(data), Valid: true}
+	}
+	res, err := s.db.ExecContext(ctx, `UPDATE apps SET claim_mapping = ? WHERE name = ?`, value, appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected(res) == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	return nil
+}
+
+// SetAppSettings replaces the token and session settings of the app, durations are stored in seconds
+func (s *Storage) SetAppSettings(ctx context.Context, appName string, settings models.AppSettings) error {
+	const op = "storage.sqlite.SetAppSettings"
+
+	stmt := `UPDATE apps SET access_ttl = ?, refresh_ttl = ?, idle_timeout = ?,
+		absolute_lifetime = ?, max_sessions = ? WHERE name = ?`
+	res, err := s.db.ExecContext(ctx, stmt, int64(settings.AccessTTL.Seconds()), int64(settings.RefreshTTL.Seconds()),
+		int64(settings.IdleTimeout.Seconds()), int64(settings.AbsoluteLifetime.Seconds()), settings.MaxSessions, appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected(res) == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	return nil
+}
+
+// UpdApp renames the app and changes its secret
+func (s *Storage) UpdApp(ctx context.Context, appName, newAppName, newAppSecret string) error {
+	const op = "storage.sqlite.UpdApp"
+
+	res, err := s.db.ExecContext(ctx, `UPDATE apps SET name = ?, secret = ? WHERE name = ?`, newAppName, newAppSecret, appName)
+	if err != nil {
+		if IsDuplicatedKeyError(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrAppExists)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected(res) == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	return nil
+}
+
+// delAppStmts remove everything linked to the app before the app itself
+var delAppStmts = []string{
+	`DELETE FROM creators WHERE app_id = ?`,
+	`DELETE FROM app_hooks WHERE app_id = ?`,
+	`DELETE FROM sessions WHERE app_id = ?`,
+	`DELETE FROM user_metadata WHERE app_id = ?`,
+	`DELETE FROM admins WHERE app_id = ?`,
+	`DELETE FROM supports WHERE app_id = ?`,
+	`DELETE FROM app_users WHERE app_id = ?`,
+	`DELETE FROM apps WHERE id = ?`,
+}
+
+// DelApp removes the app with its roles, metadata, hooks and sessions
+func (s *Storage) DelApp(ctx context.Context, appName string) error {
+	const op = "storage.sqlite.DelApp"
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var appID int
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM apps WHERE name = ?`, appName).Scan(&appID); err != nil {
+			if IsNotFoundError(err) {
+				return storage.ErrAppNotFound
+			}
+			return err
+		}
+
+		for _, stmt := range delAppStmts {
+			if _, err := tx.ExecContext(ctx, stmt, appID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) ListApps(ctx context.Context, filter models.ListFilter, creatorEmail string) ([]models.App, error) {
+	const op = "storage.sqlite.ListApps"
+
+	var k keyset
+	from := ` FROM apps a`
+	if creatorEmail != "" {
+		from += ` JOIN creators c ON c.app_id = a.id JOIN users u ON u.id = c.uid`
+		k.add("u.email = ?", creatorEmail)
+	}
+	k.filter(filter, "a.id", "a.name", "a.created_at")
+	stmt := `SELECT a.id, a.name, a.created_at` + from + k.tail(filter.Limit, "a.id")
+
+	rows, err := s.db.QueryContext(ctx, stmt, k.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var apps []models.App
+	for rows.Next() {
+		var app models.App
+		var createdAt int64
+		if err := rows.Scan(&app.ID, &app.Name, &createdAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		app.CreatedAt = fromUnix(createdAt)
+		apps = append(apps, app)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return apps, nil
+}
@@ -0,0 +1,105 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/lib/hashchain"
+	"time"
+)
+
+const auditColumns = `id, created_at, action, actor_id, actor_kind, operator_id, target, app_name, ip, outcome, reason, prev_hash, hash`
+
+func (s *Storage) AppendAudit(ctx context.Context, entry models.AuditEntry) (models.AuditEntry, error) {
+	const op = "storage.sqlite.AppendAudit"
+
+	// microseconds are stored, the hash must cover the stored value
+	entry.CreatedAt = entry.CreatedAt.UTC().Truncate(time.Microsecond)
+	if entry.ActorKind == "" {
+		entry.ActorKind = models.UserHuman
+	}
+
+	// the transaction holds the write lock, so every entry is chained to the latest one
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		entry.PrevHash = []byte{}
+		err := tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&entry.PrevHash)
+		if err != nil && !IsNotFoundError(err) {
+			return err
+		}
+		entry.Hash = hashchain.Sum(entry.PrevHash, entry)
+
+		stmt := `INSERT INTO audit_log (created_at, action, actor_id, actor_kind, operator_id, target, app_name, ip, outcome, reason, prev_hash, hash)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		res, err := tx.ExecContext(ctx, stmt, unixTime(entry.CreatedAt), entry.Action, entry.ActorID, entry.ActorKind, entry.OperatorID, entry.Target,
+			entry.AppName, entry.IP, entry.Outcome, entry.Reason, entry.PrevHash, entry.Hash)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		entry.ID = uint64(id)
+		return err
+	})
+	if err != nil {
+		return models.AuditEntry{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return entry, nil
+}
+
+// QueryAudit returns entries matching the filter, newest first
+func (s *Storage) QueryAudit(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	const op = "storage.sqlite.QueryAudit"
+
+	var k keyset
+	if filter.AppName != "" {
+		k.add("app_name = ?", filter.AppName)
+	}
+	if filter.Action != "" {
+		k.add("action = ?", filter.Action)
+	}
+	if filter.ActorID != 0 {
+		k.add("actor_id = ?", filter.ActorID)
+	}
+	if filter.ActorKind != "" {
+		k.add("actor_kind = ?", filter.ActorKind)
+	}
+	if filter.OperatorID != 0 {
+		k.add("operator_id = ?", filter.OperatorID)
+	}
+	if filter.Outcome != "" {
+		k.add("outcome = ?", filter.Outcome)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		k.add("created_at >= ?", unixTime(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		k.add("created_at < ?", unixTime(filter.CreatedBefore))
+	}
+	if filter.BeforeID != 0 {
+		k.add("id < ?", filter.BeforeID)
+	}
+	stmt := `SELECT ` + auditColumns + ` FROM audit_log` + k.tail(filter.Limit, "id DESC")
+
+	rows, err := s.db.QueryContext(ctx, stmt, k.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		var createdAt int64
+		err := rows.Scan(&e.ID, &createdAt, &e.Action, &e.ActorID, &e.ActorKind, &e.OperatorID, &e.Target, &e.AppName, &e.IP,
+			&e.Outcome, &e.Reason, &e.PrevHash, &e.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		e.CreatedAt = fromUnix(createdAt).UTC()
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,32 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+)
+
+// Ping checks that the database answers
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.sqlite.Ping"
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// MigrationVersion returns the applied schema version and whether the last migration failed halfway
+func (s *Storage) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	const op = "storage.sqlite.MigrationVersion"
+
+	var version int64
+	var dirty bool
+	err := s.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("%s: %w", op, err)
+	}
+	return uint(version), dirty, nil
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"strings"
+)
+
+// keyset builds the WHERE/ORDER/LIMIT tail shared by all list queries.
+// Rows are always ordered by idCol, which makes AfterID a stable cursor.
+type keyset struct {
+	conds []string
+	args  []any
+}
+
+func (k *keyset) add(cond string, arg any) {
+	k.args = append(k.args, arg)
+	k.conds = append(k.conds, cond)
+}
+
+func (k *keyset) filter(f models.ListFilter, idCol, prefixCol, createdCol string) {
+	if f.Prefix != "" {
+		k.add("instr("+prefixCol+", ?) = 1", f.Prefix)
+	}
+	if !f.CreatedAfter.IsZero() {
+		k.add(createdCol+" >= ?", unixTime(f.CreatedAfter))
+	}
+	if !f.CreatedBefore.IsZero() {
+		k.add(createdCol+" < ?", unixTime(f.CreatedBefore))
+	}
+	if f.AfterID != 0 {
+		k.add(idCol+" > ?", f.AfterID)
+	}
+}
+
+func (k *keyset) tail(limit int, orderBy string) string {
+	var b strings.Builder
+	if len(k.conds) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(k.conds, " AND "))
+	}
+	b.WriteString(" ORDER BY " + orderBy)
+	k.args = append(k.args, limit)
+	b.WriteString(" LIMIT ?")
+	return b.String()
+}
+
+// listMembers lists users joined through a role table (admins or creators) of the app
+func (s *Storage) listMembers(ctx context.Context, table string, appName string, filter models.ListFilter) ([]models.Member, error) {
+	var appID int
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM apps WHERE name = ?`, appName).Scan(&appID)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return nil, storage.ErrAppNotFound
+		}
+		return nil, err
+	}
+
+	var k keyset
+	k.add("r.app_id = ?", appID)
+	k.filter(filter, "u.id", "u.email", "r.created_at")
+	stmt := `SELECT u.id, u.email, r.created_at FROM ` + table + ` r JOIN users u ON u.id = r.uid` + k.tail(filter.Limit, "u.id")
+
+	rows, err := s.db.QueryContext(ctx, stmt, k.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.Member
+	for rows.Next() {
+		var member models.Member
+		var createdAt int64
+		if err := rows.Scan(&member.UserID, &member.Email, &createdAt); err != nil {
+			return nil, err
+		}
+		member.CreatedAt = fromUnix(createdAt)
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+)
+
+// members are users that joined an app and holders of its roles, as rows of uid and app_id
+const members = `(SELECT uid, app_id FROM app_users
+	UNION SELECT uid, app_id FROM creators
+	UNION SELECT uid, app_id FROM admins
+	UNION SELECT uid, app_id FROM supports)`
+
+// AddMember makes the user a member of the app, a member stays one
+func (s *Storage) AddMember(ctx context.Context, userID uint64, appID int) error {
+	const op = "storage.sqlite.AddMember"
+
+	stmt := `INSERT INTO app_users (uid, app_id, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`
+	if _, err := s.db.ExecContext(ctx, stmt, userID, appID, unixTime(time.Now())); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) IsMember(ctx context.Context, userID uint64, appName string) error {
+	const op = "storage.sqlite.IsMember"
+
+	if err := s.hasRole(ctx, members, userID, appName, storage.ErrMemberNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+)
+
+// GetProfile returns the user with its metadata in the app, for claims of tokens
+func (s *Storage) GetProfile(ctx context.Context, userID uint64, appName string) (models.Profile, error) {
+	const op = "storage.sqlite.GetProfile"
+
+	user, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt := `SELECT a.id, COALESCE(m.app_metadata, '{}'), COALESCE(m.user_metadata, '{}')
+		FROM apps a LEFT JOIN user_metadata m ON m.app_id = a.id AND m.uid = ?
+		WHERE a.name = ?`
+	profile := models.Profile{User: user}
+	var appMetadata, userMetadata string
+	err = s.db.QueryRowContext(ctx, stmt, userID, appName).Scan(&profile.AppID, &appMetadata, &userMetadata)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.Profile{}, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return models.Profile{}, fmt.Errorf("%s: %w", op, err)
+	}
+	profile.AppMetadata = []byte(appMetadata)
+	profile.UserMetadata = []byte(userMetadata)
+	return profile, nil
+}
+
+func (s *Storage) SetMetadataSchemas(ctx context.Context, appName string, schemas models.MetadataSchemas) error {
+	const op = "storage.sqlite.SetMetadataSchemas"
+
+	stmt := `UPDATE apps SET app_metadata_schema = ?, user_metadata_schema = ? WHERE name = ?`
+	res, err := s.db.ExecContext(ctx, stmt, nullJSON(schemas.AppMetadata), nullJSON(schemas.UserMetadata), appName)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected(res) == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+	}
+	return nil
+}
+
+// nullJSON stores an empty document as SQL NULL
+func nullJSON(data []byte) sql.NullString {
+	if len(data) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
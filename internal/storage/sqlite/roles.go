@@ -0,0 +1,217 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+)
+
+// roleTables are the tables holding members of every role
+var roleTables = map[string]string{
+	models.RoleCreator: "creators",
+	models.RoleAdmin:   "admins",
+	models.RoleSupport: "supports",
+}
+
+func (s *Storage) IsAdmin(ctx context.Context, userID uint64, appName string) error {
+	const op = "storage.sqlite.IsAdmin"
+
+	if err := s.hasRole(ctx, "admins", userID, appName, storage.ErrAdminNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) ListAdmins(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, error) {
+	const op = "storage.sqlite.ListAdmins"
+
+	admins, err := s.listMembers(ctx, "admins", appName, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return admins, nil
+}
+
+func (s *Storage) IsCreator(ctx context.Context, userID uint64, appName string) error {
+	const op = "storage.sqlite.IsCreator"
+
+	if err := s.hasRole(ctx, "creators", userID, appName, storage.ErrCreatorNotFound); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) SetCreator(ctx context.Context, userID uint64, appID int) error {
+	const op = "storage.sqlite.SetCreator"
+
+	stmt := `INSERT INTO creators (uid, app_id, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`
+	_, err := s.db.ExecContext(ctx, stmt, userID, appID, unixTime(time.Now()))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Storage) ListCreators(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, error) {
+	const op = "storage.sqlite.ListCreators"
+
+	creators, err := s.listMembers(ctx, "creators", appName, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return creators, nil
+}
+
+// GetRoles returns the roles of the user in the app
+func (s *Storage) GetRoles(ctx context.Context, userID uint64, appName string) ([]string, error) {
+	const op = "storage.sqlite.GetRoles"
+
+	stmt := `SELECT 'creator' FROM creators c JOIN apps a ON a.id = c.app_id WHERE c.uid = ?1 AND a.name = ?2
+		UNION SELECT 'admin' FROM admins d JOIN apps a ON a.id = d.app_id WHERE d.uid = ?1 AND a.name = ?2
+		UNION SELECT 'support' FROM supports s JOIN apps a ON a.id = s.app_id WHERE s.uid = ?1 AND a.name = ?2`
+	rows, err := s.db.QueryContext(ctx, stmt, userID, appName)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return roles, nil
+}
+
+// GrantRole gives the role in the app to the user with the email
+func (s *Storage) GrantRole(ctx context.Context, email string, appName string, role string) error {
+	const op = "storage.sqlite.GrantRole"
+
+	table, ok := roleTables[role]
+	if !ok {
+		return fmt.Errorf("%s: unknown role %q", op, role)
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		uid, appID, err := userAndApp(ctx, tx, email, appName)
+		if err != nil {
+			return err
+		}
+
+		stmt := fmt.Sprintf(`INSERT INTO %s (uid, app_id, created_at) VALUES (?, ?, ?)`, table)
+		if _, err := tx.ExecContext(ctx, stmt, uid, appID, unixTime(time.Now())); err != nil {
+			if IsDuplicatedKeyError(err) {
+				return storage.ErrRoleExists
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// RevokeRole takes the role in the app from the user with the email.
+// The last creator of an app can't be removed
+func (s *Storage) RevokeRole(ctx context.Context, email string, appName string, role string) error {
+	const op = "storage.sqlite.RevokeRole"
+
+	table, ok := roleTables[role]
+	if !ok {
+		return fmt.Errorf("%s: unknown role %q", op, role)
+	}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		uid, appID, err := userAndApp(ctx, tx, email, appName)
+		if err != nil {
+			return err
+		}
+
+		stmt := fmt.Sprintf(`DELETE FROM %s WHERE uid = ? AND app_id = ?`, table)
+		res, err := tx.ExecContext(ctx, stmt, uid, appID)
+		if err != nil {
+			return err
+		}
+		if affected(res) == 0 {
+			return storage.ErrRoleNotFound
+		}
+
+		if role == models.RoleCreator {
+			var left int
+			if err := tx.QueryRowContext(ctx, `SELECT count(*) FROM creators WHERE app_id = ?`, appID).Scan(&left); err != nil {
+				return err
+			}
+			if left == 0 {
+				return storage.ErrLastCreator
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// hasRole checks the app, the user and the role in this order, like postgres does.
+// table is a role table or a subquery of uid and app_id rows
+func (s *Storage) hasRole(ctx context.Context, table string, userID uint64, appName string, errNoRole error) error {
+	var appID int
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM apps WHERE name = ?`, appName).Scan(&appID)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return storage.ErrAppNotFound
+		}
+		return err
+	}
+
+	err = s.db.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = ?`, userID).Scan(new(int))
+	if err != nil {
+		if IsNotFoundError(err) {
+			return storage.ErrUserNotFound
+		}
+		return err
+	}
+
+	stmt := fmt.Sprintf(`SELECT 1 FROM %s WHERE uid = ? AND app_id = ?`, table)
+	err = s.db.QueryRowContext(ctx, stmt, userID, appID).Scan(new(int))
+	if err != nil {
+		if IsNotFoundError(err) {
+			return errNoRole
+		}
+		return err
+	}
+	return nil
+}
+
+// userAndApp returns ids of the user and the app. Transactions take the write lock
+// when they begin, so role changes are serialized and two creators can't remove each other at once
+func userAndApp(ctx context.Context, tx *sql.Tx, email string, appName string) (uint64, int, error) {
+	var uid uint64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM users WHERE email = ?`, email).Scan(&uid)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return 0, 0, storage.ErrUserNotFound
+		}
+		return 0, 0, err
+	}
+
+	var appID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM apps WHERE name = ?`, appName).Scan(&appID)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return 0, 0, storage.ErrAppNotFound
+		}
+		return 0, 0, err
+	}
+	return uid, appID, nil
+}
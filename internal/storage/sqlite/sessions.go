@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+)
+
+// CreateSession saves a new session. When maxSessions is positive, the oldest live
+// sessions of the user in the app are revoked so that at most maxSessions stay
+func (s *Storage) CreateSession(ctx context.Context, session models.Session, maxSessions int) (uint64, error) {
+	const op = "storage.sqlite.CreateSession"
+
+	var id uint64
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		now := unixTime(time.Now())
+		stmt := `INSERT INTO sessions (uid, app_id, refresh_hash, ip, created_at, last_used_at, refresh_expires_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+		res, err := tx.ExecContext(ctx, stmt, session.UserID, session.AppID, session.RefreshHash, session.IP,
+			now, now, unixTime(session.RefreshExpiresAt), nullTime(session.ExpiresAt))
+		if err != nil {
+			return err
+		}
+		lastID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		id = uint64(lastID)
+		if maxSessions <= 0 {
+			return nil
+		}
+
+		stmt = `UPDATE sessions SET revoked_at = ?1 WHERE id IN (
+			SELECT id FROM sessions
+			WHERE uid = ?2 AND app_id = ?3 AND revoked_at IS NULL
+				AND refresh_expires_at > ?1 AND (expires_at IS NULL OR expires_at > ?1)
+			ORDER BY created_at DESC, id DESC
+			LIMIT -1 OFFSET ?4)`
+		_, err = tx.ExecContext(ctx, stmt, now, session.UserID, session.AppID, maxSessions)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// GetSession returns the session of the refresh token hash. A session found by
+// its previous refresh token is returned with Reused set
+func (s *Storage) GetSession(ctx context.Context, refreshHash string) (models.Session, error) {
+	const op = "storage.sqlite.GetSession"
+
+	stmt := `SELECT id, uid, app_id, refresh_hash, ip, created_at, last_used_at,
+		refresh_expires_at, expires_at, revoked_at IS NOT NULL, refresh_hash <> ?1
+		FROM sessions WHERE refresh_hash = ?1 OR prev_refresh_hash = ?1
+		LIMIT 1`
+	var session models.Session
+	var createdAt, lastUsedAt, refreshExpiresAt int64
+	var expiresAt sql.NullInt64
+	err := s.db.QueryRowContext(ctx, stmt, refreshHash).Scan(&session.ID, &session.UserID, &session.AppID,
+		&session.RefreshHash, &session.IP, &createdAt, &lastUsedAt,
+		&refreshExpiresAt, &expiresAt, &session.Revoked, &session.Reused)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.Session{}, fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+		}
+		return models.Session{}, fmt.Errorf("%s: %w", op, err)
+	}
+	session.CreatedAt = fromUnix(createdAt)
+	session.LastUsedAt = fromUnix(lastUsedAt)
+	session.RefreshExpiresAt = fromUnix(refreshExpiresAt)
+	session.ExpiresAt = fromNullTime(expiresAt)
+	return session, nil
+}
+
+// RotateSession replaces the refresh token of a live session. It fails with
+// ErrSessionNotFound when the token was already rotated by a concurrent refresh
+func (s *Storage) RotateSession(ctx context.Context, id uint64, oldHash, newHash string, refreshExpiresAt time.Time) error {
+	const op = "storage.sqlite.RotateSession"
+
+	stmt := `UPDATE sessions SET refresh_hash = ?, prev_refresh_hash = refresh_hash,
+		refresh_expires_at = ?, last_used_at = ?
+		WHERE id = ? AND refresh_hash = ? AND revoked_at IS NULL`
+	res, err := s.db.ExecContext(ctx, stmt, newHash, unixTime(refreshExpiresAt), unixTime(time.Now()), id, oldHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected(res) == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+	return nil
+}
+
+// RevokeSession ends the session, revoking an already revoked session is not an error
+func (s *Storage) RevokeSession(ctx context.Context, id uint64) error {
+	const op = "storage.sqlite.RevokeSession"
+
+	stmt := `UPDATE sessions SET revoked_at = coalesce(revoked_at, ?) WHERE id = ?`
+	res, err := s.db.ExecContext(ctx, stmt, unixTime(time.Now()), id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected(res) == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrSessionNotFound)
+	}
+	return nil
+}
+
+// DeleteEndedSessions removes sessions that were revoked or expired before the given time
+func (s *Storage) DeleteEndedSessions(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.sqlite.DeleteEndedSessions"
+
+	stmt := `DELETE FROM sessions
+		WHERE revoked_at < ?1 OR refresh_expires_at < ?1 OR expires_at < ?1`
+	res, err := s.db.ExecContext(ctx, stmt, unixTime(before))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return affected(res), nil
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/config"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+var _ storage.Storage = (*Storage)(nil)
+
+// Storage keeps the tables of auth, permissions and apps in one SQLite file, for
+// single-node deployments. Unlike postgres it writes no events to the outbox
+type Storage struct {
+	db *sql.DB
+}
+
+// pragmas are set on every connection. Writers take the lock when the transaction
+// begins, so a transaction that reads before writing can't fail halfway with SQLITE_BUSY
+const pragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+func New(cfg *config.Config) (*Storage, error) {
+	const op = "storage.sqlite.NewStorage"
+
+	db, err := sql.Open("sqlite", "file:"+cfg.Storage.Path+"?"+pragmas)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	// sqlite has a single writer anyway, one connection keeps writers from waiting
+	// on each other's locks
+	db.SetMaxOpenConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Storage{db: db}, nil
+}
+
+func (s *Storage) Close() {
+	s.db.Close()
+}
+
+func IsDuplicatedKeyError(err error) bool {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+func IsNotFoundError(err error) bool {
+	return errors.Is(err, sql.ErrNoRows)
+}
+
+// unixTime and fromUnix convert times to the unix microseconds stored in the database
+func unixTime(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+func fromUnix(us int64) time.Time {
+	return time.UnixMicro(us)
+}
+
+// nullTime stores a zero time as SQL NULL
+func nullTime(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: unixTime(t), Valid: true}
+}
+
+func fromNullTime(us sql.NullInt64) time.Time {
+	if !us.Valid {
+		return time.Time{}
+	}
+	return fromUnix(us.Int64)
+}
+
+// affected returns the number of rows changed by the statement, the driver always knows it
+func affected(res sql.Result) int64 {
+	n, _ := res.RowsAffected()
+	return n
+}
+
+// inTx runs fn in a transaction, committed when fn returns nil
+func (s *Storage) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,30 @@
+package sqlite_test
+
+import (
+	"github.com/neepooha/sso/internal/config"
+	"github.com/neepooha/sso/internal/lib/migrator"
+	"github.com/neepooha/sso/internal/storage"
+	"github.com/neepooha/sso/internal/storage/sqlite"
+	"github.com/neepooha/sso/internal/storage/storagetest"
+	"path/filepath"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		var cfg config.Config
+		cfg.Storage.Driver = storage.DriverSQLite
+		cfg.Storage.Path = filepath.Join(t.TempDir(), "sso.db")
+		cfg.Storage.Migrations_path = filepath.Join("..", "..", "..", "migrations", "sqlite")
+		if err := migrator.Migrate(&cfg); err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := sqlite.New(&cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(s.Close)
+		return s
+	})
+}
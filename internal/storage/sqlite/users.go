@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"time"
+)
+
+const userColumns = `id, kind, email, pass_hash, display_name, locale, avatar_url, status, created_at, updated_at`
+
+func (s *Storage) SaveUser(ctx context.Context, email string, passHash []byte, appID int) (uint64, error) {
+	const op = "storage.sqlite.SaveUser"
+
+	now := unixTime(time.Now())
+	var uid int64
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		stmt := `INSERT INTO users (email, pass_hash, created_at, updated_at) VALUES (?, ?, ?, ?)`
+		res, err := tx.ExecContext(ctx, stmt, email, passHash, now, now)
+		if err != nil {
+			return err
+		}
+		if uid, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		if appID == 0 {
+			return nil
+		}
+		stmt = `INSERT INTO app_users (uid, app_id, created_at) VALUES (?, ?, ?)`
+		_, err = tx.ExecContext(ctx, stmt, uid, appID, now)
+		return err
+	})
+	if err != nil {
+		if IsDuplicatedKeyError(err) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return uint64(uid), nil
+}
+
+func (s *Storage) GetUser(ctx context.Context, email string) (models.User, error) {
+	const op = "storage.sqlite.GetUser"
+
+	user, err := scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, email))
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+func (s *Storage) GetUserByID(ctx context.Context, userID uint64) (models.User, error) {
+	const op = "storage.sqlite.GetUserByID"
+
+	user, err := scanUser(s.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, userID))
+	if err != nil {
+		if IsNotFoundError(err) {
+			return models.User{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return models.User{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return user, nil
+}
+
+func (s *Storage) ListUsers(ctx context.Context, appName string, filter models.ListFilter) ([]models.User, error) {
+	const op = "storage.sqlite.ListUsers"
+
+	var appID int
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM apps WHERE name = ?`, appName).Scan(&appID)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrAppNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var k keyset
+	k.add("kind = ?", models.UserHuman)
+	k.add("id IN (SELECT uid FROM "+members+" WHERE app_id = ?)", appID)
+	k.filter(filter, "id", "email", "created_at")
+	stmt := `SELECT id, email, status, created_at FROM users` + k.tail(filter.Limit, "id")
+
+	rows, err := s.db.QueryContext(ctx, stmt, k.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		var createdAt int64
+		if err := rows.Scan(&user.ID, &user.Email, &user.Status, &createdAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		user.CreatedAt = fromUnix(createdAt)
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return users, nil
+}
+
+// UpdEmail changes the email of the user
+func (s *Storage) UpdEmail(ctx context.Context, userID uint64, email string) error {
+	const op = "storage.sqlite.UpdEmail"
+
+	stmt := `UPDATE users SET email = ?, updated_at = ? WHERE id = ?`
+	res, err := s.db.ExecContext(ctx, stmt, email, unixTime(time.Now()), userID)
+	if err != nil {
+		if IsDuplicatedKeyError(err) {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserExists)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected(res) == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+	return nil
+}
+
+func scanUser(row *sql.Row) (models.User, error) {
+	var user models.User
+	var createdAt, updatedAt int64
+	err := row.Scan(&user.ID, &user.Kind, &user.Email, &user.PassHash, &user.DisplayName, &user.Locale,
+		&user.AvatarURL, &user.Status, &createdAt, &updatedAt)
+	user.CreatedAt = fromUnix(createdAt)
+	user.UpdatedAt = fromUnix(updatedAt)
+	return user, err
+}
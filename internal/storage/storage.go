@@ -1,6 +1,17 @@
 package storage
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"github.com/neepooha/sso/internal/domain/models"
+)
+
+// Drivers of storage.driver in config
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMemory   = "memory"
+)
 
 var (
 	ErrAppExists  = errors.New("app already exists")
@@ -10,6 +21,89 @@ var (
 	ErrUserNotFound    = errors.New("user not found")
 	ErrAdminNotFound   = errors.New("admin not found")
 	ErrCreatorNotFound = errors.New("creator not found")
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrSessionNotFound = errors.New("session not found")
+	ErrMemberNotFound  = errors.New("user isn't member of app")
+	ErrAPIKeyNotFound  = errors.New("api key not found")
+
+	ErrServiceAccountExists   = errors.New("service account already exists")
+	ErrServiceAccountNotFound = errors.New("service account not found")
 
 	ErrAdminExists = errors.New("user already admin")
+	ErrRoleExists  = errors.New("user already has the role")
+
+	ErrRoleNotFound = errors.New("user doesn't have the role")
+	ErrLastCreator  = errors.New("app must keep at least one creator")
+
+	ErrAuditTampered = errors.New("audit log hash chain is broken")
 )
+
+// Users store the accounts of users. Emails are unique, a second user with the
+// same email gets ErrUserExists
+type Users interface {
+	// SaveUser makes the user a member of the app unless appID is zero
+	SaveUser(ctx context.Context, email string, passHash []byte, appID int) (uint64, error)
+	GetUser(ctx context.Context, email string) (models.User, error)
+	GetUserByID(ctx context.Context, userID uint64) (models.User, error)
+	// ListUsers returns human members of the app ordered by id
+	ListUsers(ctx context.Context, appName string, filter models.ListFilter) ([]models.User, error)
+	UpdEmail(ctx context.Context, userID uint64, email string) error
+}
+
+// Apps store applications by their unique name, a taken name gets ErrAppExists
+type Apps interface {
+	SetApp(ctx context.Context, appName string, appSecret string) (int, error)
+	// GetAppID returns only the id and name of the app
+	GetAppID(ctx context.Context, appName string) (models.App, error)
+	GetApp(ctx context.Context, appName string) (models.App, error)
+	UpdApp(ctx context.Context, appName, newAppName, newAppSecret string) error
+	// DelApp removes the app with every role in it
+	DelApp(ctx context.Context, appName string) error
+	// ListApps returns apps ordered by id, only the ones created by creatorEmail unless it is empty
+	ListApps(ctx context.Context, filter models.ListFilter, creatorEmail string) ([]models.App, error)
+	SetClaimMapping(ctx context.Context, appName string, mapping map[string]string) error
+	SetAppSettings(ctx context.Context, appName string, settings models.AppSettings) error
+}
+
+// Admins tell the admins of apps. IsAdmin returns ErrAppNotFound, ErrUserNotFound
+// or ErrAdminNotFound, in this order
+type Admins interface {
+	IsAdmin(ctx context.Context, userID uint64, appName string) error
+	ListAdmins(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, error)
+}
+
+// Creators tell the creators of apps. IsCreator returns ErrAppNotFound, ErrUserNotFound
+// or ErrCreatorNotFound, in this order
+type Creators interface {
+	SetCreator(ctx context.Context, userID uint64, appID int) error
+	IsCreator(ctx context.Context, userID uint64, appName string) error
+	ListCreators(ctx context.Context, appName string, filter models.ListFilter) ([]models.Member, error)
+}
+
+// Members tell which users belong to an app: the ones that joined it by registering
+// or logging in, and every holder of a role in it. IsMember returns ErrAppNotFound,
+// ErrUserNotFound or ErrMemberNotFound, in this order. Adding a member twice is not an error
+type Members interface {
+	AddMember(ctx context.Context, userID uint64, appID int) error
+	IsMember(ctx context.Context, userID uint64, appName string) error
+}
+
+// Roles grant and revoke the roles of models.RoleLevels. Granting a role twice gets
+// ErrRoleExists, revoking a missing one ErrRoleNotFound and the last creator of an
+// app can't be revoked
+type Roles interface {
+	GetRoles(ctx context.Context, userID uint64, appName string) ([]string, error)
+	GrantRole(ctx context.Context, email string, appName string, role string) error
+	RevokeRole(ctx context.Context, email string, appName string, role string) error
+}
+
+// Storage is the contract every backend fulfills, checked by package storagetest.
+// Implementations are safe for concurrent use
+type Storage interface {
+	Users
+	Apps
+	Admins
+	Creators
+	Members
+	Roles
+}
@@ -0,0 +1,522 @@
+// Package storagetest is the conformance suite of storage.Storage, every backend runs it
+// from its own tests
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/neepooha/sso/internal/domain/models"
+	"github.com/neepooha/sso/internal/storage"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Run runs the suite against storages made by newStorage, which is called once per test.
+// A storage may be shared between tests and hold data of earlier runs, so tests
+// name their users and apps uniquely and only list what they created
+func Run(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Storage)
+	}{
+		{"Users", testUsers},
+		{"UpdEmail", testUpdEmail},
+		{"ListUsers", testListUsers},
+		{"Members", testMembers},
+		{"Apps", testApps},
+		{"UpdApp", testUpdApp},
+		{"DelApp", testDelApp},
+		{"ListApps", testListApps},
+		{"Creators", testCreators},
+		{"Admins", testAdmins},
+		{"Roles", testRoles},
+		{"LastCreator", testLastCreator},
+		{"ConcurrentSaveUser", testConcurrentSaveUser},
+		{"ConcurrentGrantRole", testConcurrentGrantRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+var seq atomic.Uint64
+
+// unique returns a name no earlier run of the suite used
+func unique(name string) string {
+	return fmt.Sprintf("st%d-%d-%s", time.Now().UnixNano(), seq.Add(1), name)
+}
+
+func testUsers(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	email := unique("user@example.com")
+
+	uid, err := s.SaveUser(ctx, email, []byte("hash"), 0)
+	must(t, err)
+	if uid == 0 {
+		t.Fatal("SaveUser returned id 0")
+	}
+	_, err = s.SaveUser(ctx, email, []byte("other"), 0)
+	wantErr(t, err, storage.ErrUserExists)
+
+	user, err := s.GetUser(ctx, email)
+	must(t, err)
+	if user.ID != uid || user.Email != email || string(user.PassHash) != "hash" {
+		t.Errorf("GetUser = %+v, want id %d and email %s", user, uid, email)
+	}
+	if user.Kind != models.UserHuman || user.Status != models.UserActive || user.CreatedAt.IsZero() {
+		t.Errorf("GetUser = %+v, want an active human with created_at", user)
+	}
+
+	byID, err := s.GetUserByID(ctx, uid)
+	must(t, err)
+	if byID.Email != email {
+		t.Errorf("GetUserByID email = %s, want %s", byID.Email, email)
+	}
+
+	_, err = s.GetUser(ctx, unique("missing@example.com"))
+	wantErr(t, err, storage.ErrUserNotFound)
+	_, err = s.GetUserByID(ctx, uid+1_000_000)
+	wantErr(t, err, storage.ErrUserNotFound)
+}
+
+func testUpdEmail(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	uid := saveUser(t, s, unique("old@example.com"))
+	taken := unique("taken@example.com")
+	saveUser(t, s, taken)
+
+	email := unique("new@example.com")
+	must(t, s.UpdEmail(ctx, uid, email))
+	user, err := s.GetUser(ctx, email)
+	must(t, err)
+	if user.ID != uid {
+		t.Errorf("GetUser after UpdEmail id = %d, want %d", user.ID, uid)
+	}
+
+	wantErr(t, s.UpdEmail(ctx, uid, taken), storage.ErrUserExists)
+	wantErr(t, s.UpdEmail(ctx, uid+1_000_000, unique("x@example.com")), storage.ErrUserNotFound)
+}
+
+func testListUsers(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := unique("app")
+	appID := setApp(t, s, name)
+	prefix := unique("list")
+	var ids []uint64
+	for i := range 5 {
+		email := fmt.Sprintf("%s-%d@example.com", prefix, i)
+		uid := saveUser(t, s, email)
+		ids = append(ids, uid)
+		if i == 0 {
+			must(t, s.GrantRole(ctx, email, name, models.RoleSupport))
+			continue
+		}
+		must(t, s.AddMember(ctx, uid, appID))
+	}
+	// users of other apps are never listed
+	saveUser(t, s, prefix+"-outsider@example.com")
+
+	first, err := s.ListUsers(ctx, name, models.ListFilter{Prefix: prefix, Limit: 3})
+	must(t, err)
+	if len(first) != 3 {
+		t.Fatalf("ListUsers with limit 3 = %d users, want 3", len(first))
+	}
+	rest, err := s.ListUsers(ctx, name, models.ListFilter{Prefix: prefix, AfterID: first[len(first)-1].ID, Limit: 3})
+	must(t, err)
+	got := userIDs(append(first, rest...))
+	if !slices.Equal(got, ids) {
+		t.Errorf("ListUsers pages = %v, want %v", got, ids)
+	}
+
+	future, err := s.ListUsers(ctx, name, models.ListFilter{Prefix: prefix, CreatedAfter: time.Now().Add(time.Hour), Limit: 10})
+	must(t, err)
+	past, err := s.ListUsers(ctx, name, models.ListFilter{Prefix: prefix, CreatedBefore: time.Now().Add(-time.Hour), Limit: 10})
+	must(t, err)
+	if len(future) != 0 || len(past) != 0 {
+		t.Errorf("ListUsers out of time range = %d and %d users, want none", len(future), len(past))
+	}
+	_, err = s.ListUsers(ctx, unique("missing"), models.ListFilter{Limit: 10})
+	wantErr(t, err, storage.ErrAppNotFound)
+}
+
+func testMembers(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := unique("app")
+	appID := setApp(t, s, name)
+	joined := saveUser(t, s, unique("joined@example.com"))
+	adminEmail := unique("admin@example.com")
+	admin := saveUser(t, s, adminEmail)
+	other := saveUser(t, s, unique("other@example.com"))
+
+	must(t, s.AddMember(ctx, joined, appID))
+	must(t, s.AddMember(ctx, joined, appID))
+	must(t, s.GrantRole(ctx, adminEmail, name, models.RoleAdmin))
+
+	must(t, s.IsMember(ctx, joined, name))
+	must(t, s.IsMember(ctx, admin, name))
+	wantErr(t, s.IsMember(ctx, other, name), storage.ErrMemberNotFound)
+	wantErr(t, s.IsMember(ctx, other+1_000_000, name), storage.ErrUserNotFound)
+	wantErr(t, s.IsMember(ctx, joined, unique("missing")), storage.ErrAppNotFound)
+
+	registered, err := s.SaveUser(ctx, unique("registered@example.com"), []byte("hash"), appID)
+	must(t, err)
+	must(t, s.IsMember(ctx, registered, name))
+
+	// members of a deleted app don't carry over to a new app of the same name
+	must(t, s.DelApp(ctx, name))
+	setApp(t, s, name)
+	wantErr(t, s.IsMember(ctx, joined, name), storage.ErrMemberNotFound)
+}
+
+func testApps(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := unique("app")
+
+	appID, err := s.SetApp(ctx, name, "secret")
+	must(t, err)
+	_, err = s.SetApp(ctx, name, "other")
+	wantErr(t, err, storage.ErrAppExists)
+
+	app, err := s.GetApp(ctx, name)
+	must(t, err)
+	if app.ID != appID || app.Name != name || app.Secret != "secret" {
+		t.Errorf("GetApp = %+v, want id %d, name %s and its secret", app, appID, name)
+	}
+	if app.ClaimMapping != nil || app.Settings != (models.AppSettings{}) {
+		t.Errorf("GetApp of a new app = %+v, want no claim mapping and default settings", app)
+	}
+	short, err := s.GetAppID(ctx, name)
+	must(t, err)
+	if short.ID != appID || short.Name != name {
+		t.Errorf("GetAppID = %+v, want id %d", short, appID)
+	}
+
+	mapping := map[string]string{"tier": "metadata.tier"}
+	must(t, s.SetClaimMapping(ctx, name, mapping))
+	settings := models.AppSettings{AccessTTL: 5 * time.Minute, RefreshTTL: 24 * time.Hour, MaxSessions: 3}
+	must(t, s.SetAppSettings(ctx, name, settings))
+	app, err = s.GetApp(ctx, name)
+	must(t, err)
+	if app.ClaimMapping["tier"] != "metadata.tier" || len(app.ClaimMapping) != 1 || app.Settings != settings {
+		t.Errorf("GetApp = %+v, want claim mapping %v and settings %+v", app, mapping, settings)
+	}
+	must(t, s.SetClaimMapping(ctx, name, nil))
+	app, err = s.GetApp(ctx, name)
+	must(t, err)
+	if app.ClaimMapping != nil {
+		t.Errorf("claim mapping after removing it = %v, want nil", app.ClaimMapping)
+	}
+
+	missing := unique("missing")
+	_, err = s.GetApp(ctx, missing)
+	wantErr(t, err, storage.ErrAppNotFound)
+	_, err = s.GetAppID(ctx, missing)
+	wantErr(t, err, storage.ErrAppNotFound)
+	wantErr(t, s.SetClaimMapping(ctx, missing, mapping), storage.ErrAppNotFound)
+	wantErr(t, s.SetAppSettings(ctx, missing, settings), storage.ErrAppNotFound)
+}
+
+func testUpdApp(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := unique("app")
+	appID := setApp(t, s, name)
+	taken := unique("taken")
+	setApp(t, s, taken)
+
+	renamed := unique("renamed")
+	must(t, s.UpdApp(ctx, name, renamed, "new-secret"))
+	app, err := s.GetApp(ctx, renamed)
+	must(t, err)
+	if app.ID != appID || app.Secret != "new-secret" {
+		t.Errorf("GetApp after UpdApp = %+v, want id %d and the new secret", app, appID)
+	}
+	_, err = s.GetApp(ctx, name)
+	wantErr(t, err, storage.ErrAppNotFound)
+
+	wantErr(t, s.UpdApp(ctx, renamed, taken, "secret"), storage.ErrAppExists)
+	wantErr(t, s.UpdApp(ctx, name, unique("other"), "secret"), storage.ErrAppNotFound)
+}
+
+func testDelApp(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := unique("app")
+	appID := setApp(t, s, name)
+	email := unique("creator@example.com")
+	uid := saveUser(t, s, email)
+	must(t, s.SetCreator(ctx, uid, appID))
+	must(t, s.GrantRole(ctx, email, name, models.RoleAdmin))
+
+	must(t, s.DelApp(ctx, name))
+	_, err := s.GetApp(ctx, name)
+	wantErr(t, err, storage.ErrAppNotFound)
+	wantErr(t, s.DelApp(ctx, name), storage.ErrAppNotFound)
+
+	// the name is free again and the new app has none of the old roles
+	setApp(t, s, name)
+	wantErr(t, s.IsCreator(ctx, uid, name), storage.ErrCreatorNotFound)
+	wantErr(t, s.IsAdmin(ctx, uid, name), storage.ErrAdminNotFound)
+}
+
+func testListApps(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	prefix := unique("list")
+	email := unique("creator@example.com")
+	uid := saveUser(t, s, email)
+	var ids, created []uint64
+	for i := range 4 {
+		appID := setApp(t, s, fmt.Sprintf("%s-%d", prefix, i))
+		ids = append(ids, uint64(appID))
+		if i%2 == 0 {
+			must(t, s.SetCreator(ctx, uid, appID))
+			created = append(created, uint64(appID))
+		}
+	}
+
+	apps, err := s.ListApps(ctx, models.ListFilter{Prefix: prefix, Limit: 10}, "")
+	must(t, err)
+	if got := appIDs(apps); !slices.Equal(got, ids) {
+		t.Errorf("ListApps = %v, want %v", got, ids)
+	}
+	apps, err = s.ListApps(ctx, models.ListFilter{Prefix: prefix, AfterID: ids[0], Limit: 2}, "")
+	must(t, err)
+	if got := appIDs(apps); !slices.Equal(got, ids[1:3]) {
+		t.Errorf("ListApps after %d = %v, want %v", ids[0], got, ids[1:3])
+	}
+	apps, err = s.ListApps(ctx, models.ListFilter{Prefix: prefix, Limit: 10}, email)
+	must(t, err)
+	if got := appIDs(apps); !slices.Equal(got, created) {
+		t.Errorf("ListApps of creator = %v, want %v", got, created)
+	}
+	for _, app := range apps {
+		if app.CreatedAt.IsZero() {
+			t.Errorf("ListApps app %d has no created_at", app.ID)
+		}
+	}
+}
+
+func testCreators(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := unique("app")
+	appID := setApp(t, s, name)
+	creator := saveUser(t, s, unique("creator@example.com"))
+	other := saveUser(t, s, unique("other@example.com"))
+
+	must(t, s.SetCreator(ctx, creator, appID))
+	must(t, s.IsCreator(ctx, creator, name))
+	wantErr(t, s.IsCreator(ctx, other, name), storage.ErrCreatorNotFound)
+	wantErr(t, s.IsCreator(ctx, creator+1_000_000, name), storage.ErrUserNotFound)
+	wantErr(t, s.IsCreator(ctx, creator, unique("missing")), storage.ErrAppNotFound)
+
+	members, err := s.ListCreators(ctx, name, models.ListFilter{Limit: 10})
+	must(t, err)
+	if len(members) != 1 || members[0].UserID != creator || members[0].CreatedAt.IsZero() {
+		t.Errorf("ListCreators = %+v, want only user %d", members, creator)
+	}
+	_, err = s.ListCreators(ctx, unique("missing"), models.ListFilter{Limit: 10})
+	wantErr(t, err, storage.ErrAppNotFound)
+}
+
+func testAdmins(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := unique("app")
+	setApp(t, s, name)
+	prefix := unique("admin")
+	var ids []uint64
+	for i := range 3 {
+		email := fmt.Sprintf("%s-%d@example.com", prefix, i)
+		ids = append(ids, saveUser(t, s, email))
+		must(t, s.GrantRole(ctx, email, name, models.RoleAdmin))
+	}
+	other := saveUser(t, s, unique("other@example.com"))
+
+	must(t, s.IsAdmin(ctx, ids[0], name))
+	wantErr(t, s.IsAdmin(ctx, other, name), storage.ErrAdminNotFound)
+	wantErr(t, s.IsAdmin(ctx, other+1_000_000, name), storage.ErrUserNotFound)
+	wantErr(t, s.IsAdmin(ctx, ids[0], unique("missing")), storage.ErrAppNotFound)
+
+	first, err := s.ListAdmins(ctx, name, models.ListFilter{Limit: 2})
+	must(t, err)
+	if len(first) != 2 {
+		t.Fatalf("ListAdmins with limit 2 = %d admins, want 2", len(first))
+	}
+	rest, err := s.ListAdmins(ctx, name, models.ListFilter{AfterID: first[len(first)-1].UserID, Limit: 2})
+	must(t, err)
+	if got := memberIDs(append(first, rest...)); !slices.Equal(got, ids) {
+		t.Errorf("ListAdmins pages = %v, want %v", got, ids)
+	}
+	_, err = s.ListAdmins(ctx, unique("missing"), models.ListFilter{Limit: 10})
+	wantErr(t, err, storage.ErrAppNotFound)
+}
+
+func testRoles(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := unique("app")
+	setApp(t, s, name)
+	email := unique("user@example.com")
+	uid := saveUser(t, s, email)
+
+	roles, err := s.GetRoles(ctx, uid, name)
+	must(t, err)
+	if len(roles) != 0 {
+		t.Errorf("GetRoles of a new user = %v, want none", roles)
+	}
+
+	must(t, s.GrantRole(ctx, email, name, models.RoleAdmin))
+	must(t, s.GrantRole(ctx, email, name, models.RoleSupport))
+	wantErr(t, s.GrantRole(ctx, email, name, models.RoleAdmin), storage.ErrRoleExists)
+	roles, err = s.GetRoles(ctx, uid, name)
+	must(t, err)
+	slices.Sort(roles)
+	if want := []string{models.RoleAdmin, models.RoleSupport}; !slices.Equal(roles, want) {
+		t.Errorf("GetRoles = %v, want %v", roles, want)
+	}
+
+	must(t, s.RevokeRole(ctx, email, name, models.RoleAdmin))
+	wantErr(t, s.RevokeRole(ctx, email, name, models.RoleAdmin), storage.ErrRoleNotFound)
+	wantErr(t, s.IsAdmin(ctx, uid, name), storage.ErrAdminNotFound)
+
+	wantErr(t, s.GrantRole(ctx, unique("missing@example.com"), name, models.RoleAdmin), storage.ErrUserNotFound)
+	wantErr(t, s.GrantRole(ctx, email, unique("missing"), models.RoleAdmin), storage.ErrAppNotFound)
+	if err := s.GrantRole(ctx, email, name, "owner"); err == nil {
+		t.Error("GrantRole of an unknown role succeeded")
+	}
+}
+
+func testLastCreator(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := unique("app")
+	appID := setApp(t, s, name)
+	first := unique("first@example.com")
+	second := unique("second@example.com")
+	must(t, s.SetCreator(ctx, saveUser(t, s, first), appID))
+	saveUser(t, s, second)
+	must(t, s.GrantRole(ctx, second, name, models.RoleCreator))
+
+	must(t, s.RevokeRole(ctx, first, name, models.RoleCreator))
+	wantErr(t, s.RevokeRole(ctx, second, name, models.RoleCreator), storage.ErrLastCreator)
+	members, err := s.ListCreators(ctx, name, models.ListFilter{Limit: 10})
+	must(t, err)
+	if len(members) != 1 || members[0].Email != second {
+		t.Errorf("ListCreators after revoking the last creator = %+v, want %s kept", members, second)
+	}
+}
+
+func testConcurrentSaveUser(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	email := unique("race@example.com")
+	const n = 10
+
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = s.SaveUser(ctx, email, []byte("hash"), 0)
+		}()
+	}
+	wg.Wait()
+
+	saved := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			saved++
+		case !errors.Is(err, storage.ErrUserExists):
+			t.Errorf("concurrent SaveUser: %v", err)
+		}
+	}
+	if saved != 1 {
+		t.Errorf("concurrent SaveUser of one email saved %d users, want 1", saved)
+	}
+}
+
+func testConcurrentGrantRole(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	name := unique("app")
+	setApp(t, s, name)
+	prefix := unique("admin")
+	const n = 10
+	var emails []string
+	for i := range n {
+		email := fmt.Sprintf("%s-%d@example.com", prefix, i)
+		saveUser(t, s, email)
+		emails = append(emails, email)
+	}
+
+	var wg sync.WaitGroup
+	for _, email := range emails {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.GrantRole(ctx, email, name, models.RoleAdmin); err != nil {
+				t.Errorf("concurrent GrantRole: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	admins, err := s.ListAdmins(ctx, name, models.ListFilter{Limit: 2 * n})
+	must(t, err)
+	if len(admins) != n {
+		t.Errorf("ListAdmins after concurrent grants = %d admins, want %d", len(admins), n)
+	}
+}
+
+func saveUser(t *testing.T, s storage.Storage, email string) uint64 {
+	t.Helper()
+	uid, err := s.SaveUser(context.Background(), email, []byte("hash"), 0)
+	must(t, err)
+	return uid
+}
+
+func setApp(t *testing.T, s storage.Storage, name string) int {
+	t.Helper()
+	appID, err := s.SetApp(context.Background(), name, "secret")
+	must(t, err)
+	return appID
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func wantErr(t *testing.T, err error, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Errorf("error = %v, want %v", err, want)
+	}
+}
+
+func userIDs(users []models.User) []uint64 {
+	ids := make([]uint64, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func appIDs(apps []models.App) []uint64 {
+	ids := make([]uint64, 0, len(apps))
+	for _, a := range apps {
+		ids = append(ids, uint64(a.ID))
+	}
+	return ids
+}
+
+func memberIDs(members []models.Member) []uint64 {
+	ids := make([]uint64, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.UserID)
+	}
+	return ids
+}
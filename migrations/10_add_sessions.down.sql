@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS sessions;
+ALTER TABLE apps DROP COLUMN IF EXISTS max_sessions;
+ALTER TABLE apps DROP COLUMN IF EXISTS absolute_lifetime;
+ALTER TABLE apps DROP COLUMN IF EXISTS idle_timeout;
+ALTER TABLE apps DROP COLUMN IF EXISTS refresh_ttl;
+ALTER TABLE apps DROP COLUMN IF EXISTS access_ttl;
@@ -0,0 +1,25 @@
+-- per-app token and session settings in seconds, 0 means the service default
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS access_ttl INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS refresh_ttl INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS idle_timeout INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS absolute_lifetime INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS max_sessions INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id                 BIGSERIAL PRIMARY KEY,
+    uid                INTEGER NOT NULL REFERENCES users (id),
+    app_id             INTEGER NOT NULL REFERENCES apps (id),
+    -- sha256 of the current and the previous refresh token
+    refresh_hash       TEXT NOT NULL UNIQUE,
+    prev_refresh_hash  TEXT,
+    ip                 TEXT NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    refresh_expires_at TIMESTAMPTZ NOT NULL,
+    -- NULL means no absolute lifetime
+    expires_at         TIMESTAMPTZ,
+    revoked_at         TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions (uid, app_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_prev_hash ON sessions (prev_refresh_hash);
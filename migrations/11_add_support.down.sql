@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_audit_log_operator;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS operator_id;
+DROP TABLE IF EXISTS supports;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS supports
+(
+    uid      INTEGER REFERENCES users (id),
+    app_id   INTEGER REFERENCES apps (id)
+);
+
+-- support user that made the entry while impersonating actor_id, 0 when the actor acted itself
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS operator_id BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_audit_log_operator ON audit_log (operator_id, id) WHERE operator_id <> 0;
@@ -0,0 +1,4 @@
+ALTER TABLE audit_log DROP COLUMN IF EXISTS actor_kind;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS service_accounts;
+ALTER TABLE users DROP COLUMN IF EXISTS kind;
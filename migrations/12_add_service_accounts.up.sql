@@ -0,0 +1,26 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'human';
+
+CREATE TABLE IF NOT EXISTS service_accounts
+(
+    uid        INTEGER PRIMARY KEY REFERENCES users (id),
+    app_id     INTEGER NOT NULL REFERENCES apps (id),
+    name       TEXT NOT NULL,
+    created_by BIGINT NOT NULL DEFAULT 0,
+    UNIQUE (app_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    id           BIGSERIAL PRIMARY KEY,
+    uid          INTEGER NOT NULL REFERENCES users (id),
+    key_hash     TEXT NOT NULL UNIQUE,
+    prefix       TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_uid ON api_keys (uid);
+
+-- actor_kind is covered by the hash only for non-human actors, so older entries still verify
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS actor_kind TEXT NOT NULL DEFAULT 'human';
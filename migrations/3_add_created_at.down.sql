@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS app_users;
+
+DROP INDEX IF EXISTS idx_creators_uid;
+DROP INDEX IF EXISTS idx_creators_app_uid;
+DROP INDEX IF EXISTS idx_admins_app_uid;
+
+ALTER TABLE creators DROP COLUMN IF EXISTS created_at;
+ALTER TABLE admins DROP COLUMN IF EXISTS created_at;
+ALTER TABLE apps DROP COLUMN IF EXISTS created_at;
+ALTER TABLE users DROP COLUMN IF EXISTS created_at;
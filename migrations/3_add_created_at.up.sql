@@ -0,0 +1,18 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE creators ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_admins_app_uid ON admins (app_id, uid);
+CREATE INDEX IF NOT EXISTS idx_creators_app_uid ON creators (app_id, uid);
+CREATE INDEX IF NOT EXISTS idx_creators_uid ON creators (uid);
+
+-- users that joined the app, holders of its roles are members without a row here
+CREATE TABLE IF NOT EXISTS app_users
+(
+    uid        INTEGER NOT NULL REFERENCES users (id),
+    app_id     INTEGER NOT NULL REFERENCES apps (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_id, uid)
+);
+CREATE INDEX IF NOT EXISTS idx_app_users_uid ON app_users (uid);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS user_metadata;
+
+ALTER TABLE apps DROP COLUMN IF EXISTS user_metadata_schema;
+ALTER TABLE apps DROP COLUMN IF EXISTS app_metadata_schema;
+
+ALTER TABLE users DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_url;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
+ALTER TABLE users DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS app_metadata_schema JSONB;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS user_metadata_schema JSONB;
+
+CREATE TABLE IF NOT EXISTS user_metadata
+(
+    uid           INTEGER REFERENCES users (id),
+    app_id        INTEGER REFERENCES apps (id),
+    app_metadata  JSONB NOT NULL DEFAULT '{}',
+    user_metadata JSONB NOT NULL DEFAULT '{}',
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (uid, app_id)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion ON users (deletion_requested_at) WHERE status = 'pending_deletion';
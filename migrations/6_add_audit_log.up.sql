@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    action     TEXT NOT NULL,
+    actor_id   BIGINT NOT NULL DEFAULT 0,
+    target     TEXT NOT NULL DEFAULT '',
+    app_name   TEXT NOT NULL DEFAULT '',
+    ip         TEXT NOT NULL DEFAULT '',
+    outcome    TEXT NOT NULL,
+    reason     TEXT NOT NULL DEFAULT '',
+    prev_hash  BYTEA NOT NULL,
+    hash       BYTEA NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_app ON audit_log (app_name, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_id, id);
+
+-- audit log is append-only
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_change ON audit_log;
+CREATE TRIGGER audit_log_no_change
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,58 @@
+-- events is the outbox: rows are written in the same transaction as the change they describe
+CREATE TABLE IF NOT EXISTS events
+(
+    id         BIGSERIAL PRIMARY KEY,
+    type       TEXT NOT NULL,
+    app_id     INTEGER,
+    -- user the event is about, the purge erases emails from payloads of these events
+    uid        BIGINT,
+    payload    JSONB NOT NULL,
+    -- transaction that wrote the event, readers go in (txid, id) order up to the oldest running transaction
+    txid       XID8 NOT NULL DEFAULT pg_current_xact_id(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_events_txid ON events (txid, id);
+CREATE INDEX IF NOT EXISTS idx_events_uid ON events (uid) WHERE uid IS NOT NULL;
+
+-- apps that see an event without app_id: the apps its user was a member of when it happened
+CREATE TABLE IF NOT EXISTS event_apps
+(
+    event_id BIGINT NOT NULL REFERENCES events (id),
+    app_id   INTEGER NOT NULL,
+    PRIMARY KEY (app_id, event_id)
+);
+
+-- webhooks of a deleted app are kept until their pending deliveries are done, so app_id has no foreign key
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id          SERIAL PRIMARY KEY,
+    app_id      INTEGER NOT NULL,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    deleted_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_app ON webhooks (app_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              BIGSERIAL PRIMARY KEY,
+    webhook_id      INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id        BIGINT NOT NULL REFERENCES events (id),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error      TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_dead_letters
+(
+    id         BIGSERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_id   BIGINT NOT NULL REFERENCES events (id),
+    attempts   INTEGER NOT NULL,
+    last_error TEXT NOT NULL,
+    failed_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_dead_letters_webhook ON webhook_dead_letters (webhook_id, id);
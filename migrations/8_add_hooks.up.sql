@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS app_hooks
+(
+    id         SERIAL PRIMARY KEY,
+    app_id     INTEGER NOT NULL REFERENCES apps (id),
+    stage      TEXT NOT NULL,
+    kind       TEXT NOT NULL,
+    target     TEXT NOT NULL,
+    timeout_ms INTEGER NOT NULL DEFAULT 0,
+    fail_open  BOOLEAN NOT NULL DEFAULT false,
+    -- hooks of one stage run in position order
+    position   INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_app_hooks_app ON app_hooks (app_id, stage, position);
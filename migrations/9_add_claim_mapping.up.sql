@@ -0,0 +1,2 @@
+-- claim name to expression, NULL means default claims only
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS claim_mapping JSONB;
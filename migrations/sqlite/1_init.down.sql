@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS app_hooks;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS user_metadata;
+DROP TABLE IF EXISTS app_users;
+DROP TABLE IF EXISTS supports;
+DROP TABLE IF EXISTS admins;
+DROP TABLE IF EXISTS creators;
+DROP TABLE IF EXISTS apps;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,140 @@
+-- schema of the sqlite driver: the tables of auth, permissions and apps.
+-- Times are unix microseconds, postgres keeps the same precision
+CREATE TABLE IF NOT EXISTS users
+(
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind         TEXT NOT NULL DEFAULT 'human',
+    email        TEXT NOT NULL UNIQUE,
+    pass_hash    BLOB NOT NULL,
+    display_name TEXT NOT NULL DEFAULT '',
+    locale       TEXT NOT NULL DEFAULT '',
+    avatar_url   TEXT NOT NULL DEFAULT '',
+    status       TEXT NOT NULL DEFAULT 'active',
+    created_at   INTEGER NOT NULL,
+    updated_at   INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS apps
+(
+    id                   INTEGER PRIMARY KEY AUTOINCREMENT,
+    name                 TEXT NOT NULL UNIQUE,
+    secret               TEXT NOT NULL,
+    -- claim name to expression, NULL means default claims only
+    claim_mapping        TEXT,
+    app_metadata_schema  TEXT,
+    user_metadata_schema TEXT,
+    -- token and session settings in seconds, 0 means the service default
+    access_ttl           INTEGER NOT NULL DEFAULT 0,
+    refresh_ttl          INTEGER NOT NULL DEFAULT 0,
+    idle_timeout         INTEGER NOT NULL DEFAULT 0,
+    absolute_lifetime    INTEGER NOT NULL DEFAULT 0,
+    max_sessions         INTEGER NOT NULL DEFAULT 0,
+    created_at           INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS creators
+(
+    uid        INTEGER NOT NULL REFERENCES users (id),
+    app_id     INTEGER NOT NULL REFERENCES apps (id),
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (app_id, uid)
+);
+CREATE INDEX IF NOT EXISTS idx_creators_uid ON creators (uid);
+
+CREATE TABLE IF NOT EXISTS admins
+(
+    uid        INTEGER NOT NULL REFERENCES users (id),
+    app_id     INTEGER NOT NULL REFERENCES apps (id),
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (app_id, uid)
+);
+
+CREATE TABLE IF NOT EXISTS supports
+(
+    uid        INTEGER NOT NULL REFERENCES users (id),
+    app_id     INTEGER NOT NULL REFERENCES apps (id),
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (app_id, uid)
+);
+
+-- users that joined the app, holders of its roles are members without a row here
+CREATE TABLE IF NOT EXISTS app_users
+(
+    uid        INTEGER NOT NULL REFERENCES users (id),
+    app_id     INTEGER NOT NULL REFERENCES apps (id),
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (app_id, uid)
+);
+CREATE INDEX IF NOT EXISTS idx_app_users_uid ON app_users (uid);
+
+CREATE TABLE IF NOT EXISTS user_metadata
+(
+    uid           INTEGER NOT NULL REFERENCES users (id),
+    app_id        INTEGER NOT NULL REFERENCES apps (id),
+    app_metadata  TEXT NOT NULL DEFAULT '{}',
+    user_metadata TEXT NOT NULL DEFAULT '{}',
+    PRIMARY KEY (uid, app_id)
+);
+
+CREATE TABLE IF NOT EXISTS sessions
+(
+    id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+    uid                INTEGER NOT NULL REFERENCES users (id),
+    app_id             INTEGER NOT NULL REFERENCES apps (id),
+    -- sha256 of the current and the previous refresh token
+    refresh_hash       TEXT NOT NULL UNIQUE,
+    prev_refresh_hash  TEXT,
+    ip                 TEXT NOT NULL DEFAULT '',
+    created_at         INTEGER NOT NULL,
+    last_used_at       INTEGER NOT NULL,
+    refresh_expires_at INTEGER NOT NULL,
+    -- NULL means no absolute lifetime
+    expires_at         INTEGER,
+    revoked_at         INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions (uid, app_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_prev_hash ON sessions (prev_refresh_hash);
+
+CREATE TABLE IF NOT EXISTS app_hooks
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    app_id     INTEGER NOT NULL REFERENCES apps (id),
+    stage      TEXT NOT NULL,
+    kind       TEXT NOT NULL,
+    target     TEXT NOT NULL,
+    timeout_ms INTEGER NOT NULL DEFAULT 0,
+    fail_open  INTEGER NOT NULL DEFAULT 0,
+    -- hooks of one stage run in position order
+    position   INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_app_hooks_app ON app_hooks (app_id, stage, position);
+
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at  INTEGER NOT NULL,
+    action      TEXT NOT NULL,
+    actor_id    INTEGER NOT NULL DEFAULT 0,
+    actor_kind  TEXT NOT NULL DEFAULT 'human',
+    -- support user that made the entry while impersonating actor_id, 0 when the actor acted itself
+    operator_id INTEGER NOT NULL DEFAULT 0,
+    target      TEXT NOT NULL DEFAULT '',
+    app_name    TEXT NOT NULL DEFAULT '',
+    ip          TEXT NOT NULL DEFAULT '',
+    outcome     TEXT NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    prev_hash   BLOB NOT NULL,
+    hash        BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_app ON audit_log (app_name, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_id, id);
+
+-- audit log is append-only
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
@@ -0,0 +1,516 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v5.26.1
+// source: sso/apps.proto
+
+package ssov2
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Apps_GetAppID_FullMethodName          = "/apps.Apps/GetAppID"
+	Apps_SetApp_FullMethodName            = "/apps.Apps/SetApp"
+	Apps_UpdApp_FullMethodName            = "/apps.Apps/UpdApp"
+	Apps_DelApp_FullMethodName            = "/apps.Apps/DelApp"
+	Apps_ListApps_FullMethodName          = "/apps.Apps/ListApps"
+	Apps_SetMetadataSchema_FullMethodName = "/apps.Apps/SetMetadataSchema"
+	Apps_SetHooks_FullMethodName          = "/apps.Apps/SetHooks"
+	Apps_ListHooks_FullMethodName         = "/apps.Apps/ListHooks"
+	Apps_SetClaimMapping_FullMethodName   = "/apps.Apps/SetClaimMapping"
+	Apps_GetClaimMapping_FullMethodName   = "/apps.Apps/GetClaimMapping"
+	Apps_SetAppSettings_FullMethodName    = "/apps.Apps/SetAppSettings"
+	Apps_GetAppSettings_FullMethodName    = "/apps.Apps/GetAppSettings"
+)
+
+// AppsClient is the client API for Apps service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AppsClient interface {
+	GetAppID(ctx context.Context, in *GetAppRequest, opts ...grpc.CallOption) (*GetAppResponse, error)
+	SetApp(ctx context.Context, in *SetAppRequest, opts ...grpc.CallOption) (*SetAppResponse, error)
+	UpdApp(ctx context.Context, in *UpdAppRequest, opts ...grpc.CallOption) (*UpdAppResponse, error)
+	DelApp(ctx context.Context, in *DelAppRequest, opts ...grpc.CallOption) (*DelAppResponse, error)
+	ListApps(ctx context.Context, in *ListAppsRequest, opts ...grpc.CallOption) (*ListAppsResponse, error)
+	SetMetadataSchema(ctx context.Context, in *SetMetadataSchemaRequest, opts ...grpc.CallOption) (*SetMetadataSchemaResponse, error)
+	SetHooks(ctx context.Context, in *SetHooksRequest, opts ...grpc.CallOption) (*SetHooksResponse, error)
+	ListHooks(ctx context.Context, in *ListHooksRequest, opts ...grpc.CallOption) (*ListHooksResponse, error)
+	SetClaimMapping(ctx context.Context, in *SetClaimMappingRequest, opts ...grpc.CallOption) (*SetClaimMappingResponse, error)
+	GetClaimMapping(ctx context.Context, in *GetClaimMappingRequest, opts ...grpc.CallOption) (*GetClaimMappingResponse, error)
+	SetAppSettings(ctx context.Context, in *SetAppSettingsRequest, opts ...grpc.CallOption) (*SetAppSettingsResponse, error)
+	GetAppSettings(ctx context.Context, in *GetAppSettingsRequest, opts ...grpc.CallOption) (*GetAppSettingsResponse, error)
+}
+
+type appsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAppsClient(cc grpc.ClientConnInterface) AppsClient {
+	return &appsClient{cc}
+}
+
+func (c *appsClient) GetAppID(ctx context.Context, in *GetAppRequest, opts ...grpc.CallOption) (*GetAppResponse, error) {
+	out := new(GetAppResponse)
+	err := c.cc.Invoke(ctx, Apps_GetAppID_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) SetApp(ctx context.Context, in *SetAppRequest, opts ...grpc.CallOption) (*SetAppResponse, error) {
+	out := new(SetAppResponse)
+	err := c.cc.Invoke(ctx, Apps_SetApp_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) UpdApp(ctx context.Context, in *UpdAppRequest, opts ...grpc.CallOption) (*UpdAppResponse, error) {
+	out := new(UpdAppResponse)
+	err := c.cc.Invoke(ctx, Apps_UpdApp_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) DelApp(ctx context.Context, in *DelAppRequest, opts ...grpc.CallOption) (*DelAppResponse, error) {
+	out := new(DelAppResponse)
+	err := c.cc.Invoke(ctx, Apps_DelApp_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) ListApps(ctx context.Context, in *ListAppsRequest, opts ...grpc.CallOption) (*ListAppsResponse, error) {
+	out := new(ListAppsResponse)
+	err := c.cc.Invoke(ctx, Apps_ListApps_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) SetMetadataSchema(ctx context.Context, in *SetMetadataSchemaRequest, opts ...grpc.CallOption) (*SetMetadataSchemaResponse, error) {
+	out := new(SetMetadataSchemaResponse)
+	err := c.cc.Invoke(ctx, Apps_SetMetadataSchema_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) SetHooks(ctx context.Context, in *SetHooksRequest, opts ...grpc.CallOption) (*SetHooksResponse, error) {
+	out := new(SetHooksResponse)
+	err := c.cc.Invoke(ctx, Apps_SetHooks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) ListHooks(ctx context.Context, in *ListHooksRequest, opts ...grpc.CallOption) (*ListHooksResponse, error) {
+	out := new(ListHooksResponse)
+	err := c.cc.Invoke(ctx, Apps_ListHooks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) SetClaimMapping(ctx context.Context, in *SetClaimMappingRequest, opts ...grpc.CallOption) (*SetClaimMappingResponse, error) {
+	out := new(SetClaimMappingResponse)
+	err := c.cc.Invoke(ctx, Apps_SetClaimMapping_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) GetClaimMapping(ctx context.Context, in *GetClaimMappingRequest, opts ...grpc.CallOption) (*GetClaimMappingResponse, error) {
+	out := new(GetClaimMappingResponse)
+	err := c.cc.Invoke(ctx, Apps_GetClaimMapping_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) SetAppSettings(ctx context.Context, in *SetAppSettingsRequest, opts ...grpc.CallOption) (*SetAppSettingsResponse, error) {
+	out := new(SetAppSettingsResponse)
+	err := c.cc.Invoke(ctx, Apps_SetAppSettings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *appsClient) GetAppSettings(ctx context.Context, in *GetAppSettingsRequest, opts ...grpc.CallOption) (*GetAppSettingsResponse, error) {
+	out := new(GetAppSettingsResponse)
+	err := c.cc.Invoke(ctx, Apps_GetAppSettings_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AppsServer is the server API for Apps service.
+// All implementations must embed UnimplementedAppsServer
+// for forward compatibility
+type AppsServer interface {
+	GetAppID(context.Context, *GetAppRequest) (*GetAppResponse, error)
+	SetApp(context.Context, *SetAppRequest) (*SetAppResponse, error)
+	UpdApp(context.Context, *UpdAppRequest) (*UpdAppResponse, error)
+	DelApp(context.Context, *DelAppRequest) (*DelAppResponse, error)
+	ListApps(context.Context, *ListAppsRequest) (*ListAppsResponse, error)
+	SetMetadataSchema(context.Context, *SetMetadataSchemaRequest) (*SetMetadataSchemaResponse, error)
+	SetHooks(context.Context, *SetHooksRequest) (*SetHooksResponse, error)
+	ListHooks(context.Context, *ListHooksRequest) (*ListHooksResponse, error)
+	SetClaimMapping(context.Context, *SetClaimMappingRequest) (*SetClaimMappingResponse, error)
+	GetClaimMapping(context.Context, *GetClaimMappingRequest) (*GetClaimMappingResponse, error)
+	SetAppSettings(context.Context, *SetAppSettingsRequest) (*SetAppSettingsResponse, error)
+	GetAppSettings(context.Context, *GetAppSettingsRequest) (*GetAppSettingsResponse, error)
+	mustEmbedUnimplementedAppsServer()
+}
+
+// UnimplementedAppsServer must be embedded to have forward compatible implementations.
+type UnimplementedAppsServer struct {
+}
+
+func (UnimplementedAppsServer) GetAppID(context.Context, *GetAppRequest) (*GetAppResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAppID not implemented")
+}
+func (UnimplementedAppsServer) SetApp(context.Context, *SetAppRequest) (*SetAppResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetApp not implemented")
+}
+func (UnimplementedAppsServer) UpdApp(context.Context, *UpdAppRequest) (*UpdAppResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdApp not implemented")
+}
+func (UnimplementedAppsServer) DelApp(context.Context, *DelAppRequest) (*DelAppResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DelApp not implemented")
+}
+func (UnimplementedAppsServer) ListApps(context.Context, *ListAppsRequest) (*ListAppsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListApps not implemented")
+}
+func (UnimplementedAppsServer) SetMetadataSchema(context.Context, *SetMetadataSchemaRequest) (*SetMetadataSchemaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMetadataSchema not implemented")
+}
+func (UnimplementedAppsServer) SetHooks(context.Context, *SetHooksRequest) (*SetHooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetHooks not implemented")
+}
+func (UnimplementedAppsServer) ListHooks(context.Context, *ListHooksRequest) (*ListHooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListHooks not implemented")
+}
+func (UnimplementedAppsServer) SetClaimMapping(context.Context, *SetClaimMappingRequest) (*SetClaimMappingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetClaimMapping not implemented")
+}
+func (UnimplementedAppsServer) GetClaimMapping(context.Context, *GetClaimMappingRequest) (*GetClaimMappingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetClaimMapping not implemented")
+}
+func (UnimplementedAppsServer) SetAppSettings(context.Context, *SetAppSettingsRequest) (*SetAppSettingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetAppSettings not implemented")
+}
+func (UnimplementedAppsServer) GetAppSettings(context.Context, *GetAppSettingsRequest) (*GetAppSettingsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAppSettings not implemented")
+}
+func (UnimplementedAppsServer) mustEmbedUnimplementedAppsServer() {}
+
+// UnsafeAppsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AppsServer will
+// result in compilation errors.
+type UnsafeAppsServer interface {
+	mustEmbedUnimplementedAppsServer()
+}
+
+func RegisterAppsServer(s grpc.ServiceRegistrar, srv AppsServer) {
+	s.RegisterService(&Apps_ServiceDesc, srv)
+}
+
+func _Apps_GetAppID_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAppRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).GetAppID(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_GetAppID_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).GetAppID(ctx, req.(*GetAppRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_SetApp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAppRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).SetApp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_SetApp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).SetApp(ctx, req.(*SetAppRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_UpdApp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdAppRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).UpdApp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_UpdApp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).UpdApp(ctx, req.(*UpdAppRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_DelApp_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DelAppRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).DelApp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_DelApp_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).DelApp(ctx, req.(*DelAppRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_ListApps_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAppsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).ListApps(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_ListApps_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).ListApps(ctx, req.(*ListAppsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_SetMetadataSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetMetadataSchemaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).SetMetadataSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_SetMetadataSchema_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).SetMetadataSchema(ctx, req.(*SetMetadataSchemaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_SetHooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetHooksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).SetHooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_SetHooks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).SetHooks(ctx, req.(*SetHooksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_ListHooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListHooksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).ListHooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_ListHooks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).ListHooks(ctx, req.(*ListHooksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_SetClaimMapping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetClaimMappingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).SetClaimMapping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_SetClaimMapping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).SetClaimMapping(ctx, req.(*SetClaimMappingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_GetClaimMapping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetClaimMappingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).GetClaimMapping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_GetClaimMapping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).GetClaimMapping(ctx, req.(*GetClaimMappingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_SetAppSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAppSettingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).SetAppSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_SetAppSettings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).SetAppSettings(ctx, req.(*SetAppSettingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Apps_GetAppSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAppSettingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AppsServer).GetAppSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Apps_GetAppSettings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AppsServer).GetAppSettings(ctx, req.(*GetAppSettingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Apps_ServiceDesc is the grpc.ServiceDesc for Apps service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Apps_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "apps.Apps",
+	HandlerType: (*AppsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetAppID",
+			Handler:    _Apps_GetAppID_Handler,
+		},
+		{
+			MethodName: "SetApp",
+			Handler:    _Apps_SetApp_Handler,
+		},
+		{
+			MethodName: "UpdApp",
+			Handler:    _Apps_UpdApp_Handler,
+		},
+		{
+			MethodName: "DelApp",
+			Handler:    _Apps_DelApp_Handler,
+		},
+		{
+			MethodName: "ListApps",
+			Handler:    _Apps_ListApps_Handler,
+		},
+		{
+			MethodName: "SetMetadataSchema",
+			Handler:    _Apps_SetMetadataSchema_Handler,
+		},
+		{
+			MethodName: "SetHooks",
+			Handler:    _Apps_SetHooks_Handler,
+		},
+		{
+			MethodName: "ListHooks",
+			Handler:    _Apps_ListHooks_Handler,
+		},
+		{
+			MethodName: "SetClaimMapping",
+			Handler:    _Apps_SetClaimMapping_Handler,
+		},
+		{
+			MethodName: "GetClaimMapping",
+			Handler:    _Apps_GetClaimMapping_Handler,
+		},
+		{
+			MethodName: "SetAppSettings",
+			Handler:    _Apps_SetAppSettings_Handler,
+		},
+		{
+			MethodName: "GetAppSettings",
+			Handler:    _Apps_GetAppSettings_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "sso/apps.proto",
+}